@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/achievement"
+	"github.com/onionj/trust/internal/entity"
+)
+
+// EvaluateAchievements tracks a completed game for both players,
+// unlocks the reached achievements and announces them through the bot
+func (server *Server) EvaluateAchievements(ctx context.Context, game entity.Game) {
+	for _, userID := range []int64{game.P1ID, game.P2ID} {
+		progress, err := server.AchieveRepo.GetProgress(ctx, userID)
+		if err != nil {
+			logrus.Error("get achievement progress error ", err)
+			continue
+		}
+		unlocked, err := server.AchieveRepo.Unlocked(ctx, userID)
+		if err != nil {
+			logrus.Error("get unlocked achievements error ", err)
+			continue
+		}
+
+		progress, reached := achievement.Evaluate(progress, unlocked, game, userID)
+		if err := server.AchieveRepo.SaveProgress(ctx, userID, progress); err != nil {
+			logrus.Error("save achievement progress error ", err)
+			continue
+		}
+
+		for _, definition := range reached {
+			added, err := server.AchieveRepo.Unlock(ctx, userID, definition.ID)
+			if err != nil || !added {
+				continue
+			}
+			server.grantAchievementRewards(ctx, userID, definition)
+		}
+	}
+}
+
+func (server *Server) grantAchievementRewards(ctx context.Context, userID int64, definition achievement.Definition) {
+	text := fmt.Sprintf("%s Achievement unlocked: %s\n%s", definition.Icon, definition.Name, definition.Description)
+
+	if definition.Avatar != 0 {
+		err := server.InventoryRepo.Add(ctx, userID, entity.InventoryAvatar, fmt.Sprint(definition.Avatar))
+		if err != nil {
+			logrus.Error("add achievement avatar error ", err)
+		} else {
+			text += "\nNew avatar available in your profile!"
+		}
+	}
+
+	if definition.BonusTokens > 0 {
+		err := server.GrantBonusTokens(ctx, userID, definition.BonusTokens)
+		if err != nil {
+			logrus.Error("add achievement bonus tokens error ", err)
+		} else {
+			text += fmt.Sprintf("\n⭐ +%d bonus game tokens!", definition.BonusTokens)
+		}
+	}
+
+	server.notify(userID, text)
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+var (
+	ErrReasonRequired = errors.New("a reason is required")
+	ErrGameNotActive  = errors.New("game is not active")
+)
+
+// Audit writes the admin action to the audit log
+func (server *Server) Audit(ctx context.Context, entry entity.AuditEntry) {
+	if err := server.AuditRepo.Append(ctx, entry); err != nil {
+		logrus.Error("audit append error ", err)
+	}
+}
+
+// AdminAdjustBalance credits (or debits with a negative amount) the user balance
+func (server *Server) AdminAdjustBalance(ctx context.Context, adminID int64, userID int64, amount int, reason string) (entity.User, error) {
+	if strings.TrimSpace(reason) == "" {
+		return entity.User{}, ErrReasonRequired
+	}
+	user, err := server.UpdateBalance(ctx, userID, amount, entity.LedgerAdmin, reason, nil)
+	if err != nil {
+		return user, err
+	}
+	server.Audit(ctx, entity.NewAuditEntry(adminID, entity.AuditBalance, userID, reason, fmt.Sprintf("amount %d balance %d", amount, user.Balance)))
+	return user, nil
+}
+
+// AdminSetHourLimit changes the number of games the user can start per hour
+func (server *Server) AdminSetHourLimit(ctx context.Context, adminID int64, userID int64, limit int, reason string) (entity.User, error) {
+	if strings.TrimSpace(reason) == "" {
+		return entity.User{}, ErrReasonRequired
+	}
+	if limit < 0 {
+		return entity.User{}, errors.New("hour limit must not be negative")
+	}
+	previous := 0
+	user, err := server.UpdateUser(ctx, userID, func(user *entity.User) error {
+		previous = user.HourLimit
+		user.HourLimit = limit
+		return nil
+	})
+	if err != nil {
+		return user, err
+	}
+	server.Audit(ctx, entity.NewAuditEntry(adminID, entity.AuditHourLimit, userID, reason, fmt.Sprintf("%d -> %d", previous, limit)))
+	return user, nil
+}
+
+// AdminBan bans the user until the given time, or permanently with entity.BanPermanent
+func (server *Server) AdminBan(ctx context.Context, adminID int64, userID int64, until int64, reason string) (entity.User, error) {
+	if strings.TrimSpace(reason) == "" {
+		return entity.User{}, ErrReasonRequired
+	}
+	user, err := server.UpdateUser(ctx, userID, func(user *entity.User) error {
+		user.BannedUntil = until
+		user.BanReason = reason
+		return nil
+	})
+	if err != nil {
+		return user, err
+	}
+
+	details := "permanent"
+	if until != entity.BanPermanent {
+		details = "until " + time.Unix(until, 0).UTC().Format(time.RFC3339)
+	}
+	server.Audit(ctx, entity.NewAuditEntry(adminID, entity.AuditBan, userID, reason, details))
+
+	if err := server.ForfeitActiveGames(ctx, userID); err != nil {
+		logrus.Error("forfeit games of banned user error ", err)
+	}
+	return user, nil
+}
+
+// AdminUnban lifts the ban of the user
+func (server *Server) AdminUnban(ctx context.Context, adminID int64, userID int64, reason string) (entity.User, error) {
+	if strings.TrimSpace(reason) == "" {
+		return entity.User{}, ErrReasonRequired
+	}
+	user, err := server.UpdateUser(ctx, userID, func(user *entity.User) error {
+		user.BannedUntil = 0
+		user.BanReason = ""
+		return nil
+	})
+	if err != nil {
+		return user, err
+	}
+	server.Audit(ctx, entity.NewAuditEntry(adminID, entity.AuditUnban, userID, reason, ""))
+	return user, nil
+}
+
+// AdminCompleteGame completes a stuck active game, the open rounds go to the server
+// and the rounds already decided are settled like a finished game
+func (server *Server) AdminCompleteGame(ctx context.Context, adminID int64, game entity.Game, reason string) (entity.Game, error) {
+	if strings.TrimSpace(reason) == "" {
+		return game, ErrReasonRequired
+	}
+
+	if err := server.completeGame(ctx, game.Id, func(game *entity.Game) { game.Abandon() }); err != nil {
+		return game, err
+	}
+	game, err := server.GameRepo.GetByID(ctx, game.Id)
+	if err != nil {
+		return game, err
+	}
+	server.Audit(ctx, entity.NewAuditEntry(adminID, entity.AuditCompleteGame, int64(game.Id), reason, fmt.Sprintf("p%d vs p%d", game.P1ID, game.P2ID)))
+	return game, nil
+}
+
+// UserGames returns the games the user played in
+func (server *Server) UserGames(ctx context.Context, userID int64) ([]entity.Game, error) {
+	return server.GameRepo.PlayerGames(ctx, userID, 0)
+}
+
+// LobbyUserID returns the user waiting longest in the lobbies, 0 if every lobby is empty
+func (server *Server) LobbyUserID(ctx context.Context) (int64, error) {
+	lobbyUser := LobbyEntry{}
+	for _, mode := range server.Config.Modes.All() {
+		entries, err := server.LobbyEntries(ctx, mode.Name)
+		if err != nil {
+			return 0, err
+		}
+		if len(entries) > 0 && (lobbyUser.UserID == 0 || entries[0].Joined.Before(lobbyUser.Joined)) {
+			lobbyUser = entries[0]
+		}
+	}
+	return lobbyUser.UserID, nil
+}
+
+// Stats is a snapshot of the game activity
+type Stats struct {
+	Users       int
+	Games       int
+	ActiveGames int
+	LobbyUserID int64
+}
+
+func (server *Server) Stats(ctx context.Context) (Stats, error) {
+	stats := Stats{}
+
+	users, err := server.UserRepo.IndexSize(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.Users = int(users)
+
+	games, err := server.GameRepo.Scan(ctx, "game:*", 0)
+	if err != nil {
+		return stats, err
+	}
+	stats.Games = len(games)
+	for _, game := range games {
+		if game.Status == entity.Active {
+			stats.ActiveGames++
+		}
+	}
+
+	stats.LobbyUserID, err = server.LobbyUserID(ctx)
+	return stats, err
+}
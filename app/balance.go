@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
+)
+
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// UpdateUser loads the user under the balance lock, applies fn and saves the result.
+// Nothing is saved if fn returns an error, fn runs again if the user is saved meanwhile
+// by a path without the lock.
+func (server *Server) UpdateUser(ctx context.Context, userID int64, fn func(user *entity.User) error) (entity.User, error) {
+	balanceLock, err := server.Locker.Obtain(
+		ctx,
+		fmt.Sprintf(USER_LOCK_BALANCE, userID),
+		5*time.Second,
+		&redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 10)},
+	)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("user balance lock error: %w", err)
+	}
+	defer balanceLock.Release(ctx)
+
+	return repository.UpdateRetry(ctx, server.UserRepo, userID, fn)
+}
+
+// UpdateBalance adds amount (negative to debit) to the user balance, applies the
+// optional fn in the same critical section and records the change in the ledger.
+// A debit fails with ErrInsufficientBalance when the balance can't cover it.
+func (server *Server) UpdateBalance(ctx context.Context, userID int64, amount int, kind string, note string, fn func(user *entity.User) error) (entity.User, error) {
+	user, err := server.UpdateUser(ctx, userID, func(user *entity.User) error {
+		if amount < 0 && user.Balance+amount < 0 {
+			return ErrInsufficientBalance
+		}
+		user.Balance += amount
+		if fn != nil {
+			return fn(user)
+		}
+		return nil
+	})
+	if err != nil {
+		return user, err
+	}
+
+	if amount != 0 {
+		err = server.LedgerRepo.Append(ctx, entity.NewLedgerEntry(userID, amount, user.Balance, kind, note))
+		if err != nil {
+			logrus.Error("ledger append error ", err)
+		}
+	}
+	return user, nil
+}
@@ -0,0 +1,181 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+const (
+	broadcastRate  = 25  // messages per second, under the telegram global limit of 30
+	broadcastBatch = 100 // users read from the index at once, progress is reported after each batch
+)
+
+// StartBroadcast creates a broadcast job and starts delivering it in the background
+func (server *Server) StartBroadcast(ctx context.Context, adminID int64, text string) (entity.Broadcast, error) {
+	total, err := server.UserRepo.IndexSize(ctx)
+	if err != nil {
+		return entity.Broadcast{}, err
+	}
+	broadcastID, err := entity.GetOrInitID(server.DB, BROADCAST_INDEX)
+	if err != nil {
+		return entity.Broadcast{}, err
+	}
+
+	broadcast := entity.NewBroadcast(broadcastID, adminID, text, total)
+	report, err := server.TeleBot.Send(tele.ChatID(adminID), broadcastProgress(broadcast))
+	if err == nil {
+		broadcast.ReportMessageID = strconv.Itoa(report.ID)
+	}
+	if err := server.BroadcastRepo.Save(ctx, &broadcast); err != nil {
+		return broadcast, err
+	}
+
+	go server.runBroadcast(broadcast)
+	return broadcast, nil
+}
+
+// ResumeBroadcasts restarts the broadcasts that were running when the server stopped
+func (server *Server) ResumeBroadcasts(ctx context.Context) {
+	broadcasts, err := server.BroadcastRepo.Scan(ctx, "broadcast:*", 0)
+	if err != nil {
+		logrus.Error("resume broadcasts error ", err)
+		return
+	}
+	for _, broadcast := range broadcasts {
+		if broadcast.Status == entity.BroadcastRunning {
+			logrus.Info("resume broadcast ", broadcast.Id, " at ", broadcast.Cursor)
+			go server.runBroadcast(broadcast)
+		}
+	}
+}
+
+// runBroadcast walks the users index from the broadcast cursor and persists
+// the cursor after every user so the job continues where it stopped.
+func (server *Server) runBroadcast(broadcast entity.Broadcast) {
+	ctx := context.Background()
+
+	lock, err := server.Locker.Obtain(ctx, fmt.Sprintf(BROADCAST_LOCK, broadcast.Id), time.Minute, nil)
+	if err != nil {
+		logrus.Warn("broadcast ", broadcast.Id, " is running elsewhere ", err)
+		return
+	}
+	defer lock.Release(ctx)
+
+	limiter := rate.NewLimiter(broadcastRate, 1)
+	for broadcast.Status == entity.BroadcastRunning {
+		userIDs, err := server.UserRepo.IndexRange(ctx, broadcast.Cursor, broadcastBatch)
+		if err != nil {
+			logrus.Error("broadcast ", broadcast.Id, " users index error ", err)
+			return
+		}
+		if len(userIDs) == 0 {
+			broadcast.Status = entity.BroadcastCompleted
+		}
+
+		for _, userID := range userIDs {
+			if err := limiter.Wait(ctx); err != nil {
+				return
+			}
+			if err := server.deliverBroadcast(ctx, lock, &broadcast, userID); err != nil {
+				logrus.Error("broadcast ", broadcast.Id, " lock lost ", err)
+				return
+			}
+			broadcast.Cursor++
+
+			if err := server.BroadcastRepo.Save(ctx, &broadcast); err != nil {
+				logrus.Error("broadcast ", broadcast.Id, " save error ", err)
+				return
+			}
+			if err := lock.Refresh(ctx, time.Minute, nil); err != nil {
+				logrus.Error("broadcast ", broadcast.Id, " lock lost ", err)
+				return
+			}
+		}
+
+		if err := server.BroadcastRepo.Save(ctx, &broadcast); err != nil {
+			logrus.Error("broadcast ", broadcast.Id, " save error ", err)
+			return
+		}
+		server.reportBroadcast(broadcast)
+	}
+}
+
+// deliverBroadcast sends the broadcast to one user, waiting out flood limits
+// and marking users who blocked the bot as inactive. The lock is refreshed to
+// outlast every flood wait, an error means the lock was lost.
+func (server *Server) deliverBroadcast(ctx context.Context, lock *redislock.Lock, broadcast *entity.Broadcast, userID int64) error {
+	user, err := server.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		broadcast.Failed++
+		return nil
+	}
+	if user.Inactive != 0 {
+		broadcast.Skipped++
+		return nil
+	}
+
+	for range 3 {
+		_, err = server.TeleBot.Send(tele.ChatID(userID), broadcast.Text)
+		var flood tele.FloodError
+		if !errors.As(err, &flood) {
+			break
+		}
+		wait := time.Duration(flood.RetryAfter) * time.Second
+		if err := lock.Refresh(ctx, wait+time.Minute, nil); err != nil {
+			return err
+		}
+		time.Sleep(wait)
+	}
+
+	switch {
+	case err == nil:
+		broadcast.Sent++
+	case errors.Is(err, tele.ErrBlockedByUser),
+		errors.Is(err, tele.ErrUserIsDeactivated),
+		errors.Is(err, tele.ErrNotStartedByUser),
+		errors.Is(err, tele.ErrChatNotFound):
+		broadcast.Blocked++
+		_, err = server.UpdateUser(ctx, userID, func(user *entity.User) error {
+			user.Inactive = time.Now().Unix()
+			return nil
+		})
+		if err != nil {
+			logrus.Error("mark user inactive error ", err)
+		}
+	default:
+		broadcast.Failed++
+		logrus.Warn("broadcast ", broadcast.Id, " to ", userID, " error ", err)
+	}
+	return nil
+}
+
+// reportBroadcast updates the progress message of the admin
+func (server *Server) reportBroadcast(broadcast entity.Broadcast) {
+	if broadcast.ReportMessageID == "" {
+		return
+	}
+	report := tele.StoredMessage{MessageID: broadcast.ReportMessageID, ChatID: broadcast.AdminID}
+	if _, err := server.TeleBot.Edit(report, broadcastProgress(broadcast)); err != nil && !errors.Is(err, tele.ErrSameMessageContent) {
+		logrus.Warn("broadcast report error ", err)
+	}
+}
+
+func broadcastProgress(broadcast entity.Broadcast) string {
+	icon := "📣"
+	if broadcast.Status == entity.BroadcastCompleted {
+		icon = "✅"
+	}
+	return fmt.Sprintf("%s Broadcast #%d %s: %d/%d\nsent %d · inactive %d · blocked %d · failed %d",
+		icon, broadcast.Id, broadcast.Status, broadcast.Cursor, broadcast.Total,
+		broadcast.Sent, broadcast.Skipped, broadcast.Blocked, broadcast.Failed)
+}
@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
+)
+
+// fakeBotAPI answers the sendMessage calls of the bot with the queued replies of the chat, ok when none is left
+type fakeBotAPI struct {
+	mu      sync.Mutex
+	replies map[string][]string
+	sent    []string
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&params)
+	chatID := fmt.Sprint(params["chat_id"])
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if replies := f.replies[chatID]; len(replies) > 0 {
+		f.replies[chatID] = replies[1:]
+		fmt.Fprint(w, replies[0])
+		return
+	}
+	f.sent = append(f.sent, chatID)
+	fmt.Fprintf(w, `{"ok":true,"result":{"message_id":1,"chat":{"id":%s}}}`, chatID)
+}
+
+const (
+	floodReply   = `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`
+	blockedReply = `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`
+)
+
+func newBroadcastServer(t *testing.T, api *fakeBotAPI) *Server {
+	server := newTestServer(t)
+	server.BroadcastRepo = repository.NewBroadcastRepository(server.DB)
+
+	httpServer := httptest.NewServer(api)
+	t.Cleanup(httpServer.Close)
+
+	bot, err := tele.NewBot(tele.Settings{URL: httpServer.URL, Token: "test", Offline: true})
+	assert.NoError(t, err)
+	server.TeleBot = bot
+	return server
+}
+
+func saveUsers(t *testing.T, server *Server, users ...entity.User) {
+	for _, user := range users {
+		assert.NoError(t, server.UserRepo.Save(context.Background(), &user))
+	}
+}
+
+func TestDeliverBroadcastFloodRefreshesLock(t *testing.T) {
+	api := &fakeBotAPI{replies: map[string][]string{"1": {floodReply}}}
+	server := newBroadcastServer(t, api)
+	ctx := context.Background()
+	saveUsers(t, server, entity.NewUser(1, "player1", 0))
+
+	lock, err := server.Locker.Obtain(ctx, fmt.Sprintf(BROADCAST_LOCK, 1), 500*time.Millisecond, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer lock.Release(ctx)
+
+	// the flood wait outlasts the lock ttl, the lock is refreshed before it
+	broadcast := entity.NewBroadcast(1, 0, "hello", 1)
+	assert.NoError(t, server.deliverBroadcast(ctx, lock, &broadcast, 1))
+	assert.Equal(t, 1, broadcast.Sent)
+	assert.Equal(t, []string{"1"}, api.sent)
+
+	ttl, err := lock.TTL(ctx)
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, 30*time.Second)
+}
+
+func TestDeliverBroadcastLockLost(t *testing.T) {
+	api := &fakeBotAPI{replies: map[string][]string{"1": {floodReply}}}
+	server := newBroadcastServer(t, api)
+	ctx := context.Background()
+	saveUsers(t, server, entity.NewUser(1, "player1", 0))
+
+	lock, err := server.Locker.Obtain(ctx, fmt.Sprintf(BROADCAST_LOCK, 1), time.Minute, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, lock.Release(ctx))
+
+	// another worker may own the broadcast, the flooded user is not retried
+	broadcast := entity.NewBroadcast(1, 0, "hello", 1)
+	assert.Error(t, server.deliverBroadcast(ctx, lock, &broadcast, 1))
+	assert.Equal(t, 0, broadcast.Sent)
+	assert.Empty(t, api.sent)
+}
+
+func TestRunBroadcast(t *testing.T) {
+	api := &fakeBotAPI{replies: map[string][]string{"2": {blockedReply}}}
+	server := newBroadcastServer(t, api)
+	ctx := context.Background()
+
+	inactive := entity.NewUser(3, "player3", 0)
+	inactive.Inactive = time.Now().Unix()
+	saveUsers(t, server, entity.NewUser(1, "player1", 0), entity.NewUser(2, "player2", 0), inactive)
+
+	broadcast := entity.NewBroadcast(1, 0, "hello", 3)
+	assert.NoError(t, server.BroadcastRepo.Save(ctx, &broadcast))
+	server.runBroadcast(broadcast)
+
+	broadcast, err := server.BroadcastRepo.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.BroadcastCompleted, broadcast.Status)
+	assert.Equal(t, int64(3), broadcast.Cursor)
+	assert.Equal(t, 1, broadcast.Sent)
+	assert.Equal(t, 1, broadcast.Blocked)
+	assert.Equal(t, 1, broadcast.Skipped)
+	assert.Equal(t, []string{"1"}, api.sent)
+
+	// the user who blocked the bot is skipped by the next broadcasts
+	blocked, err := server.UserRepo.GetByID(ctx, 2)
+	assert.NoError(t, err)
+	assert.NotZero(t, blocked.Inactive)
+
+	// the lock is released once the broadcast completed
+	locked, err := server.DB.Exists(ctx, fmt.Sprintf(BROADCAST_LOCK, 1)).Result()
+	assert.NoError(t, err)
+	assert.Zero(t, locked)
+}
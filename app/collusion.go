@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/collusion"
+	"github.com/onionj/trust/internal/entity"
+)
+
+// RecordMatch counts a new match of the pair, joinDelta is the time between both lobby joins
+func (server *Server) RecordMatch(ctx context.Context, userID int64, otherID int64, joinDelta time.Duration) {
+	stats, err := server.CollusionRepo.GetPair(ctx, userID, otherID)
+	if err != nil {
+		logrus.Error("get pair stats error ", err)
+		return
+	}
+
+	stats.Games++
+	stats.LastMatch = time.Now().Unix()
+	if joinDelta >= 0 && joinDelta < server.Config.Collusion.QuickJoin {
+		stats.QuickJoins++
+	}
+	server.saveRisk(ctx, stats)
+}
+
+// RecordRounds adds the rounds of a completed game to the pair stats
+func (server *Server) RecordRounds(ctx context.Context, game entity.Game) {
+	stats, err := server.CollusionRepo.GetPair(ctx, game.P1ID, game.P2ID)
+	if err != nil {
+		logrus.Error("get pair stats error ", err)
+		return
+	}
+
+	for _, round := range game.GetRounds() {
+		if round.P1Decision == "" || round.P2Decision == "" {
+			continue
+		}
+		stats.Rounds++
+		if round.P1Decision == entity.Share && round.P2Decision == entity.Share {
+			stats.MutualShares++
+		}
+	}
+	server.saveRisk(ctx, stats)
+}
+
+// PairBlocked reports whether two users are too risky to be matched again now
+func (server *Server) PairBlocked(ctx context.Context, userID int64, otherID int64) bool {
+	cfg := server.Config.Collusion
+	if cfg.BlockScore <= 0 {
+		return false
+	}
+
+	stats, err := server.CollusionRepo.GetPair(ctx, userID, otherID)
+	if err != nil {
+		logrus.Error("get pair stats error ", err)
+		return false
+	}
+	return stats.Score >= cfg.BlockScore && stats.MatchedWithin(cfg.BlockWindow, time.Now())
+}
+
+func (server *Server) saveRisk(ctx context.Context, stats entity.PairStats) {
+	score, reasons := collusion.Score(stats)
+	stats.Score = score
+	stats.Reasons = strings.Join(reasons, ", ")
+
+	if err := server.CollusionRepo.Save(ctx, &stats); err != nil {
+		logrus.Error("save pair stats error ", err)
+		return
+	}
+	if err := server.CollusionRepo.SaveRisk(ctx, stats); err != nil {
+		logrus.Error("save pair risk error ", err)
+	}
+}
@@ -0,0 +1,226 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+// UpdateGameResults saves the game, completes the decided rounds and settles
+// the balances of both players when the last round is completed
+func (server *Server) UpdateGameResults(game entity.Game) error {
+	err := server.GameRepo.Save(context.Background(), &game)
+	if err != nil {
+		logrus.Error(game.Id, " game not saved", err)
+		return err
+	}
+
+	if game.R1Status != entity.Completed {
+		if game.R1P1Decision == entity.Share && game.R1P2Decision == entity.Share {
+			game.R1Winner = entity.P1P2
+			game.R1Status = entity.Completed
+			game.R1Rewards = game.RoundCoopReward()
+		} else if game.R1P1Decision == entity.Steal && game.R1P2Decision == entity.Steal {
+			game.R1Winner = entity.Server
+			game.R1Status = entity.Completed
+		} else if game.R1P1Decision == entity.Share && game.R1P2Decision == entity.Steal {
+			game.R1Winner = entity.P2
+			game.R1Status = entity.Completed
+		} else if game.R1P1Decision == entity.Steal && game.R1P2Decision == entity.Share {
+			game.R1Winner = entity.P1
+			game.R1Status = entity.Completed
+		}
+	}
+	if game.R2Status != entity.Completed {
+		if game.R2P1Decision == entity.Share && game.R2P2Decision == entity.Share {
+			game.R2Winner = entity.P1P2
+			game.R2Status = entity.Completed
+			game.R2Rewards = game.RoundCoopReward()
+		} else if game.R2P1Decision == entity.Steal && game.R2P2Decision == entity.Steal {
+			game.R2Winner = entity.Server
+			game.R2Status = entity.Completed
+		} else if game.R2P1Decision == entity.Share && game.R2P2Decision == entity.Steal {
+			game.R2Winner = entity.P2
+			game.R2Status = entity.Completed
+		} else if game.R2P1Decision == entity.Steal && game.R2P2Decision == entity.Share {
+			game.R2Winner = entity.P1
+			game.R2Status = entity.Completed
+		}
+	}
+	if game.R3Status != entity.Completed {
+		if game.R3P1Decision == entity.Share && game.R3P2Decision == entity.Share {
+			game.R3Winner = entity.P1P2
+			game.R3Status = entity.Completed
+			game.R3Rewards = game.RoundCoopReward()
+		} else if game.R3P1Decision == entity.Steal && game.R3P2Decision == entity.Steal {
+			game.R3Winner = entity.Server
+			game.R3Status = entity.Completed
+		} else if game.R3P1Decision == entity.Share && game.R3P2Decision == entity.Steal {
+			game.R3Winner = entity.P2
+			game.R3Status = entity.Completed
+		} else if game.R3P1Decision == entity.Steal && game.R3P2Decision == entity.Share {
+			game.R3Winner = entity.P1
+			game.R3Status = entity.Completed
+		}
+	}
+	if game.R4Status != entity.Completed {
+		if game.R4P1Decision == entity.Share && game.R4P2Decision == entity.Share {
+			game.R4Winner = entity.P1P2
+			game.R4Status = entity.Completed
+			game.R4Rewards = game.RoundCoopReward()
+		} else if game.R4P1Decision == entity.Steal && game.R4P2Decision == entity.Steal {
+			game.R4Winner = entity.Server
+			game.R4Status = entity.Completed
+		} else if game.R4P1Decision == entity.Share && game.R4P2Decision == entity.Steal {
+			game.R4Winner = entity.P2
+			game.R4Status = entity.Completed
+		} else if game.R4P1Decision == entity.Steal && game.R4P2Decision == entity.Share {
+			game.R4Winner = entity.P1
+			game.R4Status = entity.Completed
+		}
+	}
+
+	// calculate rewards and player coins
+	serverCoins := 0
+	p1 := 0
+	p2 := 0
+
+	if game.Status != entity.Completed && game.Finished() {
+		game.Status = entity.Completed
+
+		perRoundCoin := game.Coins / game.Rounds
+
+		for _, round := range game.GetRounds() {
+			roundP1, roundP2 := game.RoundPayout(round)
+			p1 += roundP1
+			p2 += roundP2
+			serverCoins += max(perRoundCoin-roundP1-roundP2, 0)
+		}
+
+		// save p1 balance
+		_, err := server.UpdateBalance(context.Background(), game.P1ID, p1, entity.LedgerGame, fmt.Sprintf("game %d", game.Id), func(p1User *entity.User) error {
+			p1User.LastGamesResult += fmt.Sprintf("|%d:%d:%d:%d", game.Id, p1, game.P2ID, p2)
+			// TODO: Remove very Old Games
+			return nil
+		})
+		if err != nil {
+			logrus.Error("save p1 balance error ", err)
+		}
+
+		// save p2 balance
+		_, err = server.UpdateBalance(context.Background(), game.P2ID, p2, entity.LedgerGame, fmt.Sprintf("game %d", game.Id), func(p2User *entity.User) error {
+			p2User.LastGamesResult += fmt.Sprintf("|%d:%d:%d:%d", game.Id, p2, game.P1ID, p1)
+			// TODO: Remove very Old Games
+			return nil
+		})
+		if err != nil {
+			logrus.Error("save p2 balance error ", err)
+		}
+
+		server.EvaluateAchievements(context.Background(), game)
+		server.RewardReferrals(context.Background(), game)
+		server.RecordRounds(context.Background(), game)
+		server.AddSeasonPoints(context.Background(), game.P1ID, p1)
+		server.AddSeasonPoints(context.Background(), game.P2ID, p2)
+		if game.Tournament != 0 {
+			if err := server.RecordTournamentGame(context.Background(), game, p1, p2); err != nil {
+				logrus.Error("record tournament game error ", err)
+			}
+		}
+	}
+
+	return server.GameRepo.Save(context.Background(), &game)
+}
+
+// ForfeitGame completes an active game giving the remaining rounds to the competitor of the loser
+func (server *Server) ForfeitGame(ctx context.Context, game entity.Game, loserID int64) error {
+	return server.completeGame(ctx, game.Id, func(game *entity.Game) { game.Forfeit(loserID) })
+}
+
+// completeGame completes the open rounds of an active game with complete under the game lock and settles the game
+func (server *Server) completeGame(ctx context.Context, gameID uint, complete func(game *entity.Game)) error {
+	gameLock, err := server.Locker.Obtain(
+		ctx,
+		fmt.Sprintf(GAME_LOCK, gameID),
+		5*time.Second,
+		&redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 100)},
+	)
+	if err != nil {
+		return fmt.Errorf("game lock error: %w", err)
+	}
+	defer gameLock.Release(ctx)
+
+	game, err := server.GameRepo.GetByID(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	if game.Status != entity.Active {
+		return ErrGameNotActive
+	}
+
+	complete(&game)
+	return server.UpdateGameResults(game)
+}
+
+// ForfeitLateReveal forfeits the sealed game of a player who didn't reveal a round in time, the server takes
+// the open rounds when neither player revealed. The nonce of a choice is kept by the browser only and may be lost.
+// It reports whether the game was completed.
+func (server *Server) ForfeitLateReveal(ctx context.Context, game entity.Game) (bool, error) {
+	now := time.Now()
+	if _, late := game.LateRevealer(now); !late {
+		return false, nil
+	}
+	err := server.completeGame(ctx, game.Id, func(game *entity.Game) {
+		// a reveal may have been saved meanwhile
+		loserID, late := game.LateRevealer(now)
+		if !late {
+			return
+		}
+		if loserID == 0 {
+			game.Abandon()
+		} else {
+			game.Forfeit(loserID)
+		}
+	})
+	if errors.Is(err, ErrGameNotActive) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ForfeitActiveGames forfeits every active game of the user and removes the user from the lobbies
+func (server *Server) ForfeitActiveGames(ctx context.Context, userID int64) error {
+	server.LeaveLobby(ctx, userID)
+
+	// group games go on without the player, the player gets nothing of the remaining rounds
+	groupGame, err := server.ActiveGroupGame(ctx, userID)
+	if err == nil {
+		if _, err := server.forfeitGroupRounds(ctx, groupGame, userID); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, ErrNoGroupGame) {
+		return err
+	}
+
+	games, err := server.UserGames(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, game := range games {
+		if game.Status != entity.Active {
+			continue
+		}
+		if err := server.ForfeitGame(ctx, game, userID); err != nil && !errors.Is(err, ErrGameNotActive) {
+			return err
+		}
+		logrus.Info("game ", game.Id, " forfeited by ", userID)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+var ErrNoGroupGame = errors.New("no active group game")
+
+// JoinGroupLobby puts the user in the group lobby queue
+func (server *Server) JoinGroupLobby(ctx context.Context, userID int64) error {
+	return server.DB.ZAdd(ctx, GROUP_LOBBY_QUEUE, redis.Z{
+		Score:  float64(time.Now().UnixMilli()),
+		Member: userID,
+	}).Err()
+}
+
+// FormGroup starts a group game with the longest waiting players once the lobby is full,
+// or once enough players waited for GroupFillWait. ok is false when no game was started.
+func (server *Server) FormGroup(ctx context.Context) (game entity.GroupGame, ok bool, err error) {
+	cfg := server.Config.Group
+
+	// somebody else is forming a group right now
+	lock, err := server.Locker.Obtain(ctx, GROUP_LOBBY_LOCK, 5*time.Second, nil)
+	if errors.Is(err, redislock.ErrNotObtained) {
+		return game, false, nil
+	} else if err != nil {
+		return game, false, err
+	}
+	defer lock.Release(ctx)
+
+	entries, err := server.queueEntries(ctx, GROUP_LOBBY_QUEUE)
+	if err != nil {
+		return game, false, err
+	}
+
+	players := []int64{}
+	for _, entry := range entries {
+		lobbyUser, err := server.UserRepo.GetByID(ctx, entry.UserID)
+		if err != nil || lobbyUser.IsBanned(time.Now()) {
+			// banned players are not matched
+			server.DB.ZRem(ctx, GROUP_LOBBY_QUEUE, entry.UserID)
+			continue
+		}
+		players = append(players, entry.UserID)
+		if len(players) == cfg.MaxPlayers {
+			break
+		}
+	}
+
+	if len(players) < cfg.MinPlayers {
+		return game, false, nil
+	}
+	if len(players) < cfg.MaxPlayers && time.Since(entries[0].Joined) < cfg.FillWait {
+		return game, false, nil
+	}
+
+	gameID, err := entity.GetOrInitID(server.DB, GROUP_GAME_INDEX)
+	if err != nil {
+		return game, false, err
+	}
+
+	coins := cfg.CoinsPerPlayer * len(players)
+	game = entity.NewGroupGame(gameID, players, cfg.Rounds, coins, int(float64(coins)*cfg.CoopReward), int(cfg.RoundTime.Seconds()))
+	if err := server.GroupRepo.Save(ctx, &game); err != nil {
+		return game, false, err
+	}
+	if err := server.GroupRepo.SetActive(ctx, game); err != nil {
+		return game, false, err
+	}
+	for _, playerID := range players {
+		server.DB.ZRem(ctx, GROUP_LOBBY_QUEUE, playerID)
+	}
+	server.CommitGameTokens(ctx, players...)
+
+	logrus.Info("group game ", game.Id, " started with ", len(players), " players")
+	return game, true, nil
+}
+
+// ActiveGroupGame returns the active group game of the user
+func (server *Server) ActiveGroupGame(ctx context.Context, userID int64) (entity.GroupGame, error) {
+	gameID, err := server.GroupRepo.ActiveID(ctx, userID)
+	if err != nil {
+		return entity.GroupGame{}, err
+	}
+	if gameID == 0 {
+		return entity.GroupGame{}, ErrNoGroupGame
+	}
+	return server.GroupRepo.GetByID(ctx, gameID)
+}
+
+// GroupChoice stores the decision of the player under the game lock and settles the game when every round is decided
+func (server *Server) GroupChoice(ctx context.Context, gameID uint, userID int64, round int, choice string) (entity.GroupGame, error) {
+	gameLock, err := server.Locker.Obtain(
+		ctx,
+		fmt.Sprintf(GROUP_GAME_LOCK, gameID),
+		5*time.Second,
+		&redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 100)},
+	)
+	if err != nil {
+		return entity.GroupGame{}, fmt.Errorf("group game lock error: %w", err)
+	}
+	defer gameLock.Release(ctx)
+
+	game, err := server.GroupRepo.GetByID(ctx, gameID)
+	if err != nil {
+		return game, err
+	}
+	if err := game.Decide(round, userID, choice); err != nil {
+		return game, err
+	}
+
+	if game.Finished() {
+		game.Status = entity.Completed
+		server.settleGroupGame(ctx, game)
+	}
+	return game, server.GroupRepo.Save(ctx, &game)
+}
+
+// ForfeitIdleGroupPlayers forfeits the rest of the game of the players who didn't decide the current round
+// before its deadline, it returns the game with their forfeits
+func (server *Server) ForfeitIdleGroupPlayers(ctx context.Context, game entity.GroupGame) (entity.GroupGame, error) {
+	for _, playerID := range game.IdlePlayers(time.Now()) {
+		var err error
+		game, err = server.forfeitGroupRounds(ctx, game, playerID)
+		if err != nil {
+			return game, err
+		}
+		logrus.Info("group game ", game.Id, " forfeited by idle ", playerID)
+	}
+	return game, nil
+}
+
+// forfeitGroupRounds forfeits every round the player didn't decide yet, the player gets nothing of them
+func (server *Server) forfeitGroupRounds(ctx context.Context, game entity.GroupGame, userID int64) (entity.GroupGame, error) {
+	for round := game.ActiveRound(userID); round != -1; round = game.ActiveRound(userID) {
+		next, err := server.GroupChoice(ctx, game.Id, userID, round, entity.Forfeited)
+		if errors.Is(err, entity.ErrRoundNotOpen) && next.Status == entity.Active {
+			// somebody else decided for the player meanwhile, go on from the saved game
+			game = next
+			continue
+		} else if err != nil {
+			return game, err
+		}
+		game = next
+	}
+	return game, nil
+}
+
+// settleGroupGame credits the coins of every player of a completed group game
+func (server *Server) settleGroupGame(ctx context.Context, game entity.GroupGame) {
+	payout := game.Payout()
+	for seat, playerID := range game.PlayerIDs() {
+		_, err := server.UpdateBalance(ctx, playerID, payout[seat], entity.LedgerGame, fmt.Sprintf("group game %d", game.Id), nil)
+		if err != nil {
+			logrus.Error("save group game balance error ", err)
+		}
+		server.AddSeasonPoints(ctx, playerID, payout[seat])
+	}
+	if err := server.GroupRepo.ClearActive(ctx, game); err != nil {
+		logrus.Error("clear active group game error ", err)
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
+)
+
+func TestForfeitIdleGroupPlayers(t *testing.T) {
+	server := newTestServer(t)
+	server.LedgerRepo = repository.NewLedgerRepository(server.DB)
+	server.SeasonRepo = repository.NewSeasonRepository(server.DB)
+	ctx := context.Background()
+	saveUsers(t, server, entity.NewUser(10, "player10", 0), entity.NewUser(11, "player11", 0), entity.NewUser(12, "player12", 0))
+
+	game := entity.NewGroupGame(1, []int64{10, 11, 12}, 2, 300, 0, 30)
+	if !assert.NoError(t, server.GroupRepo.Save(ctx, &game)) {
+		return
+	}
+	assert.NoError(t, server.GroupRepo.SetActive(ctx, game))
+
+	// nobody is idle before the deadline
+	game, err := server.GroupChoice(ctx, game.Id, 10, 1, entity.Steal)
+	assert.NoError(t, err)
+	game, err = server.GroupChoice(ctx, game.Id, 11, 1, entity.Share)
+	assert.NoError(t, err)
+	unchanged, err := server.ForfeitIdleGroupPlayers(ctx, game)
+	assert.NoError(t, err)
+	assert.Equal(t, game.Decisions, unchanged.Decisions)
+
+	// the idle player forfeits every remaining round
+	game.RoundBy = 1
+	assert.NoError(t, server.GroupRepo.Save(ctx, &game))
+	game, err = server.ForfeitIdleGroupPlayers(ctx, game)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{entity.Steal, entity.Share, entity.Forfeited}, game.RoundDecisions(1))
+	assert.Equal(t, []string{"", "", entity.Forfeited}, game.RoundDecisions(2))
+	assert.Equal(t, -1, game.ActiveRound(12))
+
+	// the other players finish the game without waiting for the idle player
+	_, err = server.GroupChoice(ctx, game.Id, 10, 2, entity.Share)
+	assert.NoError(t, err)
+	game, err = server.GroupChoice(ctx, game.Id, 11, 2, entity.Share)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Completed, game.Status)
+
+	_, err = server.ActiveGroupGame(ctx, 12)
+	assert.ErrorIs(t, err, ErrNoGroupGame)
+}
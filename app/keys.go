@@ -0,0 +1,27 @@
+package app
+
+// Redis keys shared between web and telegram handlers
+const LOBBY_QUEUE = "trust:lobby:%s:queue"
+const LOBBY_LOCK = "trust:lobby:%s:lock"
+const PAIR_MATCH_LIMIT = "trust:rematch:%d:%d"
+const USER_LOCK = "trust:user%d:lock"
+const USER_LOCK_BALANCE = "trust:user%d:lock_balance"
+const USER_LOCK_DAILY = "trust:user%d:lock_daily"
+const USER_LOCK_SHOP = "trust:user%d:lock_shop"
+const GAME_LOCK = "trust:game%d:lock"
+const GAME_INDEX = "trust:game:index"
+const GROUP_LOBBY_QUEUE = "trust:group_lobby:queue"
+const GROUP_LOBBY_LOCK = "trust:group_lobby:lock"
+const GROUP_GAME_INDEX = "trust:group_game:index"
+const GROUP_GAME_LOCK = "trust:group_game%d:lock"
+const GAME_USER_HOUR_WINDOW = "trust:user%d:hour:window"
+const USER_BONUS_TOKENS = "trust:user%d:bonus_tokens"
+const GAME_TOKEN_RESERVATION = "trust:user%d:token_reservation"
+const HTTP_RATE_LIMIT_IP = "trust:ratelimit:ip:%s"
+const HTTP_RATE_LIMIT_USER = "trust:ratelimit:user%d"
+const BROADCAST_INDEX = "trust:broadcast:index"
+const BROADCAST_LOCK = "trust:broadcast%d:lock"
+const TOURNAMENT_INDEX = "trust:tournament:index"
+const TOURNAMENT_LOCK = "trust:tournament%d:lock"
+const SEASON_INDEX = "trust:season:index"
+const SEASON_LOCK = "trust:season:lock"
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+// LOBBY_WAIT is how long a player waits in the lobby for an opponent
+const LOBBY_WAIT = 30 * time.Second
+
+// LobbyEntry is a player waiting in the lobby
+type LobbyEntry struct {
+	UserID int64
+	Joined time.Time
+}
+
+// LobbyEntries returns the players waiting in the lobby of the game mode, longest waiting first
+func (server *Server) LobbyEntries(ctx context.Context, mode string) ([]LobbyEntry, error) {
+	return server.queueEntries(ctx, fmt.Sprintf(LOBBY_QUEUE, mode))
+}
+
+// queueEntries returns the players waiting in the lobby queue, longest waiting first
+func (server *Server) queueEntries(ctx context.Context, queue string) ([]LobbyEntry, error) {
+	// drop players whose wait is over
+	expired := time.Now().Add(-LOBBY_WAIT).UnixMilli()
+	server.DB.ZRemRangeByScore(ctx, queue, "-inf", fmt.Sprintf("(%d", expired))
+
+	members, err := server.DB.ZRangeWithScores(ctx, queue, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]LobbyEntry, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.ParseInt(fmt.Sprint(member.Member), 10, 64)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, LobbyEntry{UserID: userID, Joined: time.UnixMilli(int64(member.Score))})
+	}
+	return entries, nil
+}
+
+// JoinLobby puts the user in the lobby queue of the game mode
+func (server *Server) JoinLobby(ctx context.Context, mode string, userID int64) error {
+	return server.DB.ZAdd(ctx, fmt.Sprintf(LOBBY_QUEUE, mode), redis.Z{
+		Score:  float64(time.Now().UnixMilli()),
+		Member: userID,
+	}).Err()
+}
+
+// LeaveLobby removes the user from the lobby queues of every game mode and the group lobby
+func (server *Server) LeaveLobby(ctx context.Context, userID int64) {
+	for _, mode := range server.Config.Modes.All() {
+		server.DB.ZRem(ctx, fmt.Sprintf(LOBBY_QUEUE, mode.Name), userID)
+	}
+	server.DB.ZRem(ctx, GROUP_LOBBY_QUEUE, userID)
+}
+
+// FindOpponent takes the longest waiting player the user may be matched with out of the lobby
+// of the game mode, ok is false when nobody fits and the user should wait for another opponent.
+// The caller must hold the LOBBY_LOCK of the mode.
+func (server *Server) FindOpponent(ctx context.Context, mode string, userID int64) (opponent LobbyEntry, ok bool, err error) {
+	entries, err := server.LobbyEntries(ctx, mode)
+	if err != nil {
+		return opponent, false, err
+	}
+
+	for _, entry := range entries {
+		if entry.UserID == userID {
+			continue
+		}
+
+		lobbyUser, err := server.UserRepo.GetByID(ctx, entry.UserID)
+		if err != nil || lobbyUser.IsBanned(time.Now()) {
+			// banned players are not matched
+			server.LeaveLobby(ctx, entry.UserID)
+			continue
+		}
+
+		if !server.CanRematch(ctx, userID, entry.UserID) || server.PairBlocked(ctx, userID, entry.UserID) {
+			continue
+		}
+
+		server.LeaveLobby(ctx, entry.UserID)
+		return entry, true, nil
+	}
+	return opponent, false, nil
+}
+
+// StartLobbyGame creates the game of the user and the lobby opponent, the reserved game tokens of both
+// players are committed once the game is saved and stay pending for the callers to release otherwise
+func (server *Server) StartLobbyGame(ctx context.Context, mode entity.GameMode, userID int64, opponent LobbyEntry) (entity.Game, error) {
+	gameID, err := entity.GetOrInitID(server.DB, GAME_INDEX)
+	if err != nil {
+		return entity.Game{}, err
+	}
+	game := entity.NewModeGame(gameID, userID, opponent.UserID, mode)
+	if err := server.GameRepo.Save(ctx, &game); err != nil {
+		return game, err
+	}
+
+	server.CommitGameTokens(ctx, userID, opponent.UserID)
+	server.CountRematch(ctx, userID, opponent.UserID)
+	server.RecordMatch(ctx, userID, opponent.UserID, time.Since(opponent.Joined))
+	return game, nil
+}
+
+// CanRematch reports whether the two users are still below the repeat limit of the current window
+func (server *Server) CanRematch(ctx context.Context, userID int64, otherID int64) bool {
+	cfg := server.Config.Matchmaking
+	if cfg.RepeatLimit <= 0 {
+		return true
+	}
+
+	count, err := server.DB.Get(ctx, pairMatchKey(userID, otherID)).Int()
+	if err != nil && err != redis.Nil {
+		logrus.Error("get pair match count error ", err)
+		return true
+	}
+	return count < cfg.RepeatLimit
+}
+
+// CountRematch counts a match of the two users in the current repeat window
+func (server *Server) CountRematch(ctx context.Context, userID int64, otherID int64) {
+	cfg := server.Config.Matchmaking
+	if cfg.RepeatLimit <= 0 {
+		return
+	}
+
+	key := pairMatchKey(userID, otherID)
+	pipe := server.DB.TxPipeline()
+	pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, cfg.RepeatWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.Error("count pair match error ", err)
+	}
+}
+
+func pairMatchKey(userID int64, otherID int64) string {
+	if otherID < userID {
+		userID, otherID = otherID, userID
+	}
+	return fmt.Sprintf(PAIR_MATCH_LIMIT, userID, otherID)
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+func TestFindOpponent(t *testing.T) {
+	server := newTestServer(t)
+	server.Config.Collusion.BlockScore = 0
+	server.Config.Matchmaking.RepeatLimit = 1
+	ctx := context.Background()
+	mode := server.Config.Modes.Default().Name
+
+	banned := entity.NewUser(2, "player2", 0)
+	banned.BannedUntil = entity.BanPermanent
+	saveUsers(t, server, entity.NewUser(1, "player1", 0), banned, entity.NewUser(3, "player3", 0), entity.NewUser(4, "player4", 0))
+	for _, userID := range []int64{1, 2, 3, 4} {
+		assert.NoError(t, server.JoinLobby(ctx, mode, userID))
+		time.Sleep(2 * time.Millisecond) // distinct join times
+	}
+
+	// player 3 hit the repeat limit with player 1, the banned player leaves the lobby
+	server.CountRematch(ctx, 1, 3)
+	opponent, ok, err := server.FindOpponent(ctx, mode, 1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), opponent.UserID)
+
+	entries, err := server.LobbyEntries(ctx, mode)
+	assert.NoError(t, err)
+	waiting := []int64{}
+	for _, entry := range entries {
+		waiting = append(waiting, entry.UserID)
+	}
+	assert.Equal(t, []int64{1, 3}, waiting)
+
+	// nobody else fits, the user keeps waiting
+	_, ok, err = server.FindOpponent(ctx, mode, 1)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// the longest waiting player fits anyone else
+	opponent, ok, err = server.FindOpponent(ctx, mode, 4)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), opponent.UserID)
+}
+
+func TestRematchCount(t *testing.T) {
+	server := newTestServer(t)
+	server.Config.Matchmaking.RepeatLimit = 2
+	server.Config.Matchmaking.RepeatWindow = time.Minute
+	ctx := context.Background()
+
+	// the pair is counted in either order
+	assert.True(t, server.CanRematch(ctx, 1, 2))
+	server.CountRematch(ctx, 1, 2)
+	assert.True(t, server.CanRematch(ctx, 2, 1))
+	server.CountRematch(ctx, 2, 1)
+	assert.False(t, server.CanRematch(ctx, 1, 2))
+	assert.True(t, server.CanRematch(ctx, 1, 3))
+
+	// the count expires with the window
+	ttl, err := server.DB.TTL(ctx, pairMatchKey(1, 2)).Result()
+	assert.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0))
+	assert.LessOrEqual(t, ttl, time.Minute)
+
+	// a disabled limit never blocks the pair
+	server.Config.Matchmaking.RepeatLimit = 0
+	assert.True(t, server.CanRematch(ctx, 1, 2))
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/payment"
+	"github.com/onionj/trust/pkg/ratelimit"
+)
+
+// paymentWallet credits the Telegram Stars packs, every change is recorded in the ledger
+type paymentWallet struct {
+	server *Server
+}
+
+// Credit credits the coins then the tokens, the coins are taken back when the tokens can't be granted
+func (w paymentWallet) Credit(ctx context.Context, purchase entity.Purchase) error {
+	note := fmt.Sprintf("stars %s charge %s", purchase.PackID, purchase.ChargeID)
+	if err := w.change(ctx, purchase.UserID, purchase.Coins, note); err != nil {
+		return err
+	}
+
+	err := w.server.GrantBonusTokens(ctx, purchase.UserID, purchase.Tokens)
+	if err == nil || purchase.Coins == 0 {
+		return err
+	}
+	note = fmt.Sprintf("stars failed %s charge %s", purchase.PackID, purchase.ChargeID)
+	if _, revertErr := w.server.UpdateBalance(ctx, purchase.UserID, -purchase.Coins, entity.LedgerStars, note, nil); revertErr != nil {
+		return errors.Join(err, revertErr, payment.ErrPartialCredit)
+	}
+	return err
+}
+
+// Revoke takes the tokens then the coins back, nothing is taken when the user already spent either
+func (w paymentWallet) Revoke(ctx context.Context, purchase entity.Purchase) error {
+	taken, err := ratelimit.TakeBonusTokens(w.server.DB, fmt.Sprintf(USER_BONUS_TOKENS, purchase.UserID), purchase.Tokens)
+	if err != nil {
+		return err
+	}
+	if taken < purchase.Tokens {
+		return w.returnTokens(ctx, purchase.UserID, taken, payment.ErrPackSpent)
+	}
+
+	note := fmt.Sprintf("stars refund %s charge %s", purchase.PackID, purchase.ChargeID)
+	if err := w.change(ctx, purchase.UserID, -purchase.Coins, note); err != nil {
+		return w.returnTokens(ctx, purchase.UserID, taken, err)
+	}
+	return nil
+}
+
+// returnTokens gives back the tokens taken by a failed revoke, err is the failure
+func (w paymentWallet) returnTokens(ctx context.Context, userID int64, tokens int, err error) error {
+	if grantErr := w.server.GrantBonusTokens(ctx, userID, tokens); grantErr != nil {
+		return errors.Join(err, grantErr)
+	}
+	return err
+}
+
+// Reclaim takes the pack back after Telegram returned the stars, the coins the user already
+// spent are left as a negative balance that later credits pay back
+func (w paymentWallet) Reclaim(ctx context.Context, purchase entity.Purchase) error {
+	note := fmt.Sprintf("stars refund %s charge %s", purchase.PackID, purchase.ChargeID)
+	user, err := w.server.UpdateUser(ctx, purchase.UserID, func(user *entity.User) error {
+		user.Balance -= purchase.Coins
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = w.server.LedgerRepo.Append(ctx, entity.NewLedgerEntry(purchase.UserID, -purchase.Coins, user.Balance, entity.LedgerStars, note))
+	if err != nil {
+		logrus.Error("ledger append error ", err)
+	}
+	_, err = ratelimit.TakeBonusTokens(w.server.DB, fmt.Sprintf(USER_BONUS_TOKENS, purchase.UserID), purchase.Tokens)
+	return err
+}
+
+// change updates the balance, token packs don't change it but are still recorded in the ledger
+func (w paymentWallet) change(ctx context.Context, userID int64, coins int, note string) error {
+	user, err := w.server.UpdateBalance(ctx, userID, coins, entity.LedgerStars, note, nil)
+	if err != nil || coins != 0 {
+		return err
+	}
+	return w.server.LedgerRepo.Append(ctx, entity.NewLedgerEntry(userID, 0, user.Balance, entity.LedgerStars, note))
+}
+
+// AdminRefund returns the stars of a purchase and takes the pack back
+func (server *Server) AdminRefund(ctx context.Context, adminID int64, chargeID string, reason string) (entity.Purchase, error) {
+	purchase, err := server.Payments.Refund(ctx, chargeID)
+	if err != nil {
+		return purchase, err
+	}
+	server.Audit(ctx, entity.NewAuditEntry(adminID, entity.AuditRefund, purchase.UserID, reason,
+		fmt.Sprintf("%s %d stars charge %s", purchase.PackID, purchase.Stars, chargeID)))
+	server.notify(purchase.UserID, fmt.Sprintf("↩️ Your %d ⭐ payment was refunded.", purchase.Stars))
+	return purchase, nil
+}
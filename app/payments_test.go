@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/payment"
+	"github.com/onionj/trust/internal/repository"
+	"github.com/onionj/trust/pkg/ratelimit"
+)
+
+func TestRevokeSpentPack(t *testing.T) {
+	server := newTestServer(t)
+	server.LedgerRepo = repository.NewLedgerRepository(server.DB)
+	wallet := paymentWallet{server: server}
+	ctx := context.Background()
+	saveUsers(t, server, entity.NewUser(1, "player1", 0))
+	bonusKey := fmt.Sprintf(USER_BONUS_TOKENS, 1)
+
+	// a token pack with spent tokens keeps the tokens left
+	tokens := entity.NewPurchase("charge1", 1, "tokens_30", 25, 0, 30)
+	assert.NoError(t, wallet.Credit(ctx, tokens))
+	_, err := ratelimit.TakeBonusTokens(server.DB, bonusKey, 5)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, wallet.Revoke(ctx, tokens), payment.ErrPackSpent)
+	left, err := server.DB.Get(ctx, bonusKey).Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 25, left)
+
+	// a pack with spent coins gives its tokens back
+	mixed := entity.NewPurchase("charge2", 1, "mixed", 50, 100, 10)
+	assert.NoError(t, wallet.Credit(ctx, mixed))
+	_, err = server.UpdateBalance(ctx, 1, -50, entity.LedgerPurchase, "spent", nil)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, wallet.Revoke(ctx, mixed), ErrInsufficientBalance)
+	left, err = server.DB.Get(ctx, bonusKey).Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 35, left)
+
+	// an unspent pack is taken back whole
+	assert.NoError(t, wallet.Revoke(ctx, tokens))
+	user, err := server.UserRepo.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, user.Balance)
+	left, err = server.DB.Get(ctx, bonusKey).Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, left)
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+const REFERRAL_PAYLOAD_PREFIX = "ref_"
+
+var errReferralRewarded = errors.New("referral already rewarded")
+
+// ReferralLink returns the bot deep link that attributes new users to the referrer
+func (server *Server) ReferralLink(referrerID int64) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s%d", server.TeleBot.Me.Username, REFERRAL_PAYLOAD_PREFIX, referrerID)
+}
+
+// AttributeReferral sets the referrer of a new user from the /start payload.
+// Self referrals, unknown referrers and referrers over the cap are ignored.
+func (server *Server) AttributeReferral(ctx context.Context, user *entity.User, payload string) {
+	if !strings.HasPrefix(payload, REFERRAL_PAYLOAD_PREFIX) {
+		return
+	}
+	referrerID, err := strconv.ParseInt(strings.TrimPrefix(payload, REFERRAL_PAYLOAD_PREFIX), 10, 64)
+	if err != nil || referrerID == user.Id {
+		return
+	}
+
+	if _, err := server.UserRepo.GetByID(ctx, referrerID); err != nil {
+		return
+	}
+
+	added, err := server.ReferralRepo.Add(ctx, referrerID, user.Id, server.Config.Reward.ReferralMaxPerUser)
+	if err != nil {
+		logrus.Error("add referral error ", err)
+		return
+	} else if !added {
+		return
+	}
+	user.ReferrerID = referrerID
+}
+
+// RewardReferrals pays the referral bonus to the players of a completed game
+// and their referrers once the player completed enough games
+func (server *Server) RewardReferrals(ctx context.Context, game entity.Game) {
+	cfg := server.Config.Reward
+
+	for _, userID := range []int64{game.P1ID, game.P2ID} {
+		user, err := server.UserRepo.GetByID(ctx, userID)
+		if err != nil || user.ReferrerID == 0 || user.ReferralRewarded != 0 {
+			continue
+		}
+		progress, err := server.AchieveRepo.GetProgress(ctx, userID)
+		if err != nil || progress.GamesPlayed < cfg.ReferralMinGames {
+			continue
+		}
+
+		_, err = server.UpdateBalance(ctx, userID, cfg.ReferralBonus, entity.LedgerReferral, fmt.Sprintf("referred by %d", user.ReferrerID), func(user *entity.User) error {
+			if user.ReferralRewarded != 0 {
+				return errReferralRewarded
+			}
+			user.ReferralRewarded = time.Now().Unix()
+			return nil
+		})
+		if err != nil {
+			if !errors.Is(err, errReferralRewarded) {
+				logrus.Error("referee bonus error ", err)
+			}
+			continue
+		}
+
+		_, err = server.UpdateBalance(ctx, user.ReferrerID, cfg.ReferralBonus, entity.LedgerReferral, fmt.Sprintf("referral %d", userID), nil)
+		if err != nil {
+			logrus.Error("referrer bonus error ", err)
+		}
+
+		server.notify(userID, fmt.Sprintf("🎉 You Win %d Coins for joining with an invite!", cfg.ReferralBonus))
+		server.notify(user.ReferrerID, fmt.Sprintf("🎉 You Win %d Coins, %s played %d games!", cfg.ReferralBonus, user.DisplayName, cfg.ReferralMinGames))
+	}
+}
+
+// notify sends a message to the user through the bot without blocking the caller
+func (server *Server) notify(userID int64, text string) {
+	go func() {
+		if _, err := server.TeleBot.Send(tele.ChatID(userID), text); err != nil {
+			logrus.Warn("notify user error ", err)
+		}
+	}()
+}
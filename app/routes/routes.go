@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"net"
 	"net/http"
 	"time"
 
@@ -19,13 +20,40 @@ import (
 	"github.com/onionj/trust/app/webhandlers"
 	"github.com/onionj/trust/internal/entity"
 	"github.com/onionj/trust/internal/repository"
+	"github.com/onionj/trust/pkg/ratelimit"
 )
 
+// ipExtractor reads the client ip from X-Forwarded-For only when the request comes from a trusted proxy,
+// the connection address is used when no proxy is trusted
+func ipExtractor(trusted []*net.IPNet) echo.IPExtractor {
+	if len(trusted) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, ipRange := range trusted {
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
 func MountWebRoutes(server *app.Server, embeddedFiles embed.FS) {
 	gameHandler := webhandlers.NewGameHandlers(server)
 	authHandler := webhandlers.NewAuthHandlers(server)
+	profileHandler := webhandlers.NewProfileHandlers(server)
+	rewardHandler := webhandlers.NewRewardHandlers(server)
+	referralHandler := webhandlers.NewReferralHandlers(server)
+	seasonHandler := webhandlers.NewSeasonHandlers(server)
+	shopHandler := webhandlers.NewShopHandlers(server)
+	adminHandler := webhandlers.NewAdminHandlers(server)
+	groupHandler := webhandlers.NewGroupHandlers(server)
+	tournamentHandler := webhandlers.NewTournamentHandlers(server)
 
+	server.Echo.IPExtractor = ipExtractor(server.Config.HTTP.TrustedProxies)
 	server.Echo.Use(middleware.Recover())
+	server.Echo.Use(ratelimit.Middleware(ratelimit.MiddlewareConfig{
+		Limiter: ratelimit.NewTokenBucket(server.DB, server.Config.RateLimit.IPBurst, server.Config.RateLimit.IPInterval),
+		Key:     ratelimit.KeyByIP(app.HTTP_RATE_LIMIT_IP),
+	}))
 	server.Echo.Use(middleware.GzipWithConfig(middleware.GzipConfig{}))
 	server.Echo.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -81,6 +109,40 @@ func MountWebRoutes(server *app.Server, embeddedFiles embed.FS) {
 	game.GET("/game", gameHandler.StartGame, authHandler.AuthorizeMiddleware)
 	game.GET("/game-update/:gameID", gameHandler.GetGameUpdate, authHandler.AuthorizeMiddleware)
 	game.GET("/game-choice/:gameID/:roundID/:choice", gameHandler.GameChoice, authHandler.AuthorizeMiddleware)
+	game.GET("/game-commit/:gameID/:roundID/:commitment", gameHandler.CommitChoice, authHandler.AuthorizeMiddleware)
+	game.GET("/game-reveal/:gameID/:roundID/:choice/:nonce", gameHandler.RevealChoice, authHandler.AuthorizeMiddleware)
+	game.GET("/replay/:gameID", gameHandler.OpenReplay, authHandler.AuthorizeMiddleware)
+	game.GET("/group", groupHandler.StartGroupGame, authHandler.AuthorizeMiddleware)
+	game.GET("/group-update/:gameID", groupHandler.GetGroupUpdate, authHandler.AuthorizeMiddleware)
+	game.GET("/group-choice/:gameID/:roundID/:choice", groupHandler.GroupChoice, authHandler.AuthorizeMiddleware)
+	game.GET("/daily", rewardHandler.ClaimDaily, authHandler.AuthorizeMiddleware)
+	game.GET("/referrals", referralHandler.OpenReferrals, authHandler.AuthorizeMiddleware)
+	game.GET("/leaderboard", seasonHandler.OpenLeaderboard, authHandler.AuthorizeMiddleware)
+
+	shop := server.Echo.Group("/shop", authHandler.AuthorizeMiddleware)
+	shop.GET("", shopHandler.OpenShop)
+	shop.GET("/buy/:itemID", shopHandler.Buy)
+	shop.GET("/stars/:packID", shopHandler.BuyStars)
+
+	profile := server.Echo.Group("/profile", authHandler.AuthorizeMiddleware)
+	profile.GET("", profileHandler.OpenProfile)
+	profile.GET("/avatar/:avatarID", profileHandler.SelectAvatar)
+	profile.GET("/avatar/:avatarID/buy", profileHandler.BuyAvatar)
+
+	tournaments := server.Echo.Group("/tournaments", authHandler.AuthorizeMiddleware)
+	tournaments.GET("", tournamentHandler.OpenTournaments)
+	tournaments.GET("/:tournamentID", tournamentHandler.OpenTournament)
+	tournaments.GET("/:tournamentID/register", tournamentHandler.Register)
+
+	admin := server.Echo.Group("/admin", authHandler.AuthorizeMiddleware, adminHandler.AdminMiddleware)
+	admin.GET("", adminHandler.OpenAdmin)
+	admin.GET("/risk", adminHandler.OpenRisk)
+	admin.GET("/user/:userID", adminHandler.OpenUser)
+	admin.POST("/user/:userID/balance", adminHandler.AdjustBalance)
+	admin.POST("/user/:userID/hour-limit", adminHandler.SetHourLimit)
+	admin.POST("/user/:userID/ban", adminHandler.Ban)
+	admin.POST("/user/:userID/unban", adminHandler.Unban)
+	admin.POST("/user/:userID/game/:gameID/complete", adminHandler.CompleteGame)
 }
 
 // Telegram user count per year
@@ -111,32 +173,55 @@ func coinPerAccountAge(userID int64) int {
 	}
 }
 
+// paymentUpdate reports whether the update is a step of a Stars payment,
+// these are handled for banned users too so every payment is answered, credited and refunded
+func paymentUpdate(c tele.Context) bool {
+	if c.PreCheckoutQuery() != nil {
+		return true
+	}
+	message := c.Message()
+	return message != nil && (message.Payment != nil || message.RefundedPayment != nil)
+}
+
 func MountTelegramRoutes(server *app.Server) {
 
 	server.TeleBot.Use(func(next tele.HandlerFunc) tele.HandlerFunc {
 		return func(c tele.Context) error {
 			logrus.Info("tel: Username:", c.Sender().Username, " ID:", c.Sender().ID, " Text:", c.Text())
 
-			user, err := server.UserRepo.Get(
-				context.Background(),
-				fmt.Sprintf("user:%d", c.Sender().ID))
+			user, err := server.UserRepo.GetByID(context.Background(), c.Sender().ID)
 
 			if err == nil {
+				if user.Inactive != 0 {
+					// the user talks to the bot again, deliver broadcasts again
+					user, err = server.UpdateUser(context.Background(), user.Id, func(user *entity.User) error {
+						user.Inactive = 0
+						return nil
+					})
+					if err != nil {
+						logrus.Error("reactivate user err: ", err)
+						return err
+					}
+				}
+				if user.IsBanned(time.Now()) && !paymentUpdate(c) {
+					return c.Send(fmt.Sprint("⛔ Your account is banned: ", user.BanReason))
+				}
 				c.Set("user", user)
 
 			} else if errors.Is(err, repository.ErrNotFound) {
-				err := server.UserRepo.Save(context.Background(),
-					entity.NewUser(c.Sender().ID,
-						fmt.Sprintf("%s %s", c.Sender().FirstName, c.Sender().LastName),
-						coinPerAccountAge(c.Sender().ID),
-					))
+				newUser := entity.NewUser(c.Sender().ID,
+					fmt.Sprintf("%s %s", c.Sender().FirstName, c.Sender().LastName),
+					coinPerAccountAge(c.Sender().ID),
+				)
+				if c.Message() != nil {
+					server.AttributeReferral(context.Background(), &newUser, c.Message().Payload)
+				}
+				err := server.UserRepo.Save(context.Background(), &newUser)
 				if err != nil {
 					logrus.Error("save user err: ", err)
 					return err
 				}
-				user, err := server.UserRepo.Get(
-					context.Background(),
-					fmt.Sprintf("user:%d", c.Sender().ID))
+				user, err := server.UserRepo.GetByID(context.Background(), c.Sender().ID)
 
 				if err != nil {
 					logrus.Error("get user after save err: ", err)
@@ -156,4 +241,20 @@ func MountTelegramRoutes(server *app.Server) {
 
 	startHandlers := telhandlers.NewStartHandlers(server)
 	server.TeleBot.Handle("/start", startHandlers.Start)
+
+	paymentHandlers := telhandlers.NewPaymentHandlers(server)
+	server.TeleBot.Handle("/buy", paymentHandlers.Buy)
+	server.TeleBot.Handle(tele.OnCheckout, paymentHandlers.Checkout)
+	server.TeleBot.Handle(tele.OnPayment, paymentHandlers.Payment)
+	server.TeleBot.Handle(tele.OnRefund, paymentHandlers.Refund)
+
+	adminHandlers := telhandlers.NewAdminHandlers(server)
+	server.TeleBot.Handle("/stats", adminHandlers.Stats, adminHandlers.OnlyAdmins)
+	server.TeleBot.Handle("/tournament", adminHandlers.Tournament, adminHandlers.OnlyAdmins)
+	server.TeleBot.Handle("/user", adminHandlers.User, adminHandlers.OnlyAdmins)
+	server.TeleBot.Handle("/grant", adminHandlers.Grant, adminHandlers.OnlyAdmins)
+	server.TeleBot.Handle("/refund", adminHandlers.Refund, adminHandlers.OnlyAdmins)
+	server.TeleBot.Handle("/ban", adminHandlers.Ban, adminHandlers.OnlyAdmins)
+	server.TeleBot.Handle("/broadcast", adminHandlers.Broadcast, adminHandlers.OnlyAdmins)
+	server.TeleBot.Handle("/lobby", adminHandlers.Lobby, adminHandlers.OnlyAdmins)
 }
@@ -0,0 +1,47 @@
+package routes
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestPaymentUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		update tele.Update
+		want   bool
+	}{
+		{"pre checkout", tele.Update{PreCheckoutQuery: &tele.PreCheckoutQuery{}}, true},
+		{"payment", tele.Update{Message: &tele.Message{Payment: &tele.Payment{}}}, true},
+		{"refund", tele.Update{Message: &tele.Message{RefundedPayment: &tele.RefundedPayment{}}}, true},
+		{"text", tele.Update{Message: &tele.Message{Text: "/start"}}, false},
+		{"callback", tele.Update{Callback: &tele.Callback{}}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, paymentUpdate(tele.NewContext(nil, test.update)))
+		})
+	}
+}
+
+func TestIPExtractor(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	request.RemoteAddr = "10.0.0.2:4000"
+	request.Header.Set(echo.HeaderXForwardedFor, "203.0.113.7")
+
+	// without a trusted proxy the header is ignored
+	assert.Equal(t, "10.0.0.2", ipExtractor(nil)(request))
+
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/24")
+	assert.Equal(t, "203.0.113.7", ipExtractor([]*net.IPNet{proxies})(request))
+
+	// a client outside the trusted ranges can't choose its ip
+	request.RemoteAddr = "198.51.100.9:4000"
+	assert.Equal(t, "198.51.100.9", ipExtractor([]*net.IPNet{proxies})(request))
+}
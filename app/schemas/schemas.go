@@ -1,6 +1,11 @@
 package schemas
 
-import "github.com/onionj/trust/internal/entity"
+import (
+	"github.com/onionj/trust/internal/achievement"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/payment"
+	"github.com/onionj/trust/pkg/ratelimit"
+)
 
 type GameShortReport struct {
 	CompetitorName     string
@@ -12,6 +17,12 @@ type GameShortReport struct {
 type MenuData struct {
 	User            entity.User
 	GameShortReport []GameShortReport
+	Achievements    []achievement.Definition
+	DailyClaimed    bool
+	IsAdmin         bool
+	Modes           []entity.GameMode
+	Wallet          ratelimit.Wallet
+	TokensResetIn   string // '' when the hourly allowance is full
 }
 
 type GameData struct {
@@ -19,4 +30,171 @@ type GameData struct {
 	Game           entity.Game
 	GameResults    map[string]string
 	GameResultsSum string
+	ModeTitle      string
+	CoopBonus      int
+	StealRefund    int
+	SuckerCoins    int
+}
+
+type AvatarItem struct {
+	Avatar   entity.Avatar
+	Owned    bool
+	Selected bool
+}
+
+type ProfileData struct {
+	User    entity.User
+	Avatars []AvatarItem
+}
+
+type RefereeReport struct {
+	Name        string
+	AvatarID    int
+	GamesPlayed int
+	Rewarded    bool
+}
+
+type ReferralData struct {
+	User     entity.User
+	Link     string
+	Bonus    int
+	MinGames int
+	MaxCount int
+	Earned   int
+	Referees []RefereeReport
+}
+
+type AdminData struct {
+	Query     string
+	Users     []entity.User
+	LobbyUser *entity.User
+	Audit     []entity.AuditEntry
+}
+
+type AdminUserData struct {
+	User        entity.User
+	Risk        float64
+	Banned      bool
+	Progress    entity.AchievementProgress
+	ActiveGames []entity.Game
+	Games       []entity.Game
+	Ledger      []entity.LedgerEntry
+	Audit       []entity.AuditEntry
+}
+
+type PairRiskReport struct {
+	Stats  entity.PairStats
+	P1Name string
+	P2Name string
+}
+
+type AdminRiskData struct {
+	Pairs []PairRiskReport
+}
+
+type ReplayRound struct {
+	Number             int
+	YourDecision       string
+	CompetitorDecision string
+	YourCoins          int
+	CompetitorCoins    int
+	YourCommit         string
+	CompetitorCommit   string
+	YourNonce          string
+	CompetitorNonce    string
+	Verified           bool
+}
+
+type ReplayData struct {
+	Game       entity.Game
+	Competitor entity.User
+	Rounds     []ReplayRound
+	Verified   bool
+}
+
+type GroupRound struct {
+	Number       int
+	YourDecision string
+	Completed    bool
+	Shares       int
+	Steals       int
+	YourCoins    int
+}
+
+type GroupData struct {
+	Game        entity.GroupGame
+	Players     []entity.User
+	Rounds      []GroupRound
+	RoundCoins  int
+	ActiveRound int
+	TotalCoins  int
+	Sum         string
+}
+
+type TournamentItem struct {
+	Tournament entity.Tournament
+	Players    int
+	Registered bool
+}
+
+type TournamentsData struct {
+	Tournaments []TournamentItem
+}
+
+type TournamentMatchView struct {
+	Match  entity.TournamentMatch
+	P1Name string
+	P2Name string
+}
+
+type TournamentRoundView struct {
+	Number  int
+	Matches []TournamentMatchView
+}
+
+type TournamentStandingView struct {
+	Place int
+	Name  string
+	Wins  int
+	Coins int
+}
+
+type TournamentData struct {
+	Tournament  entity.Tournament
+	Players     int
+	Registered  bool
+	CanRegister bool
+	HasMatch    bool
+	Rounds      []TournamentRoundView
+	Standings   []TournamentStandingView
+}
+
+type SeasonOption struct {
+	Id       uint
+	Name     string
+	Selected bool
+}
+
+type LeaderboardData struct {
+	Seasons  []SeasonOption
+	Season   entity.Season
+	Live     bool // points are still counting, false for an archived season
+	EndsIn   string
+	Ranking  []entity.SeasonRank
+	MyPlace  int // 0 when the user is not ranked
+	MyPoints int
+}
+
+type ShopItemView struct {
+	Item  entity.ShopItem
+	Owned bool
+}
+
+type ShopData struct {
+	User          entity.User
+	Items         []ShopItemView
+	Wallet        ratelimit.Wallet
+	StarPacks     []payment.Pack
+	OwnedAvatars  int
+	MaxProtection int
 }
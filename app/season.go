@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+var ErrNoSeason = errors.New("no season started yet")
+
+// CurrentSeason returns the active season
+func (server *Server) CurrentSeason(ctx context.Context) (entity.Season, error) {
+	seasonID, err := server.SeasonRepo.CurrentID(ctx)
+	if err != nil {
+		return entity.Season{}, err
+	}
+	if seasonID == 0 {
+		return entity.Season{}, ErrNoSeason
+	}
+	return server.SeasonRepo.GetByID(ctx, seasonID)
+}
+
+// AddSeasonPoints adds the coins won in a game to the user points of the active season
+func (server *Server) AddSeasonPoints(ctx context.Context, userID int64, points int) {
+	if points <= 0 {
+		return
+	}
+	seasonID, err := server.SeasonRepo.CurrentID(ctx)
+	if err != nil {
+		logrus.Error("current season error ", err)
+		return
+	}
+	if seasonID == 0 {
+		return
+	}
+	if err := server.SeasonRepo.AddPoints(ctx, seasonID, userID, points); err != nil {
+		logrus.Error("add season points error ", err)
+	}
+}
+
+// RunSeasonScheduler starts the first season and rotates ended seasons until the context is done
+func (server *Server) RunSeasonScheduler(ctx context.Context) {
+	ticker := time.NewTicker(server.Config.Season.Tick)
+	defer ticker.Stop()
+
+	for {
+		if err := server.RotateSeason(ctx); err != nil && !errors.Is(err, redislock.ErrNotObtained) {
+			logrus.Error("season rotation error ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RotateSeason archives and rewards the active season once it ended and starts the next one.
+// Every place is paid once, the next season starts only when every place is paid so a failed
+// rotation is resumed on the next tick.
+func (server *Server) RotateSeason(ctx context.Context) error {
+	// another instance is rotating the season
+	lock, err := server.Locker.Obtain(ctx, SEASON_LOCK, time.Minute, nil)
+	if err != nil {
+		return err
+	}
+	defer lock.Release(ctx)
+
+	season, err := server.CurrentSeason(ctx)
+	if errors.Is(err, ErrNoSeason) {
+		_, err = server.startSeason(ctx)
+		return err
+	} else if err != nil {
+		return err
+	}
+	if !season.Ended(time.Now()) {
+		return nil
+	}
+
+	ranking, err := server.seasonRanking(ctx, season)
+	if err != nil {
+		return err
+	}
+	if err := server.SeasonRepo.Archive(ctx, season.Id, ranking); err != nil {
+		return err
+	}
+	season.Status = entity.SeasonArchived
+	if err := server.SeasonRepo.Save(ctx, &season); err != nil {
+		return err
+	}
+
+	paid, err := server.paySeason(ctx, season, ranking)
+	if err != nil {
+		return err
+	}
+
+	next, err := server.startSeason(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, rank := range paid {
+		text := fmt.Sprintf("🏁 %s is over, you finished #%d with %d points.", season.Name, rank.Place, rank.Points)
+		if rank.Reward > 0 {
+			text += fmt.Sprintf("\n💰 Reward: %d coins", rank.Reward)
+		}
+		server.notify(rank.UserID, text+fmt.Sprintf("\n%s has started, good luck!", next.Name))
+	}
+	return nil
+}
+
+// paySeason pays the places of the ranking that weren't paid by an earlier rotation and returns them
+func (server *Server) paySeason(ctx context.Context, season entity.Season, ranking []entity.SeasonRank) ([]entity.SeasonRank, error) {
+	paid := []entity.SeasonRank{}
+	var errs []error
+	for _, rank := range ranking {
+		claimed, err := server.SeasonRepo.ClaimPayout(ctx, season.Id, rank.Place)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if rank.Reward > 0 {
+			_, err := server.UpdateBalance(ctx, rank.UserID, rank.Reward, entity.LedgerSeason, fmt.Sprintf("season %d place %d", season.Id, rank.Place), nil)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("season %d place %d reward: %w", season.Id, rank.Place, err))
+				if err := server.SeasonRepo.ReleasePayout(ctx, season.Id, rank.Place); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+		}
+		paid = append(paid, rank)
+	}
+	return paid, errors.Join(errs...)
+}
+
+// seasonRanking builds the final ranking of the season, an already archived ranking is kept
+func (server *Server) seasonRanking(ctx context.Context, season entity.Season) ([]entity.SeasonRank, error) {
+	archived, err := server.SeasonRepo.Ranking(ctx, season.Id)
+	if err != nil || len(archived) > 0 {
+		return archived, err
+	}
+
+	scores, err := server.SeasonRepo.Top(ctx, season.Id, int64(server.Config.Season.ArchiveSize))
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, len(scores))
+	for i, score := range scores {
+		userIDs[i] = score.UserID
+	}
+	users, err := server.UserRepo.GetMany(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	ranking := make([]entity.SeasonRank, len(scores))
+	for i, score := range scores {
+		ranking[i] = entity.SeasonRank{Place: i + 1, UserID: score.UserID, Points: score.Points, DisplayName: users[i].DisplayName}
+		if i < len(server.Config.Season.Rewards) {
+			ranking[i].Reward = server.Config.Season.Rewards[i]
+		}
+	}
+	return ranking, nil
+}
+
+func (server *Server) startSeason(ctx context.Context) (entity.Season, error) {
+	seasonID, err := entity.GetOrInitID(server.DB, SEASON_INDEX)
+	if err != nil {
+		return entity.Season{}, err
+	}
+	season := entity.NewSeason(seasonID, time.Now(), server.Config.Season.Length)
+	if err := server.SeasonRepo.Save(ctx, &season); err != nil {
+		return season, err
+	}
+	return season, server.SeasonRepo.SetCurrent(ctx, season.Id)
+}
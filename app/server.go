@@ -1,26 +1,42 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/onionj/trust/config"
 	"github.com/onionj/trust/db"
+	"github.com/onionj/trust/internal/payment"
 	"github.com/onionj/trust/internal/repository"
 
+	"github.com/bsm/redislock"
 	"github.com/labstack/echo/v4"
 	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 	tele "gopkg.in/telebot.v4"
 )
 
 type Server struct {
-	Echo     *echo.Echo
-	TeleBot  *tele.Bot
-	DB       *redis.Client
-	Config   config.ConfigT
-	UserRepo repository.UserRepository
-	GameRepo repository.GameRepository
+	Echo           *echo.Echo
+	TeleBot        *tele.Bot
+	DB             *redis.Client
+	Locker         *redislock.Client
+	Config         config.ConfigT
+	UserRepo       repository.UserRepository
+	GameRepo       repository.GameRepository
+	GroupRepo      repository.GroupGameRepository
+	InventoryRepo  repository.InventoryRepository
+	LedgerRepo     repository.LedgerRepository
+	AchieveRepo    repository.AchievementRepository
+	ReferralRepo   repository.ReferralRepository
+	AuditRepo      repository.AuditRepository
+	BroadcastRepo  repository.BroadcastRepository
+	CollusionRepo  repository.CollusionRepository
+	TournamentRepo repository.TournamentRepository
+	SeasonRepo     repository.SeasonRepository
+	Payments       *payment.Service
 }
 
 func NewServer(cfg config.ConfigT) *Server {
@@ -36,18 +52,68 @@ func NewServer(cfg config.ConfigT) *Server {
 
 	userRepo := repository.NewUserRepository(redis)
 	gameRepo := repository.NewGameRepository(redis)
+	groupRepo := repository.NewGroupGameRepository(redis)
+	inventoryRepo := repository.NewInventoryRepository(redis)
+	ledgerRepo := repository.NewLedgerRepository(redis)
+	achieveRepo := repository.NewAchievementRepository(redis)
+	referralRepo := repository.NewReferralRepository(redis)
+	auditRepo := repository.NewAuditRepository(redis)
+	broadcastRepo := repository.NewBroadcastRepository(redis)
+	collusionRepo := repository.NewCollusionRepository(redis)
+	tournamentRepo := repository.NewTournamentRepository(redis)
+	seasonRepo := repository.NewSeasonRepository(redis)
 
-	return &Server{
-		Echo:     echo.New(),
-		TeleBot:  bot,
-		DB:       redis,
-		Config:   cfg,
-		UserRepo: userRepo,
-		GameRepo: gameRepo,
+	server := &Server{
+		Echo:           echo.New(),
+		TeleBot:        bot,
+		DB:             redis,
+		Locker:         redislock.New(redis),
+		Config:         cfg,
+		UserRepo:       userRepo,
+		GameRepo:       gameRepo,
+		GroupRepo:      groupRepo,
+		InventoryRepo:  inventoryRepo,
+		LedgerRepo:     ledgerRepo,
+		AchieveRepo:    achieveRepo,
+		ReferralRepo:   referralRepo,
+		AuditRepo:      auditRepo,
+		BroadcastRepo:  broadcastRepo,
+		CollusionRepo:  collusionRepo,
+		TournamentRepo: tournamentRepo,
+		SeasonRepo:     seasonRepo,
 	}
+	server.Payments = payment.NewService(bot, repository.NewPaymentRepository(redis), paymentWallet{server: server})
+
+	return server
 }
 
 func (server *Server) Start() error {
+	ctx := context.Background()
+
+	// Move the user data saved before the trust:user<id>:* keys
+	if moved, err := server.UserRepo.MigrateKeys(ctx); err != nil {
+		logrus.Error("migrate user keys error ", err)
+	} else if moved > 0 {
+		logrus.Info("moved ", moved, " legacy user keys")
+	}
+	// Index the users saved before the users index existed
+	if size, err := server.UserRepo.IndexSize(ctx); err == nil && size == 0 {
+		added, err := server.UserRepo.BuildIndex(ctx)
+		if err != nil {
+			logrus.Error("build users index error ", err)
+		}
+		logrus.Info("users index built with ", added, " users")
+	}
+	// Move the games saved before the game indexes existed
+	if moved, err := server.GameRepo.Migrate(ctx); err != nil {
+		logrus.Error("migrate games error ", err)
+	} else if moved > 0 {
+		logrus.Info("games index built with ", moved, " games")
+	}
+	server.ResumeBroadcasts(ctx)
+	go server.RunTournamentScheduler(ctx)
+	go server.RunSeasonScheduler(ctx)
+
 	go server.TeleBot.Start()
 	fmt.Println(server.Config.HTTP.Host + ":" + server.Config.HTTP.Port)
 	return server.Echo.Start(server.Config.HTTP.Host + ":" + server.Config.HTTP.Port)
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bsm/redislock"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+var (
+	ErrUnknownItem = errors.New("unknown shop item")
+	ErrItemOwned   = errors.New("item already owned")
+	ErrItemLimit   = errors.New("item limit reached")
+)
+
+// BuyItem debits the item price and grants the item. Streak protections are granted in the same
+// critical section, nothing is debited when the limit is reached. Avatars and bonus tokens are kept
+// outside the user and are granted once the price is debited, the price is refunded when that fails.
+// The purchases of a user run one at a time so an avatar is never charged twice.
+func (server *Server) BuyItem(ctx context.Context, userID int64, itemID string) (entity.User, error) {
+	item, ok := entity.FindShopItem(itemID)
+	if !ok {
+		return entity.User{}, ErrUnknownItem
+	}
+
+	shopLock, err := server.Locker.Obtain(
+		ctx,
+		fmt.Sprintf(USER_LOCK_SHOP, userID),
+		10*time.Second,
+		&redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 10)},
+	)
+	if err != nil {
+		return entity.User{}, fmt.Errorf("user shop lock error: %w", err)
+	}
+	defer shopLock.Release(ctx)
+
+	if item.Kind == entity.ShopCosmetic {
+		user, err := server.UserRepo.GetByID(ctx, userID)
+		if err != nil {
+			return user, err
+		}
+		if err := server.KeepAvatar(ctx, user); err != nil {
+			return user, err
+		}
+		owned, err := server.InventoryRepo.Has(ctx, userID, entity.InventoryAvatar, fmt.Sprint(item.AvatarID))
+		if err != nil {
+			return user, err
+		}
+		if owned {
+			return user, ErrItemOwned
+		}
+	}
+
+	user, err := server.UpdateBalance(ctx, userID, -item.Price, entity.LedgerPurchase, fmt.Sprint("shop ", item.ID), func(user *entity.User) error {
+		switch item.Kind {
+		case entity.ShopCosmetic, entity.ShopBonusTokens:
+			return nil
+		case entity.ShopStreakProtection:
+			if user.StreakProtection+item.Amount > entity.MaxStreakProtection {
+				return ErrItemLimit
+			}
+			user.StreakProtection += item.Amount
+			return nil
+		}
+		return ErrUnknownItem
+	})
+	if err != nil {
+		return user, err
+	}
+
+	switch item.Kind {
+	case entity.ShopCosmetic:
+		err = server.InventoryRepo.Add(ctx, userID, entity.InventoryAvatar, fmt.Sprint(item.AvatarID))
+	case entity.ShopBonusTokens:
+		err = server.GrantBonusTokens(ctx, userID, item.Amount)
+	}
+	if err != nil {
+		return server.refundItem(ctx, user, item, err)
+	}
+	return user, nil
+}
+
+// KeepAvatar adds the selected avatar to the inventory before it is replaced,
+// avatars selected before the inventory existed are only known by the user AvatarID
+func (server *Server) KeepAvatar(ctx context.Context, user entity.User) error {
+	current, ok := entity.FindAvatar(user.AvatarID)
+	if !ok || current.Free() {
+		return nil
+	}
+	return server.InventoryRepo.Add(ctx, user.Id, entity.InventoryAvatar, current.ItemID())
+}
+
+// refundItem gives back the price of an item that couldn't be granted, err is the grant failure
+func (server *Server) refundItem(ctx context.Context, user entity.User, item entity.ShopItem, err error) (entity.User, error) {
+	refunded, refundErr := server.UpdateBalance(ctx, user.Id, item.Price, entity.LedgerPurchase, fmt.Sprint("shop ", item.ID, " refund"), nil)
+	if refundErr != nil {
+		return user, errors.Join(err, refundErr)
+	}
+	return refunded, err
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
+)
+
+func TestBuyItemConcurrentAvatar(t *testing.T) {
+	server := newTestServer(t)
+	server.InventoryRepo = repository.NewInventoryRepository(server.DB)
+	server.LedgerRepo = repository.NewLedgerRepository(server.DB)
+	ctx := context.Background()
+	saveUsers(t, server, entity.NewUser(1, "player1", 20_000))
+
+	avatar, _ := entity.FindAvatar(7)
+	errs := make([]error, 5)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = server.BuyItem(ctx, 1, avatar.ShopItemID())
+		}()
+	}
+	wg.Wait()
+
+	// the avatar is charged once, the other purchases find it owned
+	bought := 0
+	for _, err := range errs {
+		if err == nil {
+			bought++
+		} else {
+			assert.ErrorIs(t, err, ErrItemOwned)
+		}
+	}
+	assert.Equal(t, 1, bought)
+
+	user, err := server.UserRepo.GetByID(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 20_000-avatar.Price, user.Balance)
+}
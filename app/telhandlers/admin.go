@@ -0,0 +1,201 @@
+package telhandlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/internal/entity"
+)
+
+type AdminHandlers struct {
+	server *app.Server
+}
+
+func NewAdminHandlers(server *app.Server) *AdminHandlers {
+	return &AdminHandlers{server: server}
+}
+
+// OnlyAdmins ignores the command unless the sender is listed in the admin config
+func (a *AdminHandlers) OnlyAdmins(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if !a.server.Config.Admin.IsAdmin(c.Sender().ID) {
+			logrus.Warn("tel: admin command denied for ", c.Sender().ID)
+			return nil
+		}
+		return next(c)
+	}
+}
+
+// Stats replies with user and game counters
+func (a *AdminHandlers) Stats(c tele.Context) error {
+	ctx := context.Background()
+	stats, err := a.server.Stats(ctx)
+	if err != nil {
+		logrus.Error("tel: stats error ", err)
+		return c.Reply("Failed to load stats.")
+	}
+	a.server.Audit(ctx, entity.NewAuditEntry(c.Sender().ID, entity.AuditStats, 0, "", ""))
+
+	return c.Reply(fmt.Sprintf("👥 Users: %d\n🎮 Games: %d\n⏳ Active games: %d\n🚪 Lobby: %s",
+		stats.Users, stats.Games, stats.ActiveGames, lobbyText(stats.LobbyUserID)))
+}
+
+// User replies with the state of a user: /user <id>
+func (a *AdminHandlers) User(c tele.Context) error {
+	ctx := context.Background()
+	args := c.Args()
+	if len(args) != 1 {
+		return c.Reply("Usage: /user <id>")
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return c.Reply("Invalid user id.")
+	}
+
+	user, err := a.server.UserRepo.GetByID(ctx, userID)
+	if err != nil {
+		return c.Reply("User Not Found.")
+	}
+	progress, _ := a.server.AchieveRepo.GetProgress(ctx, userID)
+	a.server.Audit(ctx, entity.NewAuditEntry(c.Sender().ID, entity.AuditViewUser, userID, "", ""))
+
+	text := fmt.Sprintf("👤 %s (%d)\n💰 Balance: %d\n🎮 Games: %d\n⏱ Hour limit: %d\n📅 Joined: %s",
+		user.DisplayName, user.Id, user.Balance, progress.GamesPlayed, user.HourLimit,
+		time.Unix(user.Created, 0).UTC().Format(time.DateOnly))
+	if user.IsBanned(time.Now()) {
+		text += fmt.Sprint("\n⛔ Banned: ", user.BanReason)
+	}
+	return c.Reply(text)
+}
+
+// Grant changes the balance of a user: /grant <id> <coins> <reason>
+func (a *AdminHandlers) Grant(c tele.Context) error {
+	args := c.Args()
+	if len(args) < 3 {
+		return c.Reply("Usage: /grant <id> <coins> <reason>")
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return c.Reply("Invalid user id.")
+	}
+	amount, err := strconv.Atoi(args[1])
+	if err != nil {
+		return c.Reply("Invalid coins.")
+	}
+
+	user, err := a.server.AdminAdjustBalance(context.Background(), c.Sender().ID, userID, amount, strings.Join(args[2:], " "))
+	if err != nil {
+		return c.Reply(fmt.Sprint("Grant failed: ", err))
+	}
+	return c.Reply(fmt.Sprintf("✅ %s balance: %d", user.DisplayName, user.Balance))
+}
+
+// Refund returns the stars of a payment: /refund <charge id> <reason>
+func (a *AdminHandlers) Refund(c tele.Context) error {
+	args := c.Args()
+	if len(args) < 2 {
+		return c.Reply("Usage: /refund <charge id> <reason>")
+	}
+
+	purchase, err := a.server.AdminRefund(context.Background(), c.Sender().ID, args[0], strings.Join(args[1:], " "))
+	if err != nil {
+		return c.Reply(fmt.Sprint("Refund failed: ", err))
+	}
+	return c.Reply(fmt.Sprintf("✅ Refunded %d ⭐ of user %d", purchase.Stars, purchase.UserID))
+}
+
+// Ban bans a user permanently or for a duration like 24h: /ban <id> [duration] <reason>
+func (a *AdminHandlers) Ban(c tele.Context) error {
+	args := c.Args()
+	if len(args) < 2 {
+		return c.Reply("Usage: /ban <id> [duration] <reason>")
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return c.Reply("Invalid user id.")
+	}
+
+	until := entity.BanPermanent
+	reason := args[1:]
+	if duration, err := time.ParseDuration(args[1]); err == nil && len(args) > 2 {
+		until = time.Now().Add(duration).Unix()
+		reason = args[2:]
+	}
+
+	user, err := a.server.AdminBan(context.Background(), c.Sender().ID, userID, until, strings.Join(reason, " "))
+	if err != nil {
+		return c.Reply(fmt.Sprint("Ban failed: ", err))
+	}
+	return c.Reply(fmt.Sprintf("⛔ %s is banned.", user.DisplayName))
+}
+
+// Broadcast starts a broadcast job to every active user: /broadcast <text>
+func (a *AdminHandlers) Broadcast(c tele.Context) error {
+	text := strings.TrimSpace(c.Message().Payload)
+	if text == "" {
+		return c.Reply("Usage: /broadcast <text>")
+	}
+
+	ctx := context.Background()
+	broadcast, err := a.server.StartBroadcast(ctx, c.Sender().ID, text)
+	if err != nil {
+		logrus.Error("tel: broadcast error ", err)
+		return c.Reply("Failed to start broadcast.")
+	}
+	a.server.Audit(ctx, entity.NewAuditEntry(c.Sender().ID, entity.AuditBroadcast, int64(broadcast.Id), "", text))
+	return nil
+}
+
+// Tournament schedules a tournament: /tournament <single_elimination|swiss> <fee> <prize> <starts in, e.g. 2h> <name>
+func (a *AdminHandlers) Tournament(c tele.Context) error {
+	args := c.Args()
+	if len(args) < 5 {
+		return c.Reply("Usage: /tournament <single_elimination|swiss> <fee> <prize> <starts in, e.g. 2h> <name>")
+	}
+	fee, err := strconv.Atoi(args[1])
+	if err != nil {
+		return c.Reply("Invalid fee.")
+	}
+	prize, err := strconv.Atoi(args[2])
+	if err != nil {
+		return c.Reply("Invalid prize.")
+	}
+	startsIn, err := time.ParseDuration(args[3])
+	if err != nil {
+		return c.Reply("Invalid start time.")
+	}
+
+	t, err := a.server.CreateTournament(context.Background(), c.Sender().ID, strings.Join(args[4:], " "), args[0], fee, prize, time.Now().Add(startsIn))
+	if err != nil {
+		return c.Reply(fmt.Sprint("Tournament failed: ", err))
+	}
+	return c.Reply(fmt.Sprintf("🏆 Tournament %d scheduled, registration is open until %s UTC.", t.Id,
+		time.Unix(t.StartsAt, 0).UTC().Format("2006-01-02 15:04")))
+}
+
+// Lobby replies with the user waiting in the default lobby
+func (a *AdminHandlers) Lobby(c tele.Context) error {
+	ctx := context.Background()
+	lobbyUserID, err := a.server.LobbyUserID(ctx)
+	if err != nil {
+		logrus.Error("tel: lobby error ", err)
+		return c.Reply("Failed to load lobby.")
+	}
+	a.server.Audit(ctx, entity.NewAuditEntry(c.Sender().ID, entity.AuditLobby, lobbyUserID, "", ""))
+
+	return c.Reply(fmt.Sprint("🚪 Lobby: ", lobbyText(lobbyUserID)))
+}
+
+func lobbyText(lobbyUserID int64) string {
+	if lobbyUserID == 0 {
+		return "empty"
+	}
+	return fmt.Sprintf("user %d is waiting", lobbyUserID)
+}
@@ -0,0 +1,82 @@
+package telhandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/internal/payment"
+)
+
+type PaymentHandlers struct {
+	server *app.Server
+}
+
+func NewPaymentHandlers(server *app.Server) *PaymentHandlers {
+	return &PaymentHandlers{server: server}
+}
+
+// Buy sends the invoice of a pack, or lists the packs: /buy [pack]
+func (p *PaymentHandlers) Buy(c tele.Context) error {
+	args := c.Args()
+	if len(args) != 1 {
+		lines := []string{"⭐ Packs for Telegram Stars:"}
+		for _, pack := range payment.Packs {
+			lines = append(lines, fmt.Sprintf("/buy %s — %s for %d ⭐", pack.ID, pack.Title, pack.Stars))
+		}
+		return c.Reply(strings.Join(lines, "\n"))
+	}
+
+	err := p.server.Payments.SendInvoice(c.Sender().ID, args[0])
+	if errors.Is(err, payment.ErrUnknownPack) {
+		return c.Reply("Unknown pack, send /buy to see the packs.")
+	} else if err != nil {
+		logrus.Error("tel: send invoice error ", err)
+		return c.Reply("Failed to create the invoice, please try again.")
+	}
+	return nil
+}
+
+// Checkout answers the pre-checkout query of an invoice
+func (p *PaymentHandlers) Checkout(c tele.Context) error {
+	if err := p.server.Payments.PreCheckout(c.PreCheckoutQuery()); err != nil {
+		logrus.Error("tel: pre-checkout error ", err)
+		return err
+	}
+	return nil
+}
+
+// Payment credits a successful payment
+func (p *PaymentHandlers) Payment(c tele.Context) error {
+	purchase, err := p.server.Payments.Complete(context.Background(), c.Sender().ID, c.Payment())
+	if errors.Is(err, payment.ErrDuplicateCharge) {
+		logrus.Warn("tel: duplicate payment ", purchase.ChargeID)
+		return nil
+	} else if err != nil {
+		logrus.Error("tel: payment ", c.Payment().TelegramChargeID, " error ", err)
+		return c.Reply("We couldn't credit your payment, please contact support with this id: " + c.Payment().TelegramChargeID)
+	}
+
+	text := "✅ Thank you!"
+	if purchase.Coins > 0 {
+		text += fmt.Sprintf("\n💰 +%d coins", purchase.Coins)
+	}
+	if purchase.Tokens > 0 {
+		text += fmt.Sprintf("\n⭐ +%d bonus game tokens", purchase.Tokens)
+	}
+	return c.Reply(text)
+}
+
+// Refund records a refund announced by Telegram
+func (p *PaymentHandlers) Refund(c tele.Context) error {
+	refund := c.Message().RefundedPayment
+	if _, err := p.server.Payments.Refunded(context.Background(), refund); err != nil {
+		logrus.Error("tel: refunded payment ", refund.TelegramChargeID, " error ", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/pkg/ratelimit"
+)
+
+// GAME_TOKEN_RESERVATION_TTL drops reservations of interrupted requests, longer than any lobby wait
+const GAME_TOKEN_RESERVATION_TTL = 2 * time.Minute
+
+// gameWindow allows HourLimit games in any hour
+func (server *Server) gameWindow(user entity.User) *ratelimit.SlidingWindow {
+	return ratelimit.NewSlidingWindow(server.DB, user.HourLimit, time.Hour)
+}
+
+func (server *Server) gameReservations() *ratelimit.Reservations {
+	return ratelimit.NewReservations(server.DB, GAME_TOKEN_RESERVATION_TTL)
+}
+
+// GameWallet returns the hourly game tokens left, the bonus tokens and the time until the hourly reset
+func (server *Server) GameWallet(user entity.User) (ratelimit.Wallet, error) {
+	return ratelimit.GetWallet(context.Background(), server.gameWindow(user),
+		fmt.Sprintf(GAME_USER_HOUR_WINDOW, user.Id), fmt.Sprintf(USER_BONUS_TOKENS, user.Id))
+}
+
+// ReserveGameToken takes an hourly token, or a bonus token once the hourly allowance ran out, for a game
+// that may not start. The game creation commits it, a cancel, timeout or error releases it.
+func (server *Server) ReserveGameToken(ctx context.Context, user entity.User) (ratelimit.Result, error) {
+	return server.gameReservations().Reserve(ctx, fmt.Sprintf(GAME_TOKEN_RESERVATION, user.Id), server.gameWindow(user),
+		fmt.Sprintf(GAME_USER_HOUR_WINDOW, user.Id), fmt.Sprintf(USER_BONUS_TOKENS, user.Id))
+}
+
+// CommitGameTokens keeps the reserved tokens of the players of a created game spent
+func (server *Server) CommitGameTokens(ctx context.Context, userIDs ...int64) {
+	for _, userID := range userIDs {
+		if _, err := server.gameReservations().Commit(ctx, fmt.Sprintf(GAME_TOKEN_RESERVATION, userID)); err != nil {
+			logrus.Error("commit game token error ", err)
+		}
+	}
+}
+
+// ReleaseGameToken gives back the reserved token of the user, nothing happens once the token was committed
+func (server *Server) ReleaseGameToken(ctx context.Context, userID int64) {
+	_, err := server.gameReservations().Release(ctx, fmt.Sprintf(GAME_TOKEN_RESERVATION, userID),
+		fmt.Sprintf(GAME_USER_HOUR_WINDOW, userID), fmt.Sprintf(USER_BONUS_TOKENS, userID))
+	if err != nil {
+		logrus.Error("release game token error ", err)
+	}
+}
+
+// GrantBonusTokens credits bonus tokens to the user wallet
+func (server *Server) GrantBonusTokens(ctx context.Context, userID int64, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	return ratelimit.AddBonusTokens(server.DB, fmt.Sprintf(USER_BONUS_TOKENS, userID), tokens)
+}
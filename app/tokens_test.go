@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onionj/trust/config"
+	"github.com/onionj/trust/db"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
+)
+
+func newTestServer(t *testing.T) *Server {
+	cfg := config.NewConfig("../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	assert.NoError(t, redis.FlushDB(context.Background()).Err())
+
+	return &Server{
+		DB:            redis,
+		Locker:        redislock.New(redis),
+		Config:        cfg,
+		UserRepo:      repository.NewUserRepository(redis),
+		GameRepo:      repository.NewGameRepository(redis),
+		GroupRepo:     repository.NewGroupGameRepository(redis),
+		CollusionRepo: repository.NewCollusionRepository(redis),
+	}
+}
+
+// failingGameRepo fails every game save
+type failingGameRepo struct {
+	repository.GameRepository
+}
+
+func (failingGameRepo) Save(ctx context.Context, game *entity.Game) error {
+	return errors.New("save failed")
+}
+
+// reservationPending reports whether the reserved game token of the user is neither committed nor released
+func reservationPending(t *testing.T, server *Server, userID int64) bool {
+	exists, err := server.DB.Exists(context.Background(), fmt.Sprintf(GAME_TOKEN_RESERVATION, userID)).Result()
+	assert.NoError(t, err)
+	return exists == 1
+}
+
+func reserve(t *testing.T, server *Server, userIDs ...int64) {
+	for _, userID := range userIDs {
+		user := entity.NewUser(userID, fmt.Sprint("player", userID), 0)
+		assert.NoError(t, server.UserRepo.Save(context.Background(), &user))
+		token, err := server.ReserveGameToken(context.Background(), user)
+		assert.NoError(t, err)
+		assert.True(t, token.Allowed)
+	}
+}
+
+func tokensLeft(t *testing.T, server *Server, userID int64) int {
+	wallet, err := server.GameWallet(entity.NewUser(userID, "", 0))
+	assert.NoError(t, err)
+	return wallet.Tokens
+}
+
+func TestStartLobbyGameCommitsTokens(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	reserve(t, server, 1, 2)
+
+	game, err := server.StartLobbyGame(ctx, server.Config.Modes.Default(), 1, LobbyEntry{UserID: 2, Joined: time.Now()})
+	assert.NoError(t, err)
+	assert.True(t, game.HasPlayer(2))
+
+	// the committed tokens stay spent when the handlers release them
+	assert.False(t, reservationPending(t, server, 1))
+	assert.False(t, reservationPending(t, server, 2))
+	server.ReleaseGameToken(ctx, 1)
+	server.ReleaseGameToken(ctx, 2)
+	assert.Equal(t, 9, tokensLeft(t, server, 1))
+	assert.Equal(t, 9, tokensLeft(t, server, 2))
+}
+
+func TestStartLobbyGameSaveErrorKeepsReservations(t *testing.T) {
+	server := newTestServer(t)
+	server.GameRepo = failingGameRepo{server.GameRepo}
+	ctx := context.Background()
+	reserve(t, server, 1, 2)
+
+	_, err := server.StartLobbyGame(ctx, server.Config.Modes.Default(), 1, LobbyEntry{UserID: 2, Joined: time.Now()})
+	assert.Error(t, err)
+
+	// the handlers release the tokens of a game that wasn't created
+	assert.True(t, reservationPending(t, server, 1))
+	assert.True(t, reservationPending(t, server, 2))
+	server.ReleaseGameToken(ctx, 1)
+	server.ReleaseGameToken(ctx, 2)
+	assert.Equal(t, 10, tokensLeft(t, server, 1))
+	assert.Equal(t, 10, tokensLeft(t, server, 2))
+}
+
+func TestLobbyTimeoutReleasesToken(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	reserve(t, server, 1)
+	assert.Equal(t, 9, tokensLeft(t, server, 1))
+
+	// nobody joined, the handler releases the token on the way out
+	server.ReleaseGameToken(ctx, 1)
+	assert.False(t, reservationPending(t, server, 1))
+	assert.Equal(t, 10, tokensLeft(t, server, 1))
+}
+
+func TestFormGroupCommitsTokens(t *testing.T) {
+	server := newTestServer(t)
+	server.Config.Group.MinPlayers = 3
+	server.Config.Group.MaxPlayers = 3
+	ctx := context.Background()
+
+	reserve(t, server, 1, 2, 3)
+	for _, userID := range []int64{1, 2, 3} {
+		assert.NoError(t, server.JoinGroupLobby(ctx, userID))
+	}
+
+	game, ok, err := server.FormGroup(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Len(t, game.PlayerIDs(), 3)
+
+	for _, userID := range []int64{1, 2, 3} {
+		assert.False(t, reservationPending(t, server, userID))
+		server.ReleaseGameToken(ctx, userID)
+		assert.Equal(t, 9, tokensLeft(t, server, userID))
+	}
+}
+
+func TestFormGroupWaitingKeepsReservations(t *testing.T) {
+	server := newTestServer(t)
+	server.Config.Group.MinPlayers = 3
+	ctx := context.Background()
+
+	reserve(t, server, 1, 2)
+	for _, userID := range []int64{1, 2} {
+		assert.NoError(t, server.JoinGroupLobby(ctx, userID))
+	}
+
+	// not enough players, the tokens stay reserved until the handlers give up
+	_, ok, err := server.FormGroup(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.True(t, reservationPending(t, server, 1))
+
+	server.ReleaseGameToken(ctx, 1)
+	assert.Equal(t, 10, tokensLeft(t, server, 1))
+}
+
+func TestGameTokensBeforeBonusTokens(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+	user := entity.NewUser(1, "player1", 0)
+	user.HourLimit = 2
+	assert.NoError(t, server.GrantBonusTokens(ctx, user.Id, 3))
+
+	// the hourly tokens are spent first, the bonus tokens are left alone
+	for range 2 {
+		token, err := server.ReserveGameToken(ctx, user)
+		assert.NoError(t, err)
+		assert.True(t, token.Allowed)
+		assert.False(t, token.Bonus)
+		server.CommitGameTokens(ctx, user.Id)
+	}
+	wallet, err := server.GameWallet(user)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, wallet.Tokens)
+	assert.Equal(t, 3, wallet.Bonus)
+
+	token, err := server.ReserveGameToken(ctx, user)
+	assert.NoError(t, err)
+	assert.True(t, token.Allowed)
+	assert.True(t, token.Bonus)
+	server.CommitGameTokens(ctx, user.Id)
+	wallet, err = server.GameWallet(user)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, wallet.Bonus)
+}
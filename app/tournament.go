@@ -0,0 +1,481 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
+	"github.com/onionj/trust/internal/tournament"
+)
+
+var (
+	ErrTournamentFormat      = errors.New("format must be single_elimination or swiss")
+	ErrRegistrationClosed    = errors.New("registration is closed")
+	ErrAlreadyRegistered     = errors.New("already registered")
+	ErrTournamentStartInPast = errors.New("the tournament must start in the future")
+)
+
+// CreateTournament schedules a tournament and announces it to every user
+func (server *Server) CreateTournament(ctx context.Context, adminID int64, name string, format string, fee int, prize int, startsAt time.Time) (entity.Tournament, error) {
+	if format != entity.TournamentSingleElimination && format != entity.TournamentSwiss {
+		return entity.Tournament{}, ErrTournamentFormat
+	}
+	if fee < 0 || prize < 0 {
+		return entity.Tournament{}, errors.New("fee and prize can't be negative")
+	}
+	if !startsAt.After(time.Now()) {
+		return entity.Tournament{}, ErrTournamentStartInPast
+	}
+	if strings.TrimSpace(name) == "" {
+		return entity.Tournament{}, errors.New("a name is required")
+	}
+
+	tournamentID, err := entity.GetOrInitID(server.DB, TOURNAMENT_INDEX)
+	if err != nil {
+		return entity.Tournament{}, err
+	}
+	t := entity.NewTournament(tournamentID, adminID, name, format, server.Config.Modes.Default().Name, fee, prize, startsAt)
+	if err := server.TournamentRepo.Save(ctx, &t); err != nil {
+		return t, err
+	}
+
+	server.Audit(ctx, entity.NewAuditEntry(adminID, entity.AuditTournament, int64(t.Id), "", fmt.Sprintf("%s %s fee %d prize %d", name, format, fee, prize)))
+	if _, err := server.StartBroadcast(ctx, adminID, fmt.Sprintf(
+		"🏆 New tournament: %s\n💰 Entry fee: %d, prize pool from %d coins\n⏰ Starts %s UTC\nRegister from the Tournaments page!",
+		name, fee, prize, startsAt.UTC().Format("2006-01-02 15:04"))); err != nil {
+		logrus.Error("tournament announcement error ", err)
+	}
+	return t, nil
+}
+
+// RegisterTournament charges the entry fee and registers the user
+func (server *Server) RegisterTournament(ctx context.Context, tournamentID uint, userID int64) (entity.Tournament, error) {
+	lock, err := server.lockTournament(ctx, tournamentID)
+	if err != nil {
+		return entity.Tournament{}, err
+	}
+	defer lock.Release(ctx)
+
+	t, err := server.TournamentRepo.GetByID(ctx, tournamentID)
+	if err != nil {
+		return t, err
+	}
+	if t.Status != entity.TournamentRegistration || time.Now().Unix() >= t.StartsAt {
+		return t, ErrRegistrationClosed
+	}
+
+	// the player is added before the debit so that a failed registration can be undone
+	added, err := server.TournamentRepo.AddPlayer(ctx, t.Id, userID)
+	if err != nil {
+		return t, err
+	}
+	if !added {
+		return t, ErrAlreadyRegistered
+	}
+
+	_, err = server.UpdateBalance(ctx, userID, -t.EntryFee, entity.LedgerTournament, fmt.Sprintf("tournament %d entry", t.Id), nil)
+	if err != nil {
+		return t, server.unregister(ctx, t.Id, userID, err)
+	}
+
+	t.PrizePool += t.EntryFee
+	if err := server.TournamentRepo.Save(ctx, &t); err != nil {
+		t.PrizePool -= t.EntryFee
+		_, refundErr := server.UpdateBalance(ctx, userID, t.EntryFee, entity.LedgerTournament, fmt.Sprintf("tournament %d refund", t.Id), nil)
+		return t, server.unregister(ctx, t.Id, userID, errors.Join(err, refundErr))
+	}
+	return t, nil
+}
+
+// unregister removes the player of a failed registration and returns the failure
+func (server *Server) unregister(ctx context.Context, tournamentID uint, userID int64, err error) error {
+	if removeErr := server.TournamentRepo.RemovePlayer(ctx, tournamentID, userID); removeErr != nil {
+		return errors.Join(err, removeErr)
+	}
+	return err
+}
+
+// RunTournamentScheduler starts and advances the tournaments until the context is done
+func (server *Server) RunTournamentScheduler(ctx context.Context) {
+	ticker := time.NewTicker(server.Config.Tournament.Tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			server.RunTournaments(ctx)
+		}
+	}
+}
+
+// RunTournaments starts the tournaments whose registration ended and advances the running ones
+func (server *Server) RunTournaments(ctx context.Context) {
+	tournaments, err := server.TournamentRepo.Scan(ctx, "tournament:*", 1000)
+	if err != nil {
+		logrus.Error("scan tournaments error ", err)
+		return
+	}
+
+	for _, t := range tournaments {
+		if t.Status != entity.TournamentRegistration && t.Status != entity.TournamentRunning {
+			continue
+		}
+		late, err := server.advanceTournament(ctx, t.Id)
+		if err != nil && !errors.Is(err, redislock.ErrNotObtained) {
+			logrus.Error("tournament ", t.Id, " error ", err)
+		}
+		// the forfeited games record their match under the tournament lock
+		server.forfeitLateMatches(ctx, t.Id, late)
+	}
+}
+
+// advanceTournament starts or advances the tournament, it returns the pending matches that passed the round deadline
+func (server *Server) advanceTournament(ctx context.Context, tournamentID uint) ([]entity.TournamentMatch, error) {
+	// another instance is advancing the tournament
+	lock, err := server.Locker.Obtain(ctx, fmt.Sprintf(TOURNAMENT_LOCK, tournamentID), time.Minute, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release(ctx)
+
+	t, err := server.TournamentRepo.GetByID(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.Status {
+	case entity.TournamentRegistration:
+		if time.Now().Unix() < t.StartsAt {
+			return nil, nil
+		}
+		return nil, server.startTournament(ctx, t)
+
+	case entity.TournamentRunning:
+		matches, err := server.TournamentRepo.Matches(ctx, t.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		pending := []entity.TournamentMatch{}
+		for _, match := range matches {
+			if match.Round == t.Round && !match.Decided() {
+				pending = append(pending, match)
+			}
+		}
+		if len(pending) > 0 {
+			if time.Now().Unix() >= t.RoundDeadline {
+				return pending, nil
+			}
+			return nil, nil
+		}
+
+		if t.Round >= t.Rounds {
+			return nil, server.finishTournament(ctx, t)
+		}
+		return nil, server.pairRound(ctx, t, matches)
+	}
+	return nil, nil
+}
+
+// startTournament closes the registration and pairs the first round, too small tournaments are refunded
+func (server *Server) startTournament(ctx context.Context, t entity.Tournament) error {
+	players, err := server.TournamentRepo.Players(ctx, t.Id)
+	if err != nil {
+		return err
+	}
+
+	if len(players) < 2 {
+		// the tournament is cancelled once every player is refunded, a later run refunds the rest
+		refunded, err := server.payTournament(ctx, t, players, func(int) int { return t.EntryFee }, func(i int) string {
+			return fmt.Sprintf("refund %d", players[i])
+		})
+		for _, playerID := range refunded {
+			server.notify(playerID, fmt.Sprintf("🏆 %s was cancelled, not enough players. Your entry fee is refunded.", t.Name))
+		}
+		if err != nil {
+			return err
+		}
+
+		t.Status = entity.TournamentCancelled
+		return server.TournamentRepo.Save(ctx, &t)
+	}
+
+	t.Status = entity.TournamentRunning
+	t.Rounds = tournament.Rounds(len(players))
+	return server.pairRound(ctx, t, nil)
+}
+
+// pairRound creates the games of the next round
+func (server *Server) pairRound(ctx context.Context, t entity.Tournament, matches []entity.TournamentMatch) error {
+	var pairings []tournament.Pairing
+	played := func(a, b int64) bool {
+		for _, match := range matches {
+			if (match.P1ID == a && match.P2ID == b) || (match.P1ID == b && match.P2ID == a) {
+				return true
+			}
+		}
+		return false
+	}
+	hadBye := func(userID int64) bool {
+		return played(userID, tournament.Bye)
+	}
+
+	switch t.Format {
+	case entity.TournamentSingleElimination:
+		players := []int64{}
+		if t.Round == 0 {
+			seeded, err := server.TournamentRepo.Players(ctx, t.Id)
+			if err != nil {
+				return err
+			}
+			players = seeded
+		} else {
+			for _, match := range matches {
+				if match.Round == t.Round {
+					players = append(players, match.WinnerID)
+				}
+			}
+		}
+		pairings = tournament.PairSingleElimination(players, hadBye)
+
+	case entity.TournamentSwiss:
+		standings, err := server.TournamentRepo.Standings(ctx, t.Id)
+		if err != nil {
+			return err
+		}
+		pairings = tournament.PairSwiss(standings, played, hadBye)
+	}
+
+	t.Round++
+	t.RoundDeadline = time.Now().Add(server.Config.Tournament.RoundTime).Unix()
+	mode, err := server.Config.Modes.Get(t.Mode)
+	if err != nil {
+		mode = server.Config.Modes.Default()
+	}
+
+	for idx, pairing := range pairings {
+		match := entity.TournamentMatch{Round: t.Round, Index: idx, P1ID: pairing.P1ID, P2ID: pairing.P2ID}
+
+		if pairing.P2ID == tournament.Bye {
+			match.WinnerID = pairing.P1ID
+			if err := server.TournamentRepo.AddResult(ctx, t.Id, pairing.P1ID, true, 0); err != nil {
+				return err
+			}
+			server.notify(pairing.P1ID, fmt.Sprintf("🏆 %s round %d: you advance without playing.", t.Name, t.Round))
+		} else if busyID, err := server.busyPlayer(ctx, pairing); err != nil {
+			return err
+		} else if busyID != 0 {
+			// a new game would replace the active game of the player, the match is forfeited instead
+			match.WinnerID = pairing.P1ID
+			if busyID == pairing.P1ID {
+				match.WinnerID = pairing.P2ID
+			}
+			if err := server.TournamentRepo.AddResult(ctx, t.Id, match.WinnerID, true, 0); err != nil {
+				return err
+			}
+			if err := server.TournamentRepo.AddResult(ctx, t.Id, busyID, false, 0); err != nil {
+				return err
+			}
+			server.notify(match.WinnerID, fmt.Sprintf("🏆 %s round %d: your opponent is in another game, you win the match.", t.Name, t.Round))
+			server.notify(busyID, fmt.Sprintf("🏆 %s round %d: you lost the match because you were in another game.", t.Name, t.Round))
+		} else {
+			gameID, err := entity.GetOrInitID(server.DB, GAME_INDEX)
+			if err != nil {
+				return err
+			}
+			game := entity.NewModeGame(gameID, pairing.P1ID, pairing.P2ID, mode)
+			game.Tournament = t.Id
+			if err := server.GameRepo.Save(ctx, &game); err != nil {
+				return err
+			}
+			match.GameID = gameID
+
+			text := fmt.Sprintf("🏆 %s round %d of %d started! Open the game and press Start to play your match.", t.Name, t.Round, t.Rounds)
+			server.notify(pairing.P1ID, text)
+			server.notify(pairing.P2ID, text)
+		}
+
+		if err := server.TournamentRepo.SaveMatch(ctx, t.Id, match); err != nil {
+			return err
+		}
+	}
+
+	logrus.Info("tournament ", t.Id, " round ", t.Round, " paired with ", len(pairings), " matches")
+	return server.TournamentRepo.Save(ctx, &t)
+}
+
+// busyPlayer returns the player of the pairing with an active game, p2 when both have one, 0 when none has
+func (server *Server) busyPlayer(ctx context.Context, pairing tournament.Pairing) (int64, error) {
+	for _, playerID := range []int64{pairing.P2ID, pairing.P1ID} {
+		game, err := server.GameRepo.ActiveGame(ctx, playerID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if game.Status == entity.Active {
+			return playerID, nil
+		}
+	}
+	return 0, nil
+}
+
+// RecordTournamentGame decides the match of a completed tournament game, the player with more coins wins
+// and the better seed (p1) wins a tie
+func (server *Server) RecordTournamentGame(ctx context.Context, game entity.Game, p1Coins int, p2Coins int) error {
+	lock, err := server.lockTournament(ctx, game.Tournament)
+	if err != nil {
+		return fmt.Errorf("tournament lock error: %w", err)
+	}
+	defer lock.Release(ctx)
+
+	matches, err := server.TournamentRepo.Matches(ctx, game.Tournament)
+	if err != nil {
+		return err
+	}
+
+	for _, match := range matches {
+		if match.GameID != game.Id || match.Decided() {
+			continue
+		}
+
+		match.P1Coins, match.P2Coins = p1Coins, p2Coins
+		match.WinnerID = match.P1ID
+		if p2Coins > p1Coins {
+			match.WinnerID = match.P2ID
+		}
+		return server.TournamentRepo.DecideMatch(ctx, game.Tournament, match)
+	}
+	return nil
+}
+
+// forfeitLateMatches completes the games that passed the round deadline,
+// the player with fewer decisions loses and p2 loses a tie.
+// The match of a game that was completed without deciding it is decided again.
+func (server *Server) forfeitLateMatches(ctx context.Context, tournamentID uint, matches []entity.TournamentMatch) {
+	for _, match := range matches {
+		game, err := server.GameRepo.GetByID(ctx, match.GameID)
+		if err != nil {
+			logrus.Error("tournament game error ", err)
+			continue
+		}
+
+		if game.Status == entity.Completed {
+			p1Coins, p2Coins := 0, 0
+			for _, round := range game.GetRounds() {
+				roundP1, roundP2 := game.RoundPayout(round)
+				p1Coins += roundP1
+				p2Coins += roundP2
+			}
+			if err := server.RecordTournamentGame(ctx, game, p1Coins, p2Coins); err != nil {
+				logrus.Error("tournament ", tournamentID, " match error ", err)
+			}
+			continue
+		}
+
+		p1Decisions, p2Decisions := 0, 0
+		for _, round := range game.GetRounds() {
+			if round.P1Decision != "" {
+				p1Decisions++
+			}
+			if round.P2Decision != "" {
+				p2Decisions++
+			}
+		}
+		loserID := game.P2ID
+		if p1Decisions < p2Decisions {
+			loserID = game.P1ID
+		}
+
+		if err := server.ForfeitGame(ctx, game, loserID); err != nil && !errors.Is(err, ErrGameNotActive) {
+			logrus.Error("tournament forfeit error ", err)
+		}
+	}
+}
+
+// finishTournament pays the prize pool to the best players
+func (server *Server) finishTournament(ctx context.Context, t entity.Tournament) error {
+	standings, err := server.TournamentRepo.Standings(ctx, t.Id)
+	if err != nil {
+		return err
+	}
+	ranked := tournament.Rank(standings)
+	prizes := tournament.Prizes(t.PrizePool, len(ranked))
+
+	// the tournament is completed once every prize is paid, a later run pays the rest
+	winners := make([]int64, len(prizes))
+	for place := range prizes {
+		winners[place] = ranked[place].UserID
+	}
+	if _, err := server.payTournament(ctx, t, winners, func(i int) int { return prizes[i] }, func(i int) string {
+		return fmt.Sprintf("place %d", i+1)
+	}); err != nil {
+		return err
+	}
+
+	t.Status = entity.TournamentCompleted
+	if len(ranked) > 0 {
+		t.WinnerID = ranked[0].UserID
+	}
+	if err := server.TournamentRepo.Save(ctx, &t); err != nil {
+		return err
+	}
+
+	for place, standing := range ranked {
+		text := fmt.Sprintf("🏆 %s is over! You finished #%d with %d wins.", t.Name, place+1, standing.Wins)
+		if place < len(prizes) {
+			text += fmt.Sprintf(" You won %d coins!", prizes[place])
+		}
+		server.notify(standing.UserID, text)
+	}
+	return nil
+}
+
+// payTournament pays the amount of every user whose payout wasn't paid by an earlier run and returns them,
+// the payout is claimed before the credit and released only when the credit fails
+func (server *Server) payTournament(ctx context.Context, t entity.Tournament, userIDs []int64, amount func(i int) int, payout func(i int) string) ([]int64, error) {
+	paid := []int64{}
+	var errs []error
+	for i, userID := range userIDs {
+		claimed, err := server.TournamentRepo.ClaimPayout(ctx, t.Id, payout(i))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if amount(i) > 0 {
+			_, err := server.UpdateBalance(ctx, userID, amount(i), entity.LedgerTournament, fmt.Sprintf("tournament %d %s", t.Id, payout(i)), nil)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("tournament %d %s: %w", t.Id, payout(i), err))
+				if err := server.TournamentRepo.ReleasePayout(ctx, t.Id, payout(i)); err != nil {
+					errs = append(errs, err)
+				}
+				continue
+			}
+		}
+		paid = append(paid, userID)
+	}
+	return paid, errors.Join(errs...)
+}
+
+func (server *Server) lockTournament(ctx context.Context, tournamentID uint) (*redislock.Lock, error) {
+	return server.Locker.Obtain(
+		ctx,
+		fmt.Sprintf(TOURNAMENT_LOCK, tournamentID),
+		5*time.Second,
+		&redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 50)},
+	)
+}
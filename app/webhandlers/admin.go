@@ -0,0 +1,310 @@
+package webhandlers
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
+)
+
+//go:embed templates/admin.html
+var adminHTML string
+
+//go:embed templates/admin_user.html
+var adminUserHTML string
+
+//go:embed templates/admin_risk.html
+var adminRiskHTML string
+
+var adminFuncs = template.FuncMap{
+	"time": func(unix int64) string {
+		return time.Unix(unix, 0).UTC().Format("2006-01-02 15:04")
+	},
+	// games keep their creation time as uint
+	"gameTime": func(created uint) string {
+		return time.Unix(int64(created), 0).UTC().Format("2006-01-02 15:04")
+	},
+	"percent": func(score float64) string {
+		return fmt.Sprintf("%.0f%%", score*100)
+	},
+}
+
+type AdminHandlers struct {
+	server *app.Server
+}
+
+func NewAdminHandlers(server *app.Server) *AdminHandlers {
+	return &AdminHandlers{server: server}
+}
+
+// AdminMiddleware allows only the telegram ids listed in the admin config
+func (a *AdminHandlers) AdminMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user := app.GetUserFromCtx(c)
+		if !a.server.Config.Admin.IsAdmin(user.Id) {
+			logrus.Warn("admin access denied for ", user.Id)
+			return showNotification(c, "Access denied.")
+		}
+		return next(c)
+	}
+}
+
+// Serve the admin dashboard, with user search results when q is set
+func (a *AdminHandlers) OpenAdmin(c echo.Context) error {
+	ctx := context.Background()
+	data := schemas.AdminData{Query: strings.TrimSpace(c.QueryParam("q"))}
+
+	if data.Query != "" {
+		users, err := a.searchUsers(ctx, data.Query)
+		if err != nil {
+			logrus.Error("admin search error ", err)
+			return c.JSON(http.StatusInternalServerError, "admin error (0)")
+		}
+		data.Users = users
+	}
+
+	lobbyUserID, err := a.server.LobbyUserID(ctx)
+	if err != nil {
+		logrus.Error("admin lobby error ", err)
+	}
+	if lobbyUserID != 0 {
+		lobbyUser, err := a.server.UserRepo.GetByID(ctx, lobbyUserID)
+		if err == nil {
+			data.LobbyUser = &lobbyUser
+		}
+	}
+
+	data.Audit, err = a.server.AuditRepo.List(ctx, 20)
+	if err != nil {
+		logrus.Error("admin audit error ", err)
+	}
+
+	return renderAdminPage(c, "admin", adminHTML, data)
+}
+
+// Serve the details of a user with the moderation forms
+func (a *AdminHandlers) OpenUser(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
+	if err != nil {
+		return showNotification(c, "Invalid user id.")
+	}
+	return a.renderUser(c, userID)
+}
+
+// Serve the collusion report with the riskiest pairs
+func (a *AdminHandlers) OpenRisk(c echo.Context) error {
+	ctx := context.Background()
+
+	pairs, err := a.server.CollusionRepo.TopPairs(ctx, 50)
+	if err != nil {
+		logrus.Error("admin risk error ", err)
+		return c.JSON(http.StatusInternalServerError, "admin error (2)")
+	}
+
+	playerIDs := make([]int64, 0, len(pairs)*2)
+	for _, stats := range pairs {
+		playerIDs = append(playerIDs, stats.P1ID, stats.P2ID)
+	}
+	players, err := a.server.UserRepo.GetMany(ctx, playerIDs)
+	if err != nil {
+		logrus.Error("admin risk players error ", err)
+		return c.JSON(http.StatusInternalServerError, "admin error (3)")
+	}
+
+	data := schemas.AdminRiskData{Pairs: make([]schemas.PairRiskReport, len(pairs))}
+	for idx, stats := range pairs {
+		data.Pairs[idx].Stats = stats
+		data.Pairs[idx].P1Name = players[idx*2].DisplayName
+		data.Pairs[idx].P2Name = players[idx*2+1].DisplayName
+	}
+
+	return renderAdminPage(c, "admin_risk", adminRiskHTML, data)
+}
+
+func (a *AdminHandlers) AdjustBalance(c echo.Context) error {
+	return a.userAction(c, func(ctx context.Context, adminID int64, userID int64, reason string) error {
+		amount, err := strconv.Atoi(c.FormValue("amount"))
+		if err != nil {
+			return errors.New("invalid amount")
+		}
+		_, err = a.server.AdminAdjustBalance(ctx, adminID, userID, amount, reason)
+		return err
+	})
+}
+
+func (a *AdminHandlers) SetHourLimit(c echo.Context) error {
+	return a.userAction(c, func(ctx context.Context, adminID int64, userID int64, reason string) error {
+		limit, err := strconv.Atoi(c.FormValue("limit"))
+		if err != nil {
+			return errors.New("invalid hour limit")
+		}
+		_, err = a.server.AdminSetHourLimit(ctx, adminID, userID, limit, reason)
+		return err
+	})
+}
+
+// Ban bans the user for the given hours, or permanently when hours is 0
+func (a *AdminHandlers) Ban(c echo.Context) error {
+	return a.userAction(c, func(ctx context.Context, adminID int64, userID int64, reason string) error {
+		hours, err := strconv.Atoi(c.FormValue("hours"))
+		if err != nil || hours < 0 {
+			return errors.New("invalid ban duration")
+		}
+		until := entity.BanPermanent
+		if hours > 0 {
+			until = time.Now().Add(time.Duration(hours) * time.Hour).Unix()
+		}
+		_, err = a.server.AdminBan(ctx, adminID, userID, until, reason)
+		return err
+	})
+}
+
+func (a *AdminHandlers) Unban(c echo.Context) error {
+	return a.userAction(c, func(ctx context.Context, adminID int64, userID int64, reason string) error {
+		_, err := a.server.AdminUnban(ctx, adminID, userID, reason)
+		return err
+	})
+}
+
+// CompleteGame force completes an active game of the user
+func (a *AdminHandlers) CompleteGame(c echo.Context) error {
+	return a.userAction(c, func(ctx context.Context, adminID int64, userID int64, reason string) error {
+		gameID, err := strconv.ParseUint(c.Param("gameID"), 10, 64)
+		if err != nil {
+			return errors.New("invalid game id")
+		}
+		game, err := a.server.GameRepo.GetByID(ctx, uint(gameID))
+		if err != nil {
+			return err
+		}
+		if !game.HasPlayer(userID) {
+			return repository.ErrNotFound
+		}
+		_, err = a.server.AdminCompleteGame(ctx, adminID, game, reason)
+		return err
+	})
+}
+
+// userAction runs an admin action on the user of the route and renders the user page again
+func (a *AdminHandlers) userAction(c echo.Context, action func(ctx context.Context, adminID int64, userID int64, reason string) error) error {
+	admin := app.GetUserFromCtx(c)
+	userID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
+	if err != nil {
+		return showNotification(c, "Invalid user id.")
+	}
+
+	err = action(context.Background(), admin.Id, userID, c.FormValue("reason"))
+	if err != nil {
+		logrus.Warn("admin action error ", err)
+		return showNotification(c, fmt.Sprint("Action failed: ", err))
+	}
+
+	return a.renderUser(c, userID)
+}
+
+func (a *AdminHandlers) renderUser(c echo.Context, userID int64) error {
+	ctx := context.Background()
+
+	user, err := a.server.UserRepo.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return showNotification(c, "User Not Found.")
+	}
+	if err != nil {
+		logrus.Error("admin get user error ", err)
+		return c.JSON(http.StatusInternalServerError, "admin error (1)")
+	}
+
+	data := schemas.AdminUserData{User: user, Banned: user.IsBanned(time.Now())}
+	data.Progress, _ = a.server.AchieveRepo.GetProgress(ctx, userID)
+	data.Risk, _ = a.server.CollusionRepo.UserRisk(ctx, userID)
+
+	games, err := a.server.UserGames(ctx, userID)
+	if err != nil {
+		logrus.Error("admin user games error ", err)
+	}
+	slices.SortFunc(games, func(a, b entity.Game) int { return int(b.Id) - int(a.Id) })
+	for _, game := range games {
+		if game.Status == entity.Active {
+			data.ActiveGames = append(data.ActiveGames, game)
+		} else if len(data.Games) < 20 {
+			data.Games = append(data.Games, game)
+		}
+	}
+
+	data.Ledger, err = a.server.LedgerRepo.List(ctx, userID, 20)
+	if err != nil {
+		logrus.Error("admin ledger error ", err)
+	}
+
+	audit, err := a.server.AuditRepo.List(ctx, 500)
+	if err != nil {
+		logrus.Error("admin audit error ", err)
+	}
+	for _, entry := range audit {
+		if entry.TargetID == userID && entry.Action != entity.AuditCompleteGame {
+			data.Audit = append(data.Audit, entry)
+		}
+	}
+
+	return renderAdminPage(c, "admin_user", adminUserHTML, data)
+}
+
+// searchUsers finds users by exact id or by a part of the display name
+func (a *AdminHandlers) searchUsers(ctx context.Context, query string) ([]entity.User, error) {
+	if userID, err := strconv.ParseInt(query, 10, 64); err == nil {
+		user, err := a.server.UserRepo.GetByID(ctx, userID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return []entity.User{}, nil
+		}
+		return []entity.User{user}, err
+	}
+
+	users, err := a.server.UserRepo.Scan(ctx, "user:*", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []entity.User{}
+	for _, user := range users {
+		if user.Id != 0 && strings.Contains(strings.ToLower(user.DisplayName), strings.ToLower(query)) {
+			found = append(found, user)
+		}
+		if len(found) >= 50 {
+			break
+		}
+	}
+	return found, nil
+}
+
+// Helper function to render admin pages
+func renderAdminPage(c echo.Context, name string, html string, data any) error {
+	tmpl, err := template.New(name).Funcs(adminFuncs).Parse(html)
+	if err != nil {
+		logrus.Error("parse admin page error: ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render admin (1)")
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		logrus.Error("render admin page error: ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render admin (2)")
+	}
+
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
@@ -0,0 +1,46 @@
+package webhandlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/entity"
+)
+
+func TestRenderAdminPageEscapes(t *testing.T) {
+	const markup = `<script>alert(1)</script>`
+	user := entity.NewUser(10, markup, 0)
+	user.BanReason = markup
+	audit := []entity.AuditEntry{entity.NewAuditEntry(1, entity.AuditBan, 10, markup, markup)}
+	game := entity.NewGame(1, 10, 11)
+
+	pages := []struct {
+		name string
+		html string
+		data any
+	}{
+		{"admin", adminHTML, schemas.AdminData{Query: markup, Users: []entity.User{user}, LobbyUser: &user, Audit: audit}},
+		{"admin_user", adminUserHTML, schemas.AdminUserData{
+			User:        user,
+			Banned:      true,
+			ActiveGames: []entity.Game{game},
+			Games:       []entity.Game{game},
+			Ledger:      []entity.LedgerEntry{entity.NewLedgerEntry(10, 5, 5, entity.LedgerAdmin, markup)},
+			Audit:       audit,
+		}},
+		{"admin_risk", adminRiskHTML, schemas.AdminRiskData{Pairs: []schemas.PairRiskReport{{P1Name: markup, P2Name: markup}}}},
+	}
+	for _, page := range pages {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/admin", nil), rec)
+
+		assert.NoError(t, renderAdminPage(c, page.name, page.html, page.data))
+		assert.Equal(t, http.StatusOK, rec.Code, page.name)
+		assert.NotContains(t, rec.Body.String(), markup, page.name)
+	}
+}
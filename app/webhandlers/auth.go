@@ -5,18 +5,33 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/pkg/ratelimit"
 	"github.com/sirupsen/logrus"
 )
 
 type authHandlers struct {
-	server *app.Server
+	server    *app.Server
+	userLimit echo.MiddlewareFunc
 }
 
 func NewAuthHandlers(server *app.Server) *authHandlers {
-	return &authHandlers{server: server}
+	cfg := server.Config.RateLimit
+	return &authHandlers{
+		server: server,
+		userLimit: ratelimit.Middleware(ratelimit.MiddlewareConfig{
+			Limiter: ratelimit.NewTokenBucket(server.DB, cfg.UserBurst, cfg.UserInterval),
+			Key: func(c echo.Context) string {
+				return fmt.Sprintf(app.HTTP_RATE_LIMIT_USER, app.GetUserFromCtx(c).Id)
+			},
+			Denied: func(c echo.Context, result ratelimit.Result) error {
+				return showNotification(c, fmt.Sprintf("Too many requests, please wait %s.", result.RetryAfter.Round(time.Second)))
+			},
+		}),
+	}
 }
 
 func (a authHandlers) AuthorizeMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
@@ -38,18 +53,19 @@ func (a authHandlers) AuthorizeMiddleware(next echo.HandlerFunc) echo.HandlerFun
 		user_id := int64(user_data["id"].(float64))
 		// user_id := int64(790311667)
 
-		user, err := a.server.UserRepo.Get(
-			context.Background(),
-			fmt.Sprintf("user:%d", user_id))
+		user, err := a.server.UserRepo.GetByID(context.Background(), user_id)
 
 		if err == nil {
+			if user.IsBanned(time.Now()) {
+				return showNotification(c, fmt.Sprint("Your account is banned: ", user.BanReason))
+			}
 			c.Set("user", user)
 		} else {
 			logrus.Error("get data from user repo err: ", err)
 			return c.String(244, "error")
 		}
 
-		return next(c)
+		return a.userLimit(next)(c)
 
 	}
 }
@@ -19,7 +19,10 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/onionj/trust/app"
 	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/achievement"
+	"github.com/onionj/trust/internal/daily"
 	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
 	"github.com/onionj/trust/pkg/ratelimit"
 	"github.com/sirupsen/logrus"
 )
@@ -38,14 +41,6 @@ var gameHTML string
 //go:embed templates/notification.html
 var notificationHTML string
 
-const DEFAULT_LOBBY_NAME = "trust:default_lobby:name"
-const DEFAULT_LOBBY_LOCK = "trust:default_lobby:lock"
-const USER_LOCK = "trust:user%d:lock"
-const USER_LOCK_BALANCE = "trust:user%d:lock_balance"
-const GAME_LOCK = "trust:game%d:lock"
-const GAME_INDEX = "trust:game:index"
-const GAME_USER_HOUR_LIMIT = "trust:user%d:hour:limit"
-
 type GameHandlers struct {
 	server *app.Server
 	locker *redislock.Client
@@ -72,8 +67,11 @@ func (g *GameHandlers) OpenHome(c echo.Context) error {
 
 // Serve the menu page
 func (g *GameHandlers) OpenMenu(c echo.Context) error {
-	user := app.GetUserFromCtx(c)
+	return renderMenuPage(c, g.server, app.GetUserFromCtx(c))
+}
 
+// Helper function to render the menu page
+func renderMenuPage(c echo.Context, server *app.Server, user entity.User) error {
 	gamesReportRaw := strings.Split(user.LastGamesResult, "|")
 	slices.Reverse(gamesReportRaw)
 
@@ -89,6 +87,7 @@ func (g *GameHandlers) OpenMenu(c echo.Context) error {
 	}
 
 	gameShortReports := make([]schemas.GameShortReport, len(filteredGamesReport))
+	competitorIDs := make([]int64, len(filteredGamesReport))
 
 	for idx, shortReport := range filteredGamesReport {
 
@@ -96,14 +95,40 @@ func (g *GameHandlers) OpenMenu(c echo.Context) error {
 		shortReportData := strings.Split(shortReport, ":")
 		gameShortReports[idx].YourCoins = shortReportData[1]
 		gameShortReports[idx].CompetitorCoins = shortReportData[3]
+		competitorIDs[idx], _ = strconv.ParseInt(shortReportData[2], 10, 64)
+	}
 
-		competitor, err := g.server.UserRepo.Get(context.Background(), fmt.Sprintf("user:%s", shortReportData[2])) // TODO Get all in one pipe
-		if err == nil {
+	competitors, err := server.UserRepo.GetMany(context.Background(), competitorIDs)
+	if err != nil {
+		logrus.Error("get competitors error ", err)
+	}
+	for idx, competitor := range competitors {
+		if competitor.Id != 0 {
 			gameShortReports[idx].CompetitorName = competitor.DisplayName
 			gameShortReports[idx].CompetitorAvatarId = fmt.Sprint(competitor.AvatarID)
 		}
 	}
 
+	unlocked, err := server.AchieveRepo.Unlocked(context.Background(), user.Id)
+	if err != nil {
+		logrus.Error("get unlocked achievements error ", err)
+	}
+	achievements := []achievement.Definition{}
+	for _, definition := range achievement.Definitions {
+		if slices.Contains(unlocked, definition.ID) {
+			achievements = append(achievements, definition)
+		}
+	}
+
+	wallet, err := server.GameWallet(user)
+	if err != nil {
+		logrus.Error("game wallet error ", err)
+	}
+	tokensResetIn := ""
+	if wallet.ResetIn > 0 {
+		tokensResetIn = wallet.ResetIn.Round(time.Minute).String()
+	}
+
 	tmpl, err := template.New("menu").Parse(menuHTML)
 	if err != nil {
 		logrus.Error("Failed to render menu ", err)
@@ -111,7 +136,16 @@ func (g *GameHandlers) OpenMenu(c echo.Context) error {
 	}
 
 	var buf bytes.Buffer
-	err = tmpl.Execute(&buf, schemas.MenuData{User: user, GameShortReport: gameShortReports})
+	err = tmpl.Execute(&buf, schemas.MenuData{
+		User:            user,
+		GameShortReport: gameShortReports,
+		Achievements:    achievements,
+		DailyClaimed:    user.DailyLastClaim == daily.Today(time.Now(), server.Config.Reward.TimeZone),
+		IsAdmin:         server.Config.Admin.IsAdmin(user.Id),
+		Modes:           server.Config.Modes.All(),
+		Wallet:          wallet,
+		TokensResetIn:   tokensResetIn,
+	})
 	if err != nil {
 		logrus.Error("Failed to render menu ", err)
 		return c.JSON(http.StatusInternalServerError, "Failed to render menu")
@@ -120,6 +154,12 @@ func (g *GameHandlers) OpenMenu(c echo.Context) error {
 	return c.HTMLBlob(http.StatusOK, buf.Bytes())
 }
 
+// limitedText tells the user when the next game can start
+func limitedText(token ratelimit.Result) string {
+	return fmt.Sprintf("You've used your games for this hour and have no bonus tokens left. Your next game is available in %s, or get bonus tokens in the shop!",
+		token.RetryAfter.Round(time.Second))
+}
+
 // Start And Serve the game
 func (g *GameHandlers) StartGame(c echo.Context) error {
 	user := app.GetUserFromCtx(c)
@@ -128,7 +168,7 @@ func (g *GameHandlers) StartGame(c echo.Context) error {
 	// Lock User ID
 	userLock, err := g.locker.Obtain(
 		ctx,
-		fmt.Sprintf(USER_LOCK, user.Id),
+		fmt.Sprintf(app.USER_LOCK, user.Id),
 		30*time.Second,
 		&redislock.Options{
 			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(1*time.Second), 31)},
@@ -140,29 +180,37 @@ func (g *GameHandlers) StartGame(c echo.Context) error {
 	defer userLock.Release(ctx)
 
 	// Check if user is in any Active Game
-	dbGames, err := g.server.GameRepo.Scan(ctx, fmt.Sprintf("game:*p%d*", user.Id), 1000) // TODO: filter on status?
-	if err != nil {
-		logrus.Error("GameRepo.Scan error ", err)
+	activeGame, err := g.server.GameRepo.ActiveGame(ctx, user.Id)
+	if err == nil {
+		return renderGamePage(c, g, user, activeGame)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		logrus.Error("GameRepo.ActiveGame error ", err)
 		return errors.New("server error")
 	}
-	if len(dbGames) > 0 {
-		for _, game := range dbGames {
-			if game.Status == entity.Active {
-				return renderGamePage(c, g, user, game)
-			}
-		}
+
+	mode, err := g.server.Config.Modes.Get(c.QueryParam("mode"))
+	if err != nil {
+		return showNotification(c, "Unknown game mode.")
 	}
 
-	isLimited, _ := ratelimit.IsLimited(g.server.DB, fmt.Sprintf(GAME_USER_HOUR_LIMIT, int(user.Id)), user.HourLimit, time.Hour)
-	if isLimited {
+	// Reserve the game token, the game creation commits it
+	token, err := g.server.ReserveGameToken(ctx, user)
+	if errors.Is(err, ratelimit.ErrReservationPending) {
+		return showNotification(c, "You are already waiting for a game.")
+	} else if err != nil {
+		logrus.Error("reserve game token error ", err)
+		return c.JSON(http.StatusInternalServerError, "default lobby error (5)")
+	}
+	if !token.Allowed {
 		logrus.Warn("User Limited")
-		return showNotification(c, "You've reached your game limit for this hour and can't start a new game just yet. Please try again in an hour to continue playing!!")
+		return showNotification(c, limitedText(token))
 	}
+	defer g.server.ReleaseGameToken(ctx, user.Id)
 
-	// Lock Default lobby
+	// Lock the lobby of the game mode
 	lock, err := g.locker.Obtain(
 		ctx,
-		DEFAULT_LOBBY_LOCK,
+		fmt.Sprintf(app.LOBBY_LOCK, mode.Name),
 		5*time.Second,
 		&redislock.Options{
 			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(500*time.Millisecond), 5)},
@@ -173,53 +221,37 @@ func (g *GameHandlers) StartGame(c echo.Context) error {
 	}
 	defer lock.Release(ctx)
 
-	// Check if any user is in global lobby
-	defaultLobby, _ := g.server.DB.Get(ctx, DEFAULT_LOBBY_NAME).Result()
-
-	if defaultLobby != "" {
-		defaultLobbyUserID, err := strconv.ParseInt(defaultLobby, 10, 64)
-		if err != nil {
-			logrus.Error("defaultLobbyUserID parse error ", err)
-			return c.JSON(http.StatusInternalServerError, "default lobby error (1)")
-		}
-		if defaultLobbyUserID == user.Id {
-			return c.JSON(http.StatusInternalServerError, "default lobby error (2)")
-		}
+	// Check if any user in the lobby can be matched
+	opponent, found, err := g.server.FindOpponent(ctx, mode.Name, user.Id)
+	if err != nil {
+		logrus.Error("find opponent error ", err)
+		return c.JSON(http.StatusInternalServerError, "default lobby error (1)")
+	}
 
-		g.server.DB.Del(ctx, DEFAULT_LOBBY_NAME)
-		new_game_id, err := entity.GetOrInitID(g.server.DB, GAME_INDEX)
+	if found {
+		newGame, err := g.server.StartLobbyGame(ctx, mode, user.Id, opponent)
 		if err != nil {
 			logrus.Error("save new game error ", err)
 			return c.JSON(http.StatusInternalServerError, "default lobby error (3)")
 		}
-
-		newGame := entity.NewGame(new_game_id, user.Id, defaultLobbyUserID)
-		err = g.server.GameRepo.Save(ctx, newGame)
-		if err != nil {
-			logrus.Error("save new game error (1) ", err)
-			return c.JSON(http.StatusInternalServerError, "default lobby error (4)")
-		}
-
-		ratelimit.BurnToken(g.server.DB, fmt.Sprintf(GAME_USER_HOUR_LIMIT, int(user.Id)))
 		return renderGamePage(c, g, user, newGame)
 	}
 
-	// Set current user to default lobby
-	g.server.DB.Set(ctx, DEFAULT_LOBBY_NAME, fmt.Sprint(user.Id), 30*time.Second)
+	// Wait in the lobby for another opponent
+	err = g.server.JoinLobby(ctx, mode.Name, user.Id)
+	if err != nil {
+		logrus.Error("join lobby error ", err)
+		return c.JSON(http.StatusInternalServerError, "default lobby error (2)")
+	}
+	defer g.server.LeaveLobby(ctx, user.Id)
 	lock.Release(ctx)
 
 	// Wait for Game
 	for i := 0; i < 62; i++ {
 		time.Sleep(500 * time.Millisecond)
 
-		dbGames, _ := g.server.GameRepo.Scan(ctx, fmt.Sprintf("game*:p%d*", user.Id), 1000) // TODO: filter on status?
-		if len(dbGames) > 0 {
-			for _, game := range dbGames {
-				if game.Status == entity.Active {
-					ratelimit.BurnToken(g.server.DB, fmt.Sprintf(GAME_USER_HOUR_LIMIT, int(user.Id)))
-					return renderGamePage(c, g, user, game)
-				}
-			}
+		if game, err := g.server.GameRepo.ActiveGame(ctx, user.Id); err == nil {
+			return renderGamePage(c, g, user, game)
 		}
 	}
 
@@ -228,18 +260,30 @@ func (g *GameHandlers) StartGame(c echo.Context) error {
 
 func (g *GameHandlers) GetGameUpdate(c echo.Context) error {
 	user := app.GetUserFromCtx(c)
-	gameId := c.Param("gameID")
-
-	dbGames, err := g.server.GameRepo.Scan(context.Background(), fmt.Sprintf("game:*p%d*:%s", user.Id, gameId), 1)
+	gameId, err := strconv.Atoi(c.Param("gameID"))
 	if err != nil {
-		logrus.Error("GameRepo.Scan error ", err)
-		return errors.New("server error")
+		return showNotification(c, "Invalid game ID.")
 	}
-	if len(dbGames) < 1 {
+
+	game, err := g.server.GameRepo.GetByID(context.Background(), uint(gameId))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !game.HasPlayer(user.Id)) {
 		return showNotification(c, "Game Not Found.")
+	} else if err != nil {
+		logrus.Error("GameRepo.GetByID error ", err)
+		return errors.New("server error")
+	}
+
+	// a sealed round that wasn't revealed in time is forfeited by the late player
+	if forfeited, err := g.server.ForfeitLateReveal(context.Background(), game); err != nil {
+		logrus.Error("forfeit late reveal error ", err)
+	} else if forfeited {
+		if game, err = g.server.GameRepo.GetByID(context.Background(), game.Id); err != nil {
+			logrus.Error("GameRepo.GetByID error ", err)
+			return errors.New("server error")
+		}
 	}
 
-	return renderGamePage(c, g, user, dbGames[0])
+	return renderGamePage(c, g, user, game)
 }
 
 func (g *GameHandlers) GameChoice(c echo.Context) error {
@@ -261,15 +305,9 @@ func (g *GameHandlers) GameChoice(c echo.Context) error {
 		return showNotification(c, "Invalid choice.")
 	}
 
-	dbGamesNames := g.server.GameRepo.Keys(ctx, fmt.Sprintf("game:*p%d*:%d", user.Id, gameId))
-
-	if len(dbGamesNames) != 1 {
-		return showNotification(c, "Active Game Not Found.")
-	}
-
 	gameLock, err := g.locker.Obtain(
 		ctx,
-		fmt.Sprintf(GAME_LOCK, gameId),
+		fmt.Sprintf(app.GAME_LOCK, gameId),
 		5*time.Second,
 		&redislock.Options{
 			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 100)},
@@ -280,12 +318,26 @@ func (g *GameHandlers) GameChoice(c echo.Context) error {
 	}
 	defer gameLock.Release(ctx)
 
-	game, err := g.server.GameRepo.Get(ctx, dbGamesNames[0])
-	if err != nil {
+	game, err := g.server.GameRepo.GetByID(ctx, uint(gameId))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !game.HasPlayer(user.Id)) {
+		return showNotification(c, "Active Game Not Found.")
+	} else if err != nil {
 		logrus.Error("game get error ", err)
 		return c.JSON(http.StatusInternalServerError, "game error (1)")
 	}
 
+	// a forfeited or completed game takes no more decisions
+	if game.Status != entity.Active {
+		return showNotification(c, "This game is over.")
+	}
+	if game.Sealed == 1 {
+		return showNotification(c, "This game needs sealed choices.")
+	}
+
+	if round, _ := strconv.Atoi(roundId); round > game.Rounds {
+		return showNotification(c, "Invalid round id.")
+	}
+
 	if choice == entity.Steal {
 		playerChoices := []string{}
 		if game.P1ID == user.Id {
@@ -332,7 +384,7 @@ func (g *GameHandlers) GameChoice(c echo.Context) error {
 		}
 	}
 
-	err = g.updateGameResults(game)
+	err = g.server.UpdateGameResults(game)
 	if err != nil {
 		logrus.Error(gameId, " game not saved", err)
 		return showNotification(c, "Game Not Saved!.")
@@ -341,158 +393,6 @@ func (g *GameHandlers) GameChoice(c echo.Context) error {
 	return renderGamePage(c, g, user, game)
 }
 
-// Helper function to update game results with user choices
-func (g *GameHandlers) updateGameResults(game entity.Game) error {
-	err := g.server.GameRepo.Save(context.Background(), game)
-	if err != nil {
-		logrus.Error(game.Id, " game not saved", err)
-		return err
-	}
-
-	if game.R1Status != entity.Completed {
-		if game.R1P1Decision == entity.Share && game.R1P2Decision == entity.Share {
-			game.R1Winner = entity.P1P2
-			game.R1Status = entity.Completed
-			game.R1Rewards = game.Coins / 40
-		} else if game.R1P1Decision == entity.Steal && game.R1P2Decision == entity.Steal {
-			game.R1Winner = entity.Server
-			game.R1Status = entity.Completed
-		} else if game.R1P1Decision == entity.Share && game.R1P2Decision == entity.Steal {
-			game.R1Winner = entity.P2
-			game.R1Status = entity.Completed
-		} else if game.R1P1Decision == entity.Steal && game.R1P2Decision == entity.Share {
-			game.R1Winner = entity.P1
-			game.R1Status = entity.Completed
-		}
-	}
-	if game.R2Status != entity.Completed {
-		if game.R2P1Decision == entity.Share && game.R2P2Decision == entity.Share {
-			game.R2Winner = entity.P1P2
-			game.R2Status = entity.Completed
-			game.R2Rewards = game.Coins / 40
-		} else if game.R2P1Decision == entity.Steal && game.R2P2Decision == entity.Steal {
-			game.R2Winner = entity.Server
-			game.R2Status = entity.Completed
-		} else if game.R2P1Decision == entity.Share && game.R2P2Decision == entity.Steal {
-			game.R2Winner = entity.P2
-			game.R2Status = entity.Completed
-		} else if game.R2P1Decision == entity.Steal && game.R2P2Decision == entity.Share {
-			game.R2Winner = entity.P1
-			game.R2Status = entity.Completed
-		}
-	}
-	if game.R3Status != entity.Completed {
-		if game.R3P1Decision == entity.Share && game.R3P2Decision == entity.Share {
-			game.R3Winner = entity.P1P2
-			game.R3Status = entity.Completed
-			game.R3Rewards = game.Coins / 40
-		} else if game.R3P1Decision == entity.Steal && game.R3P2Decision == entity.Steal {
-			game.R3Winner = entity.Server
-			game.R3Status = entity.Completed
-		} else if game.R3P1Decision == entity.Share && game.R3P2Decision == entity.Steal {
-			game.R3Winner = entity.P2
-			game.R3Status = entity.Completed
-		} else if game.R3P1Decision == entity.Steal && game.R3P2Decision == entity.Share {
-			game.R3Winner = entity.P1
-			game.R3Status = entity.Completed
-		}
-	}
-	if game.R4Status != entity.Completed {
-		if game.R4P1Decision == entity.Share && game.R4P2Decision == entity.Share {
-			game.R4Winner = entity.P1P2
-			game.R4Status = entity.Completed
-			game.R4Rewards = game.Coins / 40
-		} else if game.R4P1Decision == entity.Steal && game.R4P2Decision == entity.Steal {
-			game.R4Winner = entity.Server
-			game.R4Status = entity.Completed
-		} else if game.R4P1Decision == entity.Share && game.R4P2Decision == entity.Steal {
-			game.R4Winner = entity.P2
-			game.R4Status = entity.Completed
-		} else if game.R4P1Decision == entity.Steal && game.R4P2Decision == entity.Share {
-			game.R4Winner = entity.P1
-			game.R4Status = entity.Completed
-		}
-	}
-
-	// calculate rewards and player coins
-	server := 0
-	p1 := 0
-	p2 := 0
-
-	if game.Status != entity.Completed && game.R4Status == entity.Completed {
-		game.Status = entity.Completed
-
-		gameWinners := []string{game.R1Winner, game.R2Winner, game.R3Winner, game.R4Winner}
-		gameRewards := []int{game.R1Rewards, game.R2Rewards, game.R3Rewards, game.R4Rewards}
-		perRoundCoin := game.Coins / game.Rounds
-
-		for idx, winner := range gameWinners {
-			if winner == entity.Server {
-				server += perRoundCoin
-			} else if winner == entity.P1 {
-				p1 += perRoundCoin
-			} else if winner == entity.P2 {
-				p2 += perRoundCoin
-			} else if winner == entity.P1P2 {
-				p2 += (perRoundCoin / 2)
-				p1 += (perRoundCoin / 2)
-				if gameRewards[idx] > 1 {
-					p1 += gameRewards[idx] / 2
-					p2 += gameRewards[idx] / 2
-				}
-			}
-		}
-
-		// save p1 balance
-		p1BalanceLock, err := g.locker.Obtain(
-			context.Background(),
-			fmt.Sprintf(USER_LOCK_BALANCE, game.P1ID),
-			5*time.Second,
-			&redislock.Options{
-				RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 10)},
-		)
-		if err != nil {
-			logrus.Error("user balance lock error ", err)
-		} else {
-			defer p1BalanceLock.Release(context.Background())
-			p1User, err := g.server.UserRepo.Get(context.Background(), fmt.Sprintf("user:%d", game.P1ID))
-			if err != nil {
-				logrus.Error("Get p1 error ", err)
-			} else {
-				p1User.Balance += p1
-				p1User.LastGamesResult += fmt.Sprintf("|%d:%d:%d:%d", game.Id, p1, game.P2ID, p2)
-				// TODO: Remove very Old Games
-				g.server.UserRepo.Save(context.Background(), p1User)
-			}
-		}
-
-		// save p2 balance
-		p2BalanceLock, err := g.locker.Obtain(
-			context.Background(),
-			fmt.Sprintf(USER_LOCK_BALANCE, game.P2ID),
-			5*time.Second,
-			&redislock.Options{
-				RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 10)},
-		)
-		if err != nil {
-			logrus.Error("user balance lock error ", err)
-		} else {
-			defer p2BalanceLock.Release(context.Background())
-			p2User, err := g.server.UserRepo.Get(context.Background(), fmt.Sprintf("user:%d", game.P2ID))
-			if err != nil {
-				logrus.Error("Get p2 error ", err)
-			} else {
-				p2User.Balance += p2
-				p2User.LastGamesResult += fmt.Sprintf("|%d:%d:%d:%d", game.Id, p2, game.P1ID, p1)
-				// TODO: Remove very Old Games
-				g.server.UserRepo.Save(context.Background(), p2User)
-			}
-		}
-	}
-
-	return g.server.GameRepo.Save(context.Background(), game)
-}
-
 // Helper function to render the game page
 func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game entity.Game) error {
 	competitorId := int64(0)
@@ -502,7 +402,7 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 		competitorId = game.P2ID
 	}
 
-	competitor, err := g.server.UserRepo.Get(context.Background(), fmt.Sprintf("user:%d", competitorId))
+	competitor, err := g.server.UserRepo.GetByID(context.Background(), competitorId)
 	if err != nil {
 		logrus.Error("cant find competitor", err)
 		return c.JSON(http.StatusInternalServerError, "cant find competitor")
@@ -535,6 +435,8 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 		"ActiveRound":              "1",
 		"StealActive":              "true",
 		"StealCount":               fmt.Sprint(game.MaxSteal),
+		"Committed":                "",
+		"RevealRound":              "",
 	}
 
 	newGameSum := ""
@@ -558,38 +460,6 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 			if game.R4P1Decision != "" {
 				gameResults["round4CompetitorDecision"] = game.R4P2Decision
 			}
-
-			if game.R1Winner == entity.P1 {
-				gameResults["round1Result"] = fmt.Sprint(perRoundCoins)
-			} else if game.R1Winner == entity.P2 || game.R1Winner == entity.Server {
-				gameResults["round1Result"] = "0"
-			} else if game.R1Winner == entity.P1P2 {
-				gameResults["round1Result"] = fmt.Sprint((perRoundCoins + game.R1Rewards) / 2)
-			}
-
-			if game.R2Winner == entity.P1 {
-				gameResults["round2Result"] = fmt.Sprint(perRoundCoins)
-			} else if game.R2Winner == entity.P2 || game.R2Winner == entity.Server {
-				gameResults["round2Result"] = "0"
-			} else if game.R2Winner == entity.P1P2 {
-				gameResults["round2Result"] = fmt.Sprint((perRoundCoins + game.R2Rewards) / 2)
-			}
-
-			if game.R3Winner == entity.P1 {
-				gameResults["round3Result"] = fmt.Sprint(perRoundCoins)
-			} else if game.R3Winner == entity.P2 || game.R3Winner == entity.Server {
-				gameResults["round3Result"] = "0"
-			} else if game.R3Winner == entity.P1P2 {
-				gameResults["round3Result"] = fmt.Sprint((perRoundCoins + game.R3Rewards) / 2)
-			}
-
-			if game.R4Winner == entity.P1 {
-				gameResults["round4Result"] = fmt.Sprint(perRoundCoins)
-			} else if game.R4Winner == entity.P2 || game.R4Winner == entity.Server {
-				gameResults["round4Result"] = "0"
-			} else if game.R4Winner == entity.P1P2 {
-				gameResults["round4Result"] = fmt.Sprint((perRoundCoins + game.R4Rewards) / 2)
-			}
 		}
 
 		if game.P2ID == user.Id {
@@ -609,37 +479,24 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 			if game.R4P2Decision != "" {
 				gameResults["round4CompetitorDecision"] = game.R4P1Decision
 			}
+		}
 
-			if game.R1Winner == entity.P2 {
-				gameResults["round1Result"] = fmt.Sprint(perRoundCoins)
-			} else if game.R1Winner == entity.P1 || game.R1Winner == entity.Server {
-				gameResults["round1Result"] = "0"
-			} else if game.R1Winner == entity.P1P2 {
-				gameResults["round1Result"] = fmt.Sprint((perRoundCoins + game.R1Rewards) / 2)
-			}
-
-			if game.R2Winner == entity.P2 {
-				gameResults["round2Result"] = fmt.Sprint(perRoundCoins)
-			} else if game.R2Winner == entity.P1 || game.R2Winner == entity.Server {
-				gameResults["round2Result"] = "0"
-			} else if game.R2Winner == entity.P1P2 {
-				gameResults["round2Result"] = fmt.Sprint((perRoundCoins + game.R2Rewards) / 2)
-			}
-
-			if game.R3Winner == entity.P2 {
-				gameResults["round3Result"] = fmt.Sprint(perRoundCoins)
-			} else if game.R3Winner == entity.P1 || game.R3Winner == entity.Server {
-				gameResults["round3Result"] = "0"
-			} else if game.R3Winner == entity.P1P2 {
-				gameResults["round3Result"] = fmt.Sprint((perRoundCoins + game.R3Rewards) / 2)
+		for idx, round := range game.GetRounds() {
+			p1Coins, p2Coins := game.RoundPayout(round)
+			if game.P1ID == user.Id {
+				gameResults[fmt.Sprintf("round%dResult", idx+1)] = fmt.Sprint(p1Coins)
+			} else {
+				gameResults[fmt.Sprintf("round%dResult", idx+1)] = fmt.Sprint(p2Coins)
 			}
+		}
 
-			if game.R4Winner == entity.P2 {
-				gameResults["round4Result"] = fmt.Sprint(perRoundCoins)
-			} else if game.R4Winner == entity.P1 || game.R4Winner == entity.Server {
-				gameResults["round4Result"] = "0"
-			} else if game.R4Winner == entity.P1P2 {
-				gameResults["round4Result"] = fmt.Sprint((perRoundCoins + game.R4Rewards) / 2)
+		// blind games reveal nothing about the competitor until the game is completed
+		if game.Hidden() {
+			for round := 1; round <= entity.MaxRounds; round++ {
+				if gameResults[fmt.Sprintf("round%dYourDecision", round)] != "" {
+					gameResults[fmt.Sprintf("round%dCompetitorDecision", round)] = "?"
+				}
+				gameResults[fmt.Sprintf("round%dResult", round)] = "?"
 			}
 		}
 
@@ -662,6 +519,9 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 			finalRoundsResult += r4s
 		}
 		gameResults["AllRoundResult"] = fmt.Sprint(finalRoundsResult)
+		if game.Hidden() {
+			gameResults["AllRoundResult"] = "?"
+		}
 
 		if gameResults["round4YourDecision"] != "" {
 			gameResults["ActiveRound"] = "-1"
@@ -672,6 +532,9 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 		} else if gameResults["round1YourDecision"] != "" {
 			gameResults["ActiveRound"] = "2"
 		}
+		if gameResults[fmt.Sprintf("round%dYourDecision", game.Rounds)] != "" {
+			gameResults["ActiveRound"] = "-1"
+		}
 
 		playerChoices := []string{}
 		if game.P1ID == user.Id {
@@ -691,6 +554,20 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 			gameResults["StealActive"] = "false"
 		}
 
+		// sealed games wait for both commitments before the players reveal
+		if game.Sealed == 1 {
+			gameResults["Committed"], gameResults["RevealRound"] = "", ""
+			for idx, round := range game.GetRounds() {
+				if round.Commitment(game, user.Id) == "" || round.Decision(game, user.Id) != "" {
+					continue
+				}
+				gameResults["Committed"] = "true"
+				if round.CompetitorCommitment(game, user.Id) != "" {
+					gameResults["RevealRound"] = fmt.Sprint(idx + 1)
+				}
+			}
+		}
+
 		// Generate data hash for long poling algorithm
 		shaHash := sha256.New()
 		shaHash.Write([]byte(
@@ -714,18 +591,23 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 				gameResults["round4Result"] +
 				gameResults["AllRoundResult"] +
 				gameResults["AllRoundCoins"] +
-				gameResults["ActiveRound"]))
+				gameResults["ActiveRound"] +
+				gameResults["Committed"] +
+				gameResults["RevealRound"]))
 		newGameSum = hex.EncodeToString(shaHash.Sum(nil))
 
 		if newGameSum != gameSum {
 			break
 		} else { // TODO Create a Global Query Pipe for these query type
 			time.Sleep(500 * time.Millisecond)
-			game, _ = g.server.GameRepo.Get(
-				context.Background(),
-				fmt.Sprintf("game:p%d:p%d:%d", game.P1ID, game.P2ID, game.Id))
+			game, _ = g.server.GameRepo.GetByID(context.Background(), game.Id)
 		}
 	}
+	modeTitle := ""
+	if mode, err := g.server.Config.Modes.Get(game.Mode); err == nil && game.Mode != "" {
+		modeTitle = mode.Title
+	}
+
 	tmpl, err := template.New("game").Parse(gameHTML)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, "failed to render game (1)")
@@ -734,7 +616,16 @@ func renderGamePage(c echo.Context, g *GameHandlers, user entity.User, game enti
 	var buf bytes.Buffer
 	err = tmpl.Execute(
 		&buf,
-		schemas.GameData{Game: game, Competitor: competitor, GameResults: gameResults, GameResultsSum: newGameSum})
+		schemas.GameData{
+			Game:           game,
+			Competitor:     competitor,
+			GameResults:    gameResults,
+			GameResultsSum: newGameSum,
+			ModeTitle:      modeTitle,
+			CoopBonus:      game.RoundCoopReward() / 2,
+			StealRefund:    game.Payoff().Coins(perRoundCoins, game.Payoff().BothSteal),
+			SuckerCoins:    game.Payoff().Coins(perRoundCoins, game.Payoff().Sucker),
+		})
 	if err != nil {
 		logrus.Error("render game page error: ", err)
 		return c.JSON(http.StatusInternalServerError, "failed to render game (2)")
@@ -0,0 +1,229 @@
+package webhandlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/pkg/ratelimit"
+)
+
+//go:embed templates/group.html
+var groupHTML string
+
+type GroupHandlers struct {
+	server *app.Server
+	locker *redislock.Client
+}
+
+func NewGroupHandlers(server *app.Server) *GroupHandlers {
+	return &GroupHandlers{server: server, locker: server.Locker}
+}
+
+// StartGroupGame puts the user in the group lobby and serves the group game once it starts
+func (g *GroupHandlers) StartGroupGame(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+
+	// Lock User ID
+	userLock, err := g.locker.Obtain(
+		ctx,
+		fmt.Sprintf(app.USER_LOCK, user.Id),
+		30*time.Second,
+		&redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(1*time.Second), 31)},
+	)
+	if err != nil {
+		logrus.Error("user lock error ", err)
+		return errors.New("server error")
+	}
+	defer userLock.Release(ctx)
+
+	// Check if user is in an active group game
+	game, err := g.server.ActiveGroupGame(ctx, user.Id)
+	if err == nil {
+		return renderGroupPage(c, g, user, game)
+	} else if !errors.Is(err, app.ErrNoGroupGame) {
+		logrus.Error("active group game error ", err)
+		return errors.New("server error")
+	}
+
+	// Reserve the game token, the group creation commits it
+	token, err := g.server.ReserveGameToken(ctx, user)
+	if errors.Is(err, ratelimit.ErrReservationPending) {
+		return showNotification(c, "You are already waiting for a game.")
+	} else if err != nil {
+		logrus.Error("reserve game token error ", err)
+		return c.JSON(http.StatusInternalServerError, "group lobby error (1)")
+	}
+	if !token.Allowed {
+		logrus.Warn("User Limited")
+		return showNotification(c, limitedText(token))
+	}
+	defer g.server.ReleaseGameToken(ctx, user.Id)
+
+	// Wait in the group lobby until enough players joined
+	err = g.server.JoinGroupLobby(ctx, user.Id)
+	if err != nil {
+		logrus.Error("join group lobby error ", err)
+		return c.JSON(http.StatusInternalServerError, "group lobby error (0)")
+	}
+	defer g.server.LeaveLobby(ctx, user.Id)
+
+	for i := 0; i < 62; i++ {
+		if _, _, err := g.server.FormGroup(ctx); err != nil {
+			logrus.Error("form group error ", err)
+		}
+
+		game, err := g.server.ActiveGroupGame(ctx, user.Id)
+		if err == nil {
+			return renderGroupPage(c, g, user, game)
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return showNotification(c, "Not enough players for a group game, please try again.")
+}
+
+func (g *GroupHandlers) GetGroupUpdate(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+
+	gameId, err := strconv.ParseUint(c.Param("gameID"), 10, 64)
+	if err != nil {
+		return showNotification(c, "Invalid game ID.")
+	}
+
+	game, err := g.server.GroupRepo.GetByID(context.Background(), uint(gameId))
+	if err != nil || game.Seat(user.Id) < 0 {
+		return showNotification(c, "Game Not Found.")
+	}
+
+	return renderGroupPage(c, g, user, game)
+}
+
+func (g *GroupHandlers) GroupChoice(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+
+	gameId, err := strconv.Atoi(c.Param("gameID"))
+	if err != nil {
+		return showNotification(c, "Invalid game ID.")
+	}
+
+	round, err := strconv.Atoi(c.Param("roundID"))
+	if err != nil {
+		return showNotification(c, "Invalid round id.")
+	}
+
+	choice := c.Param("choice")
+	if choice != entity.Share && choice != entity.Steal {
+		return showNotification(c, "Invalid choice.")
+	}
+
+	game, err := g.server.GroupChoice(context.Background(), uint(gameId), user.Id, round, choice)
+	if errors.Is(err, entity.ErrNotInGroup) || errors.Is(err, entity.ErrRoundNotOpen) {
+		return showNotification(c, "Active Game Not Found.")
+	} else if err != nil {
+		logrus.Error(gameId, " group game not saved ", err)
+		return showNotification(c, "Game Not Saved!.")
+	}
+
+	return renderGroupPage(c, g, user, game)
+}
+
+// Helper function to render the group game page, waits for a change like renderGamePage
+func renderGroupPage(c echo.Context, g *GroupHandlers, user entity.User, game entity.GroupGame) error {
+	ctx := context.Background()
+
+	players, err := g.server.UserRepo.GetMany(ctx, game.PlayerIDs())
+	if err != nil || slices.ContainsFunc(players, func(player entity.User) bool { return player.Id == 0 }) {
+		logrus.Error("cant find group player ", err)
+		return c.JSON(http.StatusInternalServerError, "cant find group player")
+	}
+
+	data := schemas.GroupData{}
+	for range 100 {
+		// the players who let the round deadline pass are out of the game
+		if forfeited, err := g.server.ForfeitIdleGroupPlayers(ctx, game); err != nil {
+			logrus.Error("forfeit idle group players error ", err)
+		} else {
+			game = forfeited
+		}
+
+		data = groupData(game, user, players)
+		if data.Sum != c.QueryParam("gameSum") {
+			break
+		}
+
+		time.Sleep(500 * time.Millisecond)
+		dbGame, err := g.server.GroupRepo.Get(ctx, game.EntityID().String())
+		if err == nil {
+			game = dbGame
+		}
+	}
+
+	tmpl, err := template.New("group").Parse(groupHTML)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, "failed to render group game (1)")
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		logrus.Error("render group game page error: ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render group game (2)")
+	}
+
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
+
+// groupData builds the view of the player, decisions of a round are revealed when every player decided
+func groupData(game entity.GroupGame, user entity.User, players []entity.User) schemas.GroupData {
+	seat := game.Seat(user.Id)
+	data := schemas.GroupData{
+		Game:        game,
+		Players:     players,
+		RoundCoins:  game.Coins / game.Rounds,
+		ActiveRound: game.ActiveRound(user.Id),
+	}
+
+	for round := 1; round <= game.Rounds; round++ {
+		decisions := game.RoundDecisions(round)
+		groupRound := schemas.GroupRound{
+			Number:       round,
+			YourDecision: decisions[seat],
+			Completed:    game.RoundCompleted(round),
+		}
+		if groupRound.Completed {
+			for _, decision := range decisions {
+				if decision == entity.Share {
+					groupRound.Shares++
+				} else if decision == entity.Steal {
+					groupRound.Steals++
+				}
+			}
+			groupRound.YourCoins = game.RoundPayout(round)[seat]
+			data.TotalCoins += groupRound.YourCoins
+		}
+		data.Rounds = append(data.Rounds, groupRound)
+	}
+
+	// Generate data hash for long poling algorithm
+	data.Sum = fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprint(game.Status, data.ActiveRound, data.TotalCoins, data.Rounds))))
+	return data
+}
@@ -0,0 +1,159 @@
+package webhandlers
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+	"strconv"
+	"text/template"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/entity"
+)
+
+//go:embed templates/profile.html
+var profileHTML string
+
+type ProfileHandlers struct {
+	server *app.Server
+}
+
+func NewProfileHandlers(server *app.Server) *ProfileHandlers {
+	return &ProfileHandlers{server: server}
+}
+
+// Serve the profile page with the avatar picker
+func (p *ProfileHandlers) OpenProfile(c echo.Context) error {
+	return renderProfilePage(c, p, app.GetUserFromCtx(c))
+}
+
+// SelectAvatar sets one of the owned avatars as the user avatar
+func (p *ProfileHandlers) SelectAvatar(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+
+	avatar, err := avatarFromParam(c)
+	if err != nil {
+		return showNotification(c, "Invalid avatar.")
+	}
+
+	owned, err := ownsAvatar(ctx, p.server, user, avatar)
+	if err != nil {
+		logrus.Error("inventory error ", err)
+		return c.JSON(http.StatusInternalServerError, "profile error (0)")
+	}
+	if !owned {
+		return showNotification(c, "You don't own this avatar.")
+	}
+	if err := p.server.KeepAvatar(ctx, user); err != nil {
+		logrus.Error("inventory error ", err)
+		return c.JSON(http.StatusInternalServerError, "profile error (0)")
+	}
+
+	user, err = p.server.UpdateUser(ctx, user.Id, func(user *entity.User) error {
+		user.AvatarID = avatar.ID
+		return nil
+	})
+	if err != nil {
+		logrus.Error("save avatar error ", err)
+		return c.JSON(http.StatusInternalServerError, "profile error (1)")
+	}
+
+	return renderProfilePage(c, p, user)
+}
+
+// BuyAvatar buys the avatar from the shop and selects it
+func (p *ProfileHandlers) BuyAvatar(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+
+	avatar, err := avatarFromParam(c)
+	if err != nil || avatar.Price <= 0 {
+		return showNotification(c, "This avatar is not for sale.")
+	}
+
+	_, err = p.server.BuyItem(ctx, user.Id, avatar.ShopItemID())
+	if errors.Is(err, app.ErrUnknownItem) {
+		return showNotification(c, "This avatar is not for sale.")
+	} else if errors.Is(err, app.ErrItemOwned) {
+		return showNotification(c, "You already own this avatar.")
+	} else if errors.Is(err, app.ErrInsufficientBalance) {
+		return showNotification(c, "You don't have enough coins.")
+	} else if err != nil {
+		logrus.Error("buy avatar error ", err)
+		return c.JSON(http.StatusInternalServerError, "profile error (2)")
+	}
+
+	user, err = p.server.UpdateUser(ctx, user.Id, func(user *entity.User) error {
+		user.AvatarID = avatar.ID
+		return nil
+	})
+	if err != nil {
+		logrus.Error("save avatar error ", err)
+		return c.JSON(http.StatusInternalServerError, "profile error (1)")
+	}
+
+	return renderProfilePage(c, p, user)
+}
+
+func avatarFromParam(c echo.Context) (entity.Avatar, error) {
+	avatarID, err := strconv.Atoi(c.Param("avatarID"))
+	if err != nil {
+		return entity.Avatar{}, err
+	}
+	avatar, ok := entity.FindAvatar(avatarID)
+	if !ok {
+		return entity.Avatar{}, errors.New("avatar not found")
+	}
+	return avatar, nil
+}
+
+func ownsAvatar(ctx context.Context, server *app.Server, user entity.User, avatar entity.Avatar) (bool, error) {
+	if avatar.Free() || user.AvatarID == avatar.ID {
+		return true, nil
+	}
+	return server.InventoryRepo.Has(ctx, user.Id, entity.InventoryAvatar, avatar.ItemID())
+}
+
+// Helper function to render the profile page
+func renderProfilePage(c echo.Context, p *ProfileHandlers, user entity.User) error {
+	owned, err := p.server.InventoryRepo.List(context.Background(), user.Id, entity.InventoryAvatar)
+	if err != nil {
+		logrus.Error("inventory list error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render profile (0)")
+	}
+
+	avatars := make([]schemas.AvatarItem, len(entity.Avatars))
+	for idx, avatar := range entity.Avatars {
+		avatars[idx] = schemas.AvatarItem{
+			Avatar:   avatar,
+			Owned:    avatar.Free() || user.AvatarID == avatar.ID,
+			Selected: user.AvatarID == avatar.ID,
+		}
+		for _, itemID := range owned {
+			if itemID == avatar.ItemID() {
+				avatars[idx].Owned = true
+			}
+		}
+	}
+
+	tmpl, err := template.New("profile").Parse(profileHTML)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, "failed to render profile (1)")
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, schemas.ProfileData{User: user, Avatars: avatars})
+	if err != nil {
+		logrus.Error("render profile page error: ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render profile (2)")
+	}
+
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
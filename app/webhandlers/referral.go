@@ -0,0 +1,86 @@
+package webhandlers
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"net/http"
+	"text/template"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/app/schemas"
+)
+
+//go:embed templates/referrals.html
+var referralsHTML string
+
+type ReferralHandlers struct {
+	server *app.Server
+}
+
+func NewReferralHandlers(server *app.Server) *ReferralHandlers {
+	return &ReferralHandlers{server: server}
+}
+
+// Serve the referral dashboard
+func (r *ReferralHandlers) OpenReferrals(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+	cfg := r.server.Config.Reward
+
+	refereeIDs, err := r.server.ReferralRepo.List(ctx, user.Id)
+	if err != nil {
+		logrus.Error("referral list error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render referrals (0)")
+	}
+
+	data := schemas.ReferralData{
+		User:     user,
+		Link:     r.server.ReferralLink(user.Id),
+		Bonus:    cfg.ReferralBonus,
+		MinGames: cfg.ReferralMinGames,
+		MaxCount: cfg.ReferralMaxPerUser,
+		Referees: make([]schemas.RefereeReport, 0, len(refereeIDs)),
+	}
+
+	referees, err := r.server.UserRepo.GetMany(ctx, refereeIDs)
+	if err != nil {
+		logrus.Error("get referees error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render referrals (1)")
+	}
+
+	for _, referee := range referees {
+		if referee.Id == 0 {
+			continue
+		}
+		progress, _ := r.server.AchieveRepo.GetProgress(ctx, referee.Id)
+
+		report := schemas.RefereeReport{
+			Name:        referee.DisplayName,
+			AvatarID:    referee.AvatarID,
+			GamesPlayed: min(progress.GamesPlayed, cfg.ReferralMinGames),
+			Rewarded:    referee.ReferralRewarded != 0,
+		}
+		if report.Rewarded {
+			data.Earned += cfg.ReferralBonus
+		}
+		data.Referees = append(data.Referees, report)
+	}
+
+	tmpl, err := template.New("referrals").Parse(referralsHTML)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, "failed to render referrals (1)")
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		logrus.Error("render referrals page error: ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render referrals (2)")
+	}
+
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
@@ -0,0 +1,81 @@
+package webhandlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/internal/daily"
+	"github.com/onionj/trust/internal/entity"
+)
+
+type RewardHandlers struct {
+	server *app.Server
+	locker *redislock.Client
+}
+
+func NewRewardHandlers(server *app.Server) *RewardHandlers {
+	return &RewardHandlers{server: server, locker: server.Locker}
+}
+
+// ClaimDaily credits the daily reward once per day and grows the streak
+func (r *RewardHandlers) ClaimDaily(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+	cfg := r.server.Config.Reward
+
+	// Lock daily claim, a second concurrent claim fails immediately
+	dailyLock, err := r.locker.Obtain(ctx, fmt.Sprintf(app.USER_LOCK_DAILY, user.Id), 10*time.Second, nil)
+	if errors.Is(err, redislock.ErrNotObtained) {
+		return showNotification(c, "Your daily reward is being claimed.")
+	}
+	if err != nil {
+		logrus.Error("daily lock error ", err)
+		return c.JSON(http.StatusInternalServerError, "daily reward error (0)")
+	}
+	defer dailyLock.Release(ctx)
+
+	user, err = r.server.UserRepo.GetByID(ctx, user.Id)
+	if err != nil {
+		logrus.Error("get user error ", err)
+		return c.JSON(http.StatusInternalServerError, "daily reward error (1)")
+	}
+
+	now := time.Now()
+	claim, err := daily.NextClaim(user, now, cfg.TimeZone, cfg.DailyBase, cfg.DailyMaxMultiplier)
+	if errors.Is(err, daily.ErrAlreadyClaimed) {
+		return showNotification(c, "You already claimed today's reward. Come back tomorrow!")
+	}
+
+	note := fmt.Sprintf("daily %s streak %d", claim.Day, claim.Streak)
+	user, err = r.server.UpdateBalance(ctx, user.Id, claim.Reward, entity.LedgerDaily, note, func(user *entity.User) error {
+		check, err := daily.NextClaim(*user, now, cfg.TimeZone, cfg.DailyBase, cfg.DailyMaxMultiplier)
+		if err != nil {
+			return err
+		}
+		if check != claim {
+			return errors.New("daily claim changed")
+		}
+		claim.Apply(user)
+		return nil
+	})
+	if errors.Is(err, daily.ErrAlreadyClaimed) {
+		return showNotification(c, "You already claimed today's reward. Come back tomorrow!")
+	}
+	if err != nil {
+		logrus.Error("claim daily error ", err)
+		return c.JSON(http.StatusInternalServerError, "daily reward error (2)")
+	}
+	if err := r.server.GrantBonusTokens(ctx, user.Id, cfg.DailyBonusTokens); err != nil {
+		logrus.Error("daily bonus tokens error ", err)
+	}
+
+	return renderMenuPage(c, r.server, user)
+}
@@ -0,0 +1,179 @@
+package webhandlers
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/bsm/redislock"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/repository"
+)
+
+//go:embed templates/replay.html
+var replayHTML string
+
+// CommitChoice stores the hash commitment of the player for a round of a sealed game
+func (g *GameHandlers) CommitChoice(c echo.Context) error {
+	return g.updateSealedGame(c, func(game *entity.Game, userID int64, round int) error {
+		return game.Commit(round, userID, c.Param("commitment"))
+	})
+}
+
+// RevealChoice opens the commitment of the player once both players committed to the round
+func (g *GameHandlers) RevealChoice(c echo.Context) error {
+	return g.updateSealedGame(c, func(game *entity.Game, userID int64, round int) error {
+		choice := c.Param("choice")
+		if choice != entity.Share && choice != entity.Steal {
+			return errors.New("invalid choice")
+		}
+		if choice == entity.Steal && stealCount(*game, userID) >= game.MaxSteal {
+			return errors.New("you can not steal anymore")
+		}
+		return game.Reveal(round, userID, choice, c.Param("nonce"))
+	})
+}
+
+// updateSealedGame applies the change to the sealed game under the game lock and settles the decided rounds
+func (g *GameHandlers) updateSealedGame(c echo.Context, change func(game *entity.Game, userID int64, round int) error) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+
+	gameId, err := strconv.Atoi(c.Param("gameID"))
+	if err != nil {
+		return showNotification(c, "Invalid game ID.")
+	}
+	round, err := strconv.Atoi(c.Param("roundID"))
+	if err != nil {
+		return showNotification(c, "Invalid round id.")
+	}
+
+	gameLock, err := g.locker.Obtain(
+		ctx,
+		fmt.Sprintf(app.GAME_LOCK, gameId),
+		5*time.Second,
+		&redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 100)},
+	)
+	if err != nil {
+		logrus.Error("game lock error ", err)
+		return c.JSON(http.StatusInternalServerError, "game error (0)")
+	}
+	defer gameLock.Release(ctx)
+
+	game, err := g.server.GameRepo.GetByID(ctx, uint(gameId))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !game.HasPlayer(user.Id)) {
+		return showNotification(c, "Active Game Not Found.")
+	} else if err != nil {
+		logrus.Error("game get error ", err)
+		return c.JSON(http.StatusInternalServerError, "game error (1)")
+	}
+	if game.Status != entity.Active {
+		return showNotification(c, "This game is over.")
+	}
+	if game.Sealed != 1 {
+		return showNotification(c, "This game is not sealed.")
+	}
+
+	if err := change(&game, user.Id, round); err != nil {
+		return showNotification(c, fmt.Sprint("Choice not accepted: ", err))
+	}
+
+	err = g.server.UpdateGameResults(game)
+	if err != nil {
+		logrus.Error(gameId, " game not saved", err)
+		return showNotification(c, "Game Not Saved!.")
+	}
+
+	return renderGamePage(c, g, user, game)
+}
+
+// OpenReplay shows every round of a completed game with the commitments to verify sealed games
+func (g *GameHandlers) OpenReplay(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+
+	gameId, err := strconv.Atoi(c.Param("gameID"))
+	if err != nil {
+		return showNotification(c, "Invalid game ID.")
+	}
+
+	game, err := g.server.GameRepo.GetByID(ctx, uint(gameId))
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !game.HasPlayer(user.Id)) {
+		return showNotification(c, "Game Not Found.")
+	} else if err != nil {
+		logrus.Error("GameRepo.GetByID error ", err)
+		return errors.New("server error")
+	}
+	if game.Status != entity.Completed {
+		return showNotification(c, "The replay is available when the game is completed.")
+	}
+
+	competitor, err := g.server.UserRepo.GetByID(ctx, game.CompetitorID(user.Id))
+	if err != nil {
+		logrus.Error("cant find competitor", err)
+		return c.JSON(http.StatusInternalServerError, "cant find competitor")
+	}
+
+	data := schemas.ReplayData{Game: game, Competitor: competitor, Verified: game.Sealed == 1}
+	for idx, round := range game.GetRounds() {
+		p1Coins, p2Coins := game.RoundPayout(round)
+		replayRound := schemas.ReplayRound{
+			Number:             idx + 1,
+			YourDecision:       round.Decision(game, user.Id),
+			CompetitorDecision: round.CompetitorDecision(game, user.Id),
+			YourCoins:          p1Coins,
+			CompetitorCoins:    p2Coins,
+		}
+		if game.P2ID == user.Id {
+			replayRound.YourCoins, replayRound.CompetitorCoins = p2Coins, p1Coins
+		}
+		if game.Sealed == 1 {
+			replayRound.YourCommit = round.Commitment(game, user.Id)
+			replayRound.CompetitorCommit = round.CompetitorCommitment(game, user.Id)
+			replayRound.YourNonce, replayRound.CompetitorNonce = round.P1Nonce, round.P2Nonce
+			if game.P2ID == user.Id {
+				replayRound.YourNonce, replayRound.CompetitorNonce = round.P2Nonce, round.P1Nonce
+			}
+			replayRound.Verified = round.Verified()
+			data.Verified = data.Verified && replayRound.Verified
+		}
+		data.Rounds = append(data.Rounds, replayRound)
+	}
+
+	tmpl, err := template.New("replay").Parse(replayHTML)
+	if err != nil {
+		logrus.Error("Failed to render replay ", err)
+		return c.JSON(http.StatusInternalServerError, "Failed to render replay")
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		logrus.Error("Failed to render replay ", err)
+		return c.JSON(http.StatusInternalServerError, "Failed to render replay")
+	}
+
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
+
+// stealCount returns how many times the player stole in the game
+func stealCount(game entity.Game, userID int64) int {
+	steals := 0
+	for _, round := range game.GetRounds() {
+		if round.Decision(game, userID) == entity.Steal {
+			steals++
+		}
+	}
+	return steals
+}
@@ -0,0 +1,128 @@
+package webhandlers
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/entity"
+)
+
+//go:embed templates/leaderboard.html
+var leaderboardHTML string
+
+const LEADERBOARD_SIZE = 50
+
+type SeasonHandlers struct {
+	server *app.Server
+}
+
+func NewSeasonHandlers(server *app.Server) *SeasonHandlers {
+	return &SeasonHandlers{server: server}
+}
+
+// Serve the ranking of the selected season, the active season by default
+func (s *SeasonHandlers) OpenLeaderboard(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+
+	current, err := s.server.CurrentSeason(ctx)
+	if errors.Is(err, app.ErrNoSeason) {
+		return showNotification(c, "The first season has not started yet.")
+	} else if err != nil {
+		logrus.Error("current season error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render leaderboard (0)")
+	}
+
+	season := current
+	if raw := c.QueryParam("season"); raw != "" && raw != fmt.Sprint(current.Id) {
+		seasonID, err := strconv.Atoi(raw)
+		if err != nil {
+			return showNotification(c, "Invalid season.")
+		}
+		season, err = s.server.SeasonRepo.GetByID(ctx, uint(seasonID))
+		if err != nil {
+			return showNotification(c, "Season Not Found.")
+		}
+	}
+
+	seasons, err := s.server.SeasonRepo.Scan(ctx, "season:*", 1000)
+	if err != nil {
+		logrus.Error("scan seasons error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render leaderboard (1)")
+	}
+	slices.SortFunc(seasons, func(a, b entity.Season) int { return int(b.Id) - int(a.Id) })
+
+	data := schemas.LeaderboardData{
+		Season: season,
+		Live:   season.Status == entity.SeasonActive,
+	}
+	for _, item := range seasons {
+		data.Seasons = append(data.Seasons, schemas.SeasonOption{Id: item.Id, Name: item.Name, Selected: item.Id == season.Id})
+	}
+
+	if data.Live {
+		data.EndsIn = time.Until(time.Unix(season.End, 0)).Round(time.Minute).String()
+
+		scores, err := s.server.SeasonRepo.Top(ctx, season.Id, LEADERBOARD_SIZE)
+		if err != nil {
+			logrus.Error("season top error ", err)
+			return c.JSON(http.StatusInternalServerError, "failed to render leaderboard (2)")
+		}
+		playerIDs := make([]int64, len(scores))
+		for i, score := range scores {
+			playerIDs[i] = score.UserID
+		}
+		players, err := s.server.UserRepo.GetMany(ctx, playerIDs)
+		if err != nil {
+			logrus.Error("season players error ", err)
+			return c.JSON(http.StatusInternalServerError, "failed to render leaderboard (3)")
+		}
+		for i, score := range scores {
+			rank := entity.SeasonRank{Place: i + 1, UserID: score.UserID, Points: score.Points, DisplayName: players[i].DisplayName}
+			data.Ranking = append(data.Ranking, rank)
+		}
+
+		data.MyPlace, data.MyPoints, err = s.server.SeasonRepo.Score(ctx, season.Id, user.Id)
+		if err != nil {
+			logrus.Error("season score error ", err)
+		}
+	} else {
+		data.Ranking, err = s.server.SeasonRepo.Ranking(ctx, season.Id)
+		if err != nil {
+			logrus.Error("season ranking error ", err)
+			return c.JSON(http.StatusInternalServerError, "failed to render leaderboard (2)")
+		}
+		for _, rank := range data.Ranking {
+			if rank.UserID == user.Id {
+				data.MyPlace, data.MyPoints = rank.Place, rank.Points
+			}
+		}
+	}
+
+	tmpl, err := template.New("leaderboard").Parse(leaderboardHTML)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, "failed to render leaderboard (3)")
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		logrus.Error("render leaderboard page error: ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render leaderboard (4)")
+	}
+
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
@@ -0,0 +1,112 @@
+package webhandlers
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"text/template"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/payment"
+)
+
+//go:embed templates/shop.html
+var shopHTML string
+
+type ShopHandlers struct {
+	server *app.Server
+}
+
+func NewShopHandlers(server *app.Server) *ShopHandlers {
+	return &ShopHandlers{server: server}
+}
+
+// Serve the shop catalog and the user inventory
+func (s *ShopHandlers) OpenShop(c echo.Context) error {
+	return renderShopPage(c, s, app.GetUserFromCtx(c))
+}
+
+// Buy debits the item price and grants the item
+func (s *ShopHandlers) Buy(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+
+	user, err := s.server.BuyItem(context.Background(), user.Id, c.Param("itemID"))
+	if errors.Is(err, app.ErrUnknownItem) {
+		return showNotification(c, "This item is not for sale.")
+	} else if errors.Is(err, app.ErrItemOwned) {
+		return showNotification(c, "You already own this item.")
+	} else if errors.Is(err, app.ErrItemLimit) {
+		return showNotification(c, fmt.Sprintf("You can hold at most %d streak protections.", entity.MaxStreakProtection))
+	} else if errors.Is(err, app.ErrInsufficientBalance) {
+		return showNotification(c, "You don't have enough coins.")
+	} else if err != nil {
+		logrus.Error("buy item error ", err)
+		return c.JSON(http.StatusInternalServerError, "shop error (0)")
+	}
+
+	return renderShopPage(c, s, user)
+}
+
+// BuyStars sends the invoice of a Telegram Stars pack to the user chat
+func (s *ShopHandlers) BuyStars(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+
+	err := s.server.Payments.SendInvoice(user.Id, c.Param("packID"))
+	if errors.Is(err, payment.ErrUnknownPack) {
+		return showNotification(c, "This pack is not for sale.")
+	} else if err != nil {
+		logrus.Error("send invoice error ", err)
+		return showNotification(c, "Failed to create the invoice, please try again.")
+	}
+	return showNotification(c, "⭐ The invoice was sent to your Telegram chat.")
+}
+
+// Helper function to render the shop page
+func renderShopPage(c echo.Context, s *ShopHandlers, user entity.User) error {
+	owned, err := s.server.InventoryRepo.List(context.Background(), user.Id, entity.InventoryAvatar)
+	if err != nil {
+		logrus.Error("inventory list error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render shop (0)")
+	}
+	wallet, err := s.server.GameWallet(user)
+	if err != nil {
+		logrus.Error("game wallet error ", err)
+	}
+
+	data := schemas.ShopData{
+		User:          user,
+		Wallet:        wallet,
+		OwnedAvatars:  len(owned),
+		MaxProtection: entity.MaxStreakProtection,
+		StarPacks:     payment.Packs,
+	}
+	for _, item := range entity.ShopCatalog() {
+		data.Items = append(data.Items, schemas.ShopItemView{
+			Item:  item,
+			Owned: item.Kind == entity.ShopCosmetic && slices.Contains(owned, fmt.Sprint(item.AvatarID)),
+		})
+	}
+
+	tmpl, err := template.New("shop").Parse(shopHTML)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, "failed to render shop (1)")
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, data)
+	if err != nil {
+		logrus.Error("render shop page error: ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render shop (2)")
+	}
+
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
@@ -0,0 +1,187 @@
+package webhandlers
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/onionj/trust/app"
+	"github.com/onionj/trust/app/schemas"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/tournament"
+)
+
+//go:embed templates/tournaments.html
+var tournamentsHTML string
+
+//go:embed templates/tournament.html
+var tournamentHTML string
+
+var tournamentFuncs = template.FuncMap{
+	"time": func(unix int64) string {
+		return time.Unix(unix, 0).UTC().Format("2006-01-02 15:04")
+	},
+}
+
+type TournamentHandlers struct {
+	server *app.Server
+}
+
+func NewTournamentHandlers(server *app.Server) *TournamentHandlers {
+	return &TournamentHandlers{server: server}
+}
+
+// Serve the list of tournaments, newest first
+func (t *TournamentHandlers) OpenTournaments(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+	ctx := context.Background()
+
+	tournaments, err := t.server.TournamentRepo.Scan(ctx, "tournament:*", 1000)
+	if err != nil {
+		logrus.Error("scan tournaments error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render tournaments (0)")
+	}
+	slices.SortFunc(tournaments, func(a, b entity.Tournament) int { return int(b.Id) - int(a.Id) })
+
+	data := schemas.TournamentsData{}
+	for _, tournament := range tournaments {
+		players, err := t.server.TournamentRepo.Players(ctx, tournament.Id)
+		if err != nil {
+			logrus.Error("tournament players error ", err)
+		}
+		data.Tournaments = append(data.Tournaments, schemas.TournamentItem{
+			Tournament: tournament,
+			Players:    len(players),
+			Registered: slices.Contains(players, user.Id),
+		})
+	}
+
+	return renderTournamentPage(c, "tournaments", tournamentsHTML, data)
+}
+
+// Serve the bracket and standings of a tournament
+func (t *TournamentHandlers) OpenTournament(c echo.Context) error {
+	tournamentID, err := strconv.Atoi(c.Param("tournamentID"))
+	if err != nil {
+		return showNotification(c, "Invalid tournament ID.")
+	}
+	return renderTournament(c, t.server, app.GetUserFromCtx(c), uint(tournamentID))
+}
+
+// Register pays the entry fee and registers the user
+func (t *TournamentHandlers) Register(c echo.Context) error {
+	user := app.GetUserFromCtx(c)
+
+	tournamentID, err := strconv.Atoi(c.Param("tournamentID"))
+	if err != nil {
+		return showNotification(c, "Invalid tournament ID.")
+	}
+
+	_, err = t.server.RegisterTournament(context.Background(), uint(tournamentID), user.Id)
+	if errors.Is(err, app.ErrInsufficientBalance) {
+		return showNotification(c, "You don't have enough coins for the entry fee.")
+	} else if errors.Is(err, app.ErrRegistrationClosed) || errors.Is(err, app.ErrAlreadyRegistered) {
+		return showNotification(c, fmt.Sprint("Can't register: ", err))
+	} else if err != nil {
+		logrus.Error("tournament register error ", err)
+		return showNotification(c, "Registration failed, please try again.")
+	}
+
+	return renderTournament(c, t.server, user, uint(tournamentID))
+}
+
+func renderTournament(c echo.Context, server *app.Server, user entity.User, tournamentID uint) error {
+	ctx := context.Background()
+
+	t, err := server.TournamentRepo.GetByID(ctx, tournamentID)
+	if err != nil {
+		return showNotification(c, "Tournament Not Found.")
+	}
+	players, err := server.TournamentRepo.Players(ctx, t.Id)
+	if err != nil {
+		logrus.Error("tournament players error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render tournament (0)")
+	}
+	matches, err := server.TournamentRepo.Matches(ctx, t.Id)
+	if err != nil {
+		logrus.Error("tournament matches error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render tournament (1)")
+	}
+	standings, err := server.TournamentRepo.Standings(ctx, t.Id)
+	if err != nil {
+		logrus.Error("tournament standings error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render tournament (2)")
+	}
+
+	playerUsers, err := server.UserRepo.GetMany(ctx, players)
+	if err != nil {
+		logrus.Error("tournament players error ", err)
+		return c.JSON(http.StatusInternalServerError, "failed to render tournament (3)")
+	}
+	names := map[int64]string{tournament.Bye: "bye"}
+	for _, player := range playerUsers {
+		if player.Id != 0 {
+			names[player.Id] = player.DisplayName
+		}
+	}
+
+	data := schemas.TournamentData{
+		Tournament: t,
+		Players:    len(players),
+		Registered: slices.Contains(players, user.Id),
+	}
+	data.CanRegister = !data.Registered && t.Status == entity.TournamentRegistration
+
+	for _, match := range matches {
+		if len(data.Rounds) < match.Round {
+			data.Rounds = append(data.Rounds, schemas.TournamentRoundView{Number: match.Round})
+		}
+		round := &data.Rounds[match.Round-1]
+		round.Matches = append(round.Matches, schemas.TournamentMatchView{
+			Match:  match,
+			P1Name: names[match.P1ID],
+			P2Name: names[match.P2ID],
+		})
+		if match.Round == t.Round && !match.Decided() && (match.P1ID == user.Id || match.P2ID == user.Id) {
+			data.HasMatch = true
+		}
+	}
+	slices.Reverse(data.Rounds)
+
+	for place, standing := range tournament.Rank(standings) {
+		data.Standings = append(data.Standings, schemas.TournamentStandingView{
+			Place: place + 1,
+			Name:  names[standing.UserID],
+			Wins:  standing.Wins,
+			Coins: standing.Coins,
+		})
+	}
+
+	return renderTournamentPage(c, "tournament", tournamentHTML, data)
+}
+
+func renderTournamentPage(c echo.Context, name string, page string, data any) error {
+	tmpl, err := template.New(name).Funcs(tournamentFuncs).Parse(page)
+	if err != nil {
+		logrus.Error("Failed to render ", name, " ", err)
+		return c.JSON(http.StatusInternalServerError, "Failed to render tournament")
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		logrus.Error("Failed to render ", name, " ", err)
+		return c.JSON(http.StatusInternalServerError, "Failed to render tournament")
+	}
+
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type adminConfig struct {
+	IDs []int64 // telegram ids allowed to use admin features
+}
+
+func LoadAdminConfig() adminConfig {
+	ids := []int64{}
+	for _, raw := range strings.Split(os.Getenv("ADMIN_IDS"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			log.Println("Invalid id in ADMIN_IDS", raw)
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	return adminConfig{
+		IDs: ids,
+	}
+}
+
+func (a adminConfig) IsAdmin(userID int64) bool {
+	return slices.Contains(a.IDs, userID)
+}
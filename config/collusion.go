@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+type collusionConfig struct {
+	QuickJoin   time.Duration // lobby joins closer than this count as correlated
+	BlockScore  float64       // pairs at or above this risk score are not matched again, 0 disables
+	BlockWindow time.Duration // time since the last match of a blocked pair before it can match again
+}
+
+func LoadCollusionConfig() collusionConfig {
+	blockScore, err := strconv.ParseFloat(os.Getenv("COLLUSION_BLOCK_SCORE"), 64)
+	if err != nil {
+		blockScore = 0
+	}
+
+	return collusionConfig{
+		QuickJoin:   time.Duration(envInt("COLLUSION_QUICK_JOIN_MS", 1500)) * time.Millisecond,
+		BlockScore:  blockScore,
+		BlockWindow: time.Duration(envInt("COLLUSION_BLOCK_WINDOW_MINUTES", 24*60)) * time.Minute,
+	}
+}
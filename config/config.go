@@ -4,12 +4,23 @@ import (
 	"log"
 
 	"github.com/joho/godotenv"
+
+	"github.com/onionj/trust/internal/mode"
 )
 
 type ConfigT struct {
-	HTTP     httpConfig
-	Redis    redisConfig
-	Telegram telegramConfig
+	HTTP        httpConfig
+	Redis       redisConfig
+	Telegram    telegramConfig
+	Reward      rewardConfig
+	Admin       adminConfig
+	Collusion   collusionConfig
+	Matchmaking matchmakingConfig
+	Modes       *mode.Registry
+	Group       groupConfig
+	Tournament  tournamentConfig
+	Season      seasonConfig
+	RateLimit   rateLimitConfig
 }
 
 var GlobalConfig ConfigT
@@ -23,9 +34,18 @@ func NewConfig(envFile string) ConfigT {
 		log.Println("Error loading .env file", err)
 	}
 	GlobalConfig = ConfigT{
-		HTTP:     LoadHTTPConfig(),
-		Redis:    LoadRedisConfig(),
-		Telegram: LoadTelegramConfig(),
+		HTTP:        LoadHTTPConfig(),
+		Redis:       LoadRedisConfig(),
+		Telegram:    LoadTelegramConfig(),
+		Reward:      LoadRewardConfig(),
+		Admin:       LoadAdminConfig(),
+		Collusion:   LoadCollusionConfig(),
+		Matchmaking: LoadMatchmakingConfig(),
+		Modes:       LoadModesConfig(),
+		Group:       LoadGroupConfig(),
+		Tournament:  LoadTournamentConfig(),
+		Season:      LoadSeasonConfig(),
+		RateLimit:   LoadRateLimitConfig(),
 	}
 
 	return GlobalConfig
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+type groupConfig struct {
+	MinPlayers     int           // a group game starts with at least this many players
+	MaxPlayers     int           // and at most this many
+	FillWait       time.Duration // how long the lobby waits for more players once MinPlayers are waiting
+	Rounds         int           // rounds of a group game
+	CoinsPerPlayer int           // the pot is CoinsPerPlayer * players
+	CoopReward     float64       // server reward of an all share round as a ratio of the pot
+	RoundTime      time.Duration // time to decide a round, the idle players forfeit the rest of the game
+}
+
+func LoadGroupConfig() groupConfig {
+	coopReward, err := strconv.ParseFloat(os.Getenv("GROUP_COOP_REWARD"), 64)
+	if err != nil || coopReward < 0 {
+		coopReward = 0.025
+	}
+
+	minPlayers := min(max(envInt("GROUP_MIN_PLAYERS", 3), 3), 6)
+	return groupConfig{
+		MinPlayers:     minPlayers,
+		MaxPlayers:     min(max(envInt("GROUP_MAX_PLAYERS", 6), minPlayers), 6),
+		FillWait:       time.Duration(envInt("GROUP_FILL_SECONDS", 10)) * time.Second,
+		Rounds:         min(max(envInt("GROUP_ROUNDS", 4), 1), 4),
+		CoinsPerPlayer: max(envInt("GROUP_COINS_PER_PLAYER", 100), 1),
+		CoopReward:     coopReward,
+		RoundTime:      time.Duration(max(envInt("GROUP_ROUND_SECONDS", 60), 10)) * time.Second,
+	}
+}
@@ -1,17 +1,38 @@
 package config
 
-import "os"
+import (
+	"log"
+	"net"
+	"os"
+	"strings"
+)
 
 type httpConfig struct {
-	Host          string
-	Port          string
-	ExposeAddress string
+	Host           string
+	Port           string
+	ExposeAddress  string
+	TrustedProxies []*net.IPNet // proxies allowed to set X-Forwarded-For, the connection address is used when empty
 }
 
 func LoadHTTPConfig() httpConfig {
+	proxies := []*net.IPNet{}
+	for _, raw := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		_, ipRange, err := net.ParseCIDR(raw)
+		if err != nil {
+			log.Println("Invalid range in TRUSTED_PROXIES", raw)
+			continue
+		}
+		proxies = append(proxies, ipRange)
+	}
+
 	return httpConfig{
-		Host:          os.Getenv("HOST"),
-		Port:          os.Getenv("PORT"),
-		ExposeAddress: os.Getenv("EXPOSE_ADDRESS"),
+		Host:           os.Getenv("HOST"),
+		Port:           os.Getenv("PORT"),
+		ExposeAddress:  os.Getenv("EXPOSE_ADDRESS"),
+		TrustedProxies: proxies,
 	}
 }
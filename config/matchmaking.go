@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+type matchmakingConfig struct {
+	RepeatLimit  int           // max matches of the same two players per window, 0 disables
+	RepeatWindow time.Duration // window the repeat limit is counted in
+}
+
+func LoadMatchmakingConfig() matchmakingConfig {
+	return matchmakingConfig{
+		RepeatLimit:  envInt("MATCH_REPEAT_LIMIT", 3),
+		RepeatWindow: time.Duration(envInt("MATCH_REPEAT_WINDOW_MINUTES", 10)) * time.Minute,
+	}
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"log"
+	"os"
+
+	"github.com/onionj/trust/internal/mode"
+)
+
+// LoadModesConfig loads the game modes from GAME_MODES_FILE, the built-in modes when unset
+func LoadModesConfig() *mode.Registry {
+	registry, err := mode.Load(os.Getenv("GAME_MODES_FILE"))
+	if err != nil {
+		log.Fatalln("Error loading game modes", err)
+	}
+	return registry
+}
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+type rateLimitConfig struct {
+	IPBurst      int           // requests a client IP can send at once
+	IPInterval   time.Duration // time to earn back a request of an IP
+	UserBurst    int           // requests an authorized user can send at once
+	UserInterval time.Duration // time to earn back a request of a user
+}
+
+func LoadRateLimitConfig() rateLimitConfig {
+	return rateLimitConfig{
+		IPBurst:      envInt("RATE_LIMIT_IP_BURST", 120),
+		IPInterval:   time.Duration(envInt("RATE_LIMIT_IP_INTERVAL_MS", 50)) * time.Millisecond,
+		UserBurst:    envInt("RATE_LIMIT_USER_BURST", 30),
+		UserInterval: time.Duration(envInt("RATE_LIMIT_USER_INTERVAL_MS", 200)) * time.Millisecond,
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+type rewardConfig struct {
+	DailyBase          int            // coins of the first day of a streak
+	DailyMaxMultiplier int            // streak day where the reward stops growing
+	TimeZone           *time.Location // days start at midnight in this location
+	DailyBonusTokens   int            // bonus game tokens of every daily claim
+
+	ReferralBonus      int // coins paid to both referrer and referee
+	ReferralMinGames   int // games the referee must complete before the bonus is paid
+	ReferralMaxPerUser int // referrals a single user can be credited for
+}
+
+func LoadRewardConfig() rewardConfig {
+	location, err := time.LoadLocation(os.Getenv("TIMEZONE"))
+	if err != nil {
+		log.Println("Invalid TIMEZONE, using UTC", err)
+		location = time.UTC
+	}
+
+	return rewardConfig{
+		DailyBase:          envInt("DAILY_REWARD", 100),
+		DailyMaxMultiplier: envInt("DAILY_REWARD_MAX_MULTIPLIER", 7),
+		TimeZone:           location,
+		DailyBonusTokens:   envInt("DAILY_BONUS_TOKENS", 1),
+
+		ReferralBonus:      envInt("REFERRAL_BONUS", 1000),
+		ReferralMinGames:   envInt("REFERRAL_MIN_GAMES", 5),
+		ReferralMaxPerUser: envInt("REFERRAL_MAX_PER_USER", 50),
+	}
+}
+
+// envInt reads an integer environment variable, falling back to def when unset or invalid
+func envInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
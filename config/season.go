@@ -0,0 +1,44 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type seasonConfig struct {
+	Length      time.Duration
+	Rewards     []int         // coins paid by final place, first place first
+	ArchiveSize int           // players kept in the archived ranking
+	Tick        time.Duration // how often the scheduler checks for the end of the season
+}
+
+func LoadSeasonConfig() seasonConfig {
+	return seasonConfig{
+		Length:      time.Duration(envInt("SEASON_DAYS", 30)) * 24 * time.Hour,
+		Rewards:     envInts("SEASON_REWARDS", []int{5000, 3000, 2000, 1000, 1000}),
+		ArchiveSize: envInt("SEASON_ARCHIVE_SIZE", 100),
+		Tick:        time.Duration(envInt("SEASON_TICK_SECONDS", 60)) * time.Second,
+	}
+}
+
+// envInts reads a comma separated list of integers, falling back to def when unset or invalid
+func envInts(key string, def []int) []int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+
+	values := []int{}
+	for _, item := range strings.Split(raw, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || value < 0 {
+			log.Println("Invalid", key, "using default", err)
+			return def
+		}
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+type tournamentConfig struct {
+	RoundTime time.Duration // unfinished games of a round are forfeited after this time
+	Tick      time.Duration // how often the scheduler starts and advances tournaments
+}
+
+func LoadTournamentConfig() tournamentConfig {
+	return tournamentConfig{
+		RoundTime: time.Duration(envInt("TOURNAMENT_ROUND_MINUTES", 10)) * time.Minute,
+		Tick:      time.Duration(envInt("TOURNAMENT_TICK_SECONDS", 15)) * time.Second,
+	}
+}
@@ -0,0 +1,126 @@
+package achievement
+
+import (
+	"slices"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+// Definition declares an achievement and the condition that unlocks it
+type Definition struct {
+	ID          string
+	Name        string
+	Icon        string
+	Description string
+	Avatar      int // avatar unlocked together with the achievement, 0 if none
+	BonusTokens int // bonus game tokens granted with the achievement
+	Reached     func(p entity.AchievementProgress) bool
+}
+
+var Definitions = []Definition{
+	{
+		ID:          "first_betrayal",
+		Name:        "First Betrayal",
+		Icon:        "🗡",
+		Description: "Steal while your competitor shares",
+		Reached:     func(p entity.AchievementProgress) bool { return p.Betrayals >= 1 },
+	},
+	{
+		ID:          "saint",
+		Name:        "Saint",
+		Icon:        "😇",
+		Description: "Share in 50 consecutive rounds",
+		Avatar:      10,
+		Reached:     func(p entity.AchievementProgress) bool { return p.ShareStreak >= 50 },
+	},
+	{
+		ID:          "mutual_trust_10",
+		Name:        "Mutual Trust x10",
+		Icon:        "🤝",
+		Description: "Both players share in 10 rounds",
+		BonusTokens: 5,
+		Reached:     func(p entity.AchievementProgress) bool { return p.MutualShares >= 10 },
+	},
+	{
+		ID:          "comeback",
+		Name:        "Comeback",
+		Icon:        "🔥",
+		Description: "Win a game after losing the first round",
+		BonusTokens: 3,
+		Reached:     func(p entity.AchievementProgress) bool { return p.Comebacks >= 1 },
+	},
+	{
+		ID:          "played_100",
+		Name:        "Veteran",
+		Icon:        "🎖",
+		Description: "Play 100 games",
+		BonusTokens: 10,
+		Avatar:      11,
+		Reached:     func(p entity.AchievementProgress) bool { return p.GamesPlayed >= 100 },
+	},
+}
+
+func Find(id string) (Definition, bool) {
+	for _, definition := range Definitions {
+		if definition.ID == id {
+			return definition, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Track updates the progress of the player with a completed game
+func Track(p entity.AchievementProgress, game entity.Game, userID int64) entity.AchievementProgress {
+	side := game.PlayerSide(userID)
+	lostFirstRound := false
+	lead := 0 // rounds won alone minus rounds the competitor won alone
+
+	for idx, round := range game.GetRounds() {
+		decision := round.Decision(game, userID)
+		competitorDecision := round.CompetitorDecision(game, userID)
+
+		// forfeited rounds have no decision and don't break the streak
+		if decision == entity.Share {
+			p.ShareStreak++
+		} else if decision == entity.Steal {
+			p.ShareStreak = 0
+		}
+		if decision == entity.Share && competitorDecision == entity.Share {
+			p.MutualShares++
+		}
+		if decision == entity.Steal && competitorDecision == entity.Share {
+			p.Betrayals++
+		}
+
+		switch round.Winner {
+		case side:
+			lead++
+		case entity.P1, entity.P2:
+			if idx == 0 {
+				lostFirstRound = true
+			}
+			lead--
+		}
+	}
+
+	p.GamesPlayed++
+	if lostFirstRound && lead > 0 {
+		p.Comebacks++
+	}
+	return p
+}
+
+// Evaluate tracks the game and returns the achievements that are newly reached.
+// unlocked holds the ids the player already owns.
+func Evaluate(p entity.AchievementProgress, unlocked []string, game entity.Game, userID int64) (entity.AchievementProgress, []Definition) {
+	p = Track(p, game, userID)
+
+	reached := []Definition{}
+	for _, definition := range Definitions {
+		if !definition.Reached(p) || slices.Contains(unlocked, definition.ID) {
+			continue
+		}
+		reached = append(reached, definition)
+	}
+	return p, reached
+}
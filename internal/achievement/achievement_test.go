@@ -0,0 +1,127 @@
+package achievement
+
+import (
+	"testing"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+// completedGame builds a finished game between user 10 (p1) and 11 (p2)
+func completedGame(p1Decisions [4]string, p2Decisions [4]string) entity.Game {
+	game := entity.NewGame(1, 10, 11)
+	winners := [4]string{}
+	for idx := range 4 {
+		switch {
+		case p1Decisions[idx] == entity.Share && p2Decisions[idx] == entity.Share:
+			winners[idx] = entity.P1P2
+		case p1Decisions[idx] == entity.Steal && p2Decisions[idx] == entity.Steal:
+			winners[idx] = entity.Server
+		case p1Decisions[idx] == entity.Steal:
+			winners[idx] = entity.P1
+		default:
+			winners[idx] = entity.P2
+		}
+	}
+
+	game.Status = entity.Completed
+	game.R1P1Decision, game.R1P2Decision, game.R1Winner = p1Decisions[0], p2Decisions[0], winners[0]
+	game.R2P1Decision, game.R2P2Decision, game.R2Winner = p1Decisions[1], p2Decisions[1], winners[1]
+	game.R3P1Decision, game.R3P2Decision, game.R3Winner = p1Decisions[2], p2Decisions[2], winners[2]
+	game.R4P1Decision, game.R4P2Decision, game.R4Winner = p1Decisions[3], p2Decisions[3], winners[3]
+	return game
+}
+
+func ids(definitions []Definition) []string {
+	result := []string{}
+	for _, definition := range definitions {
+		result = append(result, definition.ID)
+	}
+	return result
+}
+
+func TestFirstBetrayal(t *testing.T) {
+	sh, st := entity.Share, entity.Steal
+	game := completedGame([4]string{sh, st, sh, sh}, [4]string{sh, sh, sh, sh})
+
+	progress, reached := Evaluate(entity.AchievementProgress{}, nil, game, 10)
+	assert.Equal(t, 1, progress.Betrayals)
+	assert.Equal(t, 1, progress.GamesPlayed)
+	assert.Equal(t, []string{"first_betrayal"}, ids(reached))
+
+	// p2 was betrayed, not the betrayer
+	progress, reached = Evaluate(entity.AchievementProgress{}, nil, game, 11)
+	assert.Equal(t, 0, progress.Betrayals)
+	assert.Empty(t, reached)
+
+	// already unlocked achievements are not reported again
+	_, reached = Evaluate(progress, []string{"first_betrayal"}, game, 10)
+	assert.Empty(t, reached)
+}
+
+func TestSaintAndMutualTrust(t *testing.T) {
+	sh, st := entity.Share, entity.Steal
+	allShare := completedGame([4]string{sh, sh, sh, sh}, [4]string{sh, sh, sh, sh})
+
+	progress := entity.AchievementProgress{}
+	unlocked := []string{}
+	for game := 1; game <= 12; game++ {
+		var reached []Definition
+		progress, reached = Evaluate(progress, unlocked, allShare, 11)
+		unlocked = append(unlocked, ids(reached)...)
+
+		if game < 3 {
+			assert.NotContains(t, unlocked, "mutual_trust_10")
+		}
+		if game == 3 {
+			assert.Contains(t, unlocked, "mutual_trust_10")
+		}
+	}
+	assert.Equal(t, 48, progress.ShareStreak)
+	assert.NotContains(t, unlocked, "saint")
+
+	// a steal resets the streak
+	oneSteal := completedGame([4]string{sh, sh, sh, sh}, [4]string{sh, sh, st, sh})
+	progress, _ = Evaluate(progress, unlocked, oneSteal, 11)
+	assert.Equal(t, 1, progress.ShareStreak)
+
+	for range 13 {
+		var reached []Definition
+		progress, reached = Evaluate(progress, unlocked, allShare, 11)
+		unlocked = append(unlocked, ids(reached)...)
+	}
+	assert.Equal(t, 53, progress.ShareStreak)
+	assert.Contains(t, unlocked, "saint")
+}
+
+func TestComeback(t *testing.T) {
+	sh, st := entity.Share, entity.Steal
+
+	// p1 loses round 1, then wins rounds 2 and 3
+	game := completedGame([4]string{sh, st, st, sh}, [4]string{st, sh, sh, sh})
+	progress, reached := Evaluate(entity.AchievementProgress{}, nil, game, 10)
+	assert.Equal(t, 1, progress.Comebacks)
+	assert.Contains(t, ids(reached), "comeback")
+
+	// a draw is not a comeback
+	game = completedGame([4]string{sh, st, sh, sh}, [4]string{st, sh, sh, sh})
+	progress, _ = Evaluate(entity.AchievementProgress{}, nil, game, 10)
+	assert.Equal(t, 0, progress.Comebacks)
+}
+
+func TestPlayed100(t *testing.T) {
+	st := entity.Steal
+	game := completedGame([4]string{st, st, st, st}, [4]string{st, st, st, st})
+
+	progress := entity.AchievementProgress{GamesPlayed: 98}
+	progress, reached := Evaluate(progress, nil, game, 10)
+	assert.Empty(t, reached)
+
+	progress, reached = Evaluate(progress, nil, game, 10)
+	assert.Equal(t, 100, progress.GamesPlayed)
+	assert.Equal(t, []string{"played_100"}, ids(reached))
+
+	definition, ok := Find("played_100")
+	assert.True(t, ok)
+	assert.Equal(t, 11, definition.Avatar)
+}
@@ -0,0 +1,48 @@
+package collusion
+
+import (
+	"fmt"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+const (
+	repeatMinGames    = 5   // matches before repeated matching counts
+	repeatFullGames   = 20  // matches for the full repeat weight
+	shareMinRounds    = 12  // rounds before the mutual share rate counts
+	shareMinRate      = 0.9 // mutual share rate that looks like farming
+	quickJoinMinGames = 3   // matches before the quick join rate counts
+	quickJoinMinRate  = 0.5 // share of matches with correlated lobby joins
+	repeatWeight      = 0.4
+	mutualShareWeight = 0.4
+	quickJoinWeight   = 0.3
+)
+
+// Score rates how likely a pair colludes, between 0 and 1, with the reasons behind it
+func Score(stats entity.PairStats) (float64, []string) {
+	score := 0.0
+	reasons := []string{}
+
+	if stats.Games >= repeatMinGames {
+		score += repeatWeight * min(float64(stats.Games)/repeatFullGames, 1)
+		reasons = append(reasons, fmt.Sprintf("matched %d times", stats.Games))
+	}
+
+	if stats.Rounds >= shareMinRounds {
+		rate := float64(stats.MutualShares) / float64(stats.Rounds)
+		if rate >= shareMinRate {
+			score += mutualShareWeight
+			reasons = append(reasons, fmt.Sprintf("mutual share in %.0f%% of rounds", rate*100))
+		}
+	}
+
+	if stats.Games >= quickJoinMinGames {
+		rate := float64(stats.QuickJoins) / float64(stats.Games)
+		if rate >= quickJoinMinRate {
+			score += quickJoinWeight
+			reasons = append(reasons, fmt.Sprintf("joined the lobby together in %d of %d matches", stats.QuickJoins, stats.Games))
+		}
+	}
+
+	return min(score, 1), reasons
+}
@@ -0,0 +1,32 @@
+package collusion
+
+import (
+	"testing"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	stats := entity.NewPairStats(11, 10)
+	assert.Equal(t, int64(10), stats.P1ID)
+	assert.Equal(t, int64(11), stats.P2ID)
+
+	// a few honest games are not risky
+	stats.Games, stats.Rounds, stats.MutualShares, stats.QuickJoins = 2, 8, 8, 0
+	score, reasons := Score(stats)
+	assert.Equal(t, 0.0, score)
+	assert.Empty(t, reasons)
+
+	// repeated matching alone is a weak signal
+	stats.Games, stats.Rounds, stats.MutualShares = 10, 40, 10
+	score, reasons = Score(stats)
+	assert.InDelta(t, 0.2, score, 0.001)
+	assert.Len(t, reasons, 1)
+
+	// always sharing and joining together is a strong one
+	stats.Games, stats.Rounds, stats.MutualShares, stats.QuickJoins = 20, 80, 78, 15
+	score, reasons = Score(stats)
+	assert.Equal(t, 1.0, score)
+	assert.Len(t, reasons, 3)
+}
@@ -0,0 +1,37 @@
+// Package commitment implements the hash commitments of the commit–reveal games.
+// A player commits to a choice by sending sha256("<choice>:<nonce>") as hex and
+// reveals it later with the choice and the nonce, so nobody can see the choice
+// before both players committed and nobody can change it afterwards.
+package commitment
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+)
+
+var (
+	hashPattern  = regexp.MustCompile(`^[0-9a-f]{64}$`)
+	noncePattern = regexp.MustCompile(`^[0-9a-f]{32,64}$`)
+)
+
+// Hash returns the commitment of the choice with the nonce
+func Hash(choice string, nonce string) string {
+	sum := sha256.Sum256([]byte(choice + ":" + nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify reports whether the choice and nonce open the commitment
+func Verify(commitment string, choice string, nonce string) bool {
+	return ValidNonce(nonce) && Hash(choice, nonce) == commitment
+}
+
+// ValidHash reports whether the commitment is a lowercase hex sha256
+func ValidHash(commitment string) bool {
+	return hashPattern.MatchString(commitment)
+}
+
+// ValidNonce reports whether the nonce is 16 to 32 random bytes as lowercase hex
+func ValidNonce(nonce string) bool {
+	return noncePattern.MatchString(nonce)
+}
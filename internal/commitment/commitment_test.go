@@ -0,0 +1,24 @@
+package commitment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitment(t *testing.T) {
+	nonce := "00112233445566778899aabbccddeeff"
+	hash := Hash("share", nonce)
+
+	assert.True(t, ValidHash(hash))
+	assert.True(t, Verify(hash, "share", nonce))
+	assert.False(t, Verify(hash, "steal", nonce))
+	assert.False(t, Verify(hash, "share", "00112233445566778899aabbccddeefe"))
+
+	// echo -n "share:00112233445566778899aabbccddeeff" | sha256sum
+	assert.Equal(t, "2a40d62222d646e63b157e11e6dee81ad27f0fe21d9b45f6274c2d8ecdaf8ddf", hash)
+
+	assert.False(t, ValidNonce("short"))
+	assert.False(t, ValidNonce("00112233445566778899AABBCCDDEEFF"))
+	assert.False(t, ValidHash("not a hash"))
+}
@@ -0,0 +1,64 @@
+package daily
+
+import (
+	"errors"
+	"time"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+const dayLayout = "2006-01-02"
+
+var ErrAlreadyClaimed = errors.New("daily reward already claimed")
+
+// Claim is the outcome of claiming the daily reward
+type Claim struct {
+	Day            string // claimed day 'YYYY-MM-DD'
+	Streak         int    // streak including the claimed day
+	Reward         int    // coins to credit
+	UsedProtection bool   // a streak protection was spent to cover a missed day
+}
+
+// Today returns the current day in the given location
+func Today(now time.Time, location *time.Location) string {
+	return now.In(location).Format(dayLayout)
+}
+
+// NextClaim computes the reward of the user for the day of now in the given location.
+// The reward is base multiplied by the streak, capped at maxMultiplier.
+// Missing exactly one day keeps the streak if the user has a streak protection.
+func NextClaim(user entity.User, now time.Time, location *time.Location, base int, maxMultiplier int) (Claim, error) {
+	today := Today(now, location)
+	if user.DailyLastClaim == today {
+		return Claim{}, ErrAlreadyClaimed
+	}
+
+	claim := Claim{Day: today, Streak: 1}
+	if lastClaim, err := time.ParseInLocation(dayLayout, user.DailyLastClaim, location); err == nil {
+		todayStart, _ := time.ParseInLocation(dayLayout, today, location)
+		missedDays := int(todayStart.Sub(lastClaim).Round(time.Hour).Hours()/24) - 1
+
+		switch {
+		case missedDays < 0:
+			return Claim{}, ErrAlreadyClaimed
+		case missedDays == 0:
+			claim.Streak = user.DailyStreak + 1
+		case missedDays == 1 && user.StreakProtection > 0:
+			claim.Streak = user.DailyStreak + 1
+			claim.UsedProtection = true
+		}
+	}
+
+	multiplier := min(claim.Streak, maxMultiplier)
+	claim.Reward = base * max(multiplier, 1)
+	return claim, nil
+}
+
+// Apply stores the claim on the user
+func (claim Claim) Apply(user *entity.User) {
+	user.DailyStreak = claim.Streak
+	user.DailyLastClaim = claim.Day
+	if claim.UsedProtection {
+		user.StreakProtection--
+	}
+}
@@ -0,0 +1,69 @@
+package daily
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextClaim(t *testing.T) {
+	tehran, err := time.LoadLocation("Asia/Tehran")
+	assert.NoError(t, err)
+
+	user := entity.NewUser(10, "Onion", 0)
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	claim, err := NextClaim(user, now, time.UTC, 100, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, Claim{Day: "2024-10-01", Streak: 1, Reward: 100}, claim)
+	claim.Apply(&user)
+
+	_, err = NextClaim(user, now.Add(11*time.Hour), time.UTC, 100, 7)
+	assert.ErrorIs(t, err, ErrAlreadyClaimed)
+
+	// next day continues the streak
+	claim, err = NextClaim(user, now.Add(24*time.Hour), time.UTC, 100, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, claim.Streak)
+	assert.Equal(t, 200, claim.Reward)
+
+	// the reward stops growing at max multiplier
+	user.DailyStreak = 9
+	claim, err = NextClaim(user, now.Add(24*time.Hour), time.UTC, 100, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, claim.Streak)
+	assert.Equal(t, 700, claim.Reward)
+
+	// a missed day resets the streak
+	user.DailyStreak = 3
+	claim, err = NextClaim(user, now.Add(48*time.Hour), time.UTC, 100, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claim.Streak)
+
+	// unless the user has a streak protection
+	user.StreakProtection = 1
+	claim, err = NextClaim(user, now.Add(48*time.Hour), time.UTC, 100, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, claim.Streak)
+	assert.True(t, claim.UsedProtection)
+	claim.Apply(&user)
+	assert.Equal(t, 0, user.StreakProtection)
+
+	// two missed days are not covered
+	user.StreakProtection = 1
+	claim, err = NextClaim(user, now.Add(5*24*time.Hour), time.UTC, 100, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, claim.Streak)
+	assert.False(t, claim.UsedProtection)
+
+	// days follow the configured time zone: 21:00 UTC is already the next day in Tehran
+	user = entity.NewUser(11, "Sarah", 0)
+	claim, _ = NextClaim(user, time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC), tehran, 100, 7)
+	claim.Apply(&user)
+	claim, err = NextClaim(user, time.Date(2024, 10, 1, 21, 0, 0, 0, time.UTC), tehran, 100, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-10-02", claim.Day)
+	assert.Equal(t, 2, claim.Streak)
+}
@@ -0,0 +1,10 @@
+package entity
+
+// AchievementProgress holds the per user counters achievements are evaluated against
+type AchievementProgress struct {
+	GamesPlayed  int `json:"games_played" redis:"games_played"`   // completed games
+	ShareStreak  int `json:"share_streak" redis:"share_streak"`   // consecutive rounds shared
+	MutualShares int `json:"mutual_shares" redis:"mutual_shares"` // rounds both players shared
+	Betrayals    int `json:"betrayals" redis:"betrayals"`         // rounds stolen while the competitor shared
+	Comebacks    int `json:"comebacks" redis:"comebacks"`         // games won after losing the first round
+}
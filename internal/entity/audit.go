@@ -0,0 +1,38 @@
+package entity
+
+import "time"
+
+const (
+	AuditBalance      string = "balance"
+	AuditHourLimit    string = "hour_limit"
+	AuditBan          string = "ban"
+	AuditUnban        string = "unban"
+	AuditCompleteGame string = "complete_game"
+	AuditStats        string = "stats"
+	AuditViewUser     string = "view_user"
+	AuditLobby        string = "lobby"
+	AuditBroadcast    string = "broadcast"
+	AuditTournament   string = "tournament"
+	AuditRefund       string = "refund"
+)
+
+// AuditEntry records an action taken by an admin
+type AuditEntry struct {
+	Created  int64  `json:"created"`
+	AdminID  int64  `json:"admin_id"`
+	Action   string `json:"action"`
+	TargetID int64  `json:"target_id"` // user or game the action applies to
+	Reason   string `json:"reason"`
+	Details  string `json:"details"`
+}
+
+func NewAuditEntry(adminID int64, action string, targetID int64, reason string, details string) AuditEntry {
+	return AuditEntry{
+		Created:  time.Now().Unix(),
+		AdminID:  adminID,
+		Action:   action,
+		TargetID: targetID,
+		Reason:   reason,
+		Details:  details,
+	}
+}
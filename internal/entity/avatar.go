@@ -0,0 +1,59 @@
+package entity
+
+import "fmt"
+
+const InventoryAvatar string = "avatar"
+
+// Avatar describes a profile picture and how a user can own it
+type Avatar struct {
+	ID          int    `json:"id"`
+	Price       int    `json:"price"`       // coins needed to buy the avatar, 0 if not for sale
+	Achievement string `json:"achievement"` // achievement that unlocks the avatar, '' if none
+}
+
+// Free avatars are owned by every user
+func (a Avatar) Free() bool {
+	return a.Price == 0 && a.Achievement == ""
+}
+
+func (a Avatar) ItemID() string {
+	return fmt.Sprint(a.ID)
+}
+
+// ShopItemID is the id of the shop item that sells the avatar
+func (a Avatar) ShopItemID() string {
+	return fmt.Sprintf("avatar_%d", a.ID)
+}
+
+var Avatars = []Avatar{
+	{ID: 1},
+	{ID: 2},
+	{ID: 3},
+	{ID: 4},
+	{ID: 5},
+	{ID: 6},
+	{ID: 7, Price: 2_000},
+	{ID: 8, Price: 5_000},
+	{ID: 9, Price: 10_000},
+	{ID: 10, Achievement: "saint"},
+	{ID: 11, Achievement: "played_100"},
+}
+
+func FindAvatar(id int) (Avatar, bool) {
+	for _, avatar := range Avatars {
+		if avatar.ID == id {
+			return avatar, true
+		}
+	}
+	return Avatar{}, false
+}
+
+func freeAvatarIDs() []int {
+	ids := []int{}
+	for _, avatar := range Avatars {
+		if avatar.Free() {
+			ids = append(ids, avatar.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"time"
+)
+
+const (
+	BroadcastRunning   string = "running"
+	BroadcastCompleted string = "completed"
+)
+
+// Broadcast is a message delivered to every user by a background job
+type Broadcast struct {
+	Id      uint   `json:"id" redis:"id"`
+	Created int64  `json:"created" redis:"created"`
+	AdminID int64  `json:"admin_id" redis:"admin_id"` // admin who started the broadcast, receives the progress report
+	Text    string `json:"text" redis:"text"`
+	Status  string `json:"status" redis:"status"` // 'running' or 'completed'
+
+	Cursor  int64 `json:"cursor" redis:"cursor"`   // offset in the users index of the next user
+	Total   int64 `json:"total" redis:"total"`     // indexed users when the broadcast started
+	Sent    int   `json:"sent" redis:"sent"`       // delivered messages
+	Skipped int   `json:"skipped" redis:"skipped"` // inactive users
+	Blocked int   `json:"blocked" redis:"blocked"` // users who blocked the bot during this broadcast
+	Failed  int   `json:"failed" redis:"failed"`   // other delivery errors
+
+	ReportMessageID string `json:"report_message_id" redis:"report_message_id"` // progress message sent to the admin
+}
+
+func NewBroadcast(broadcastID uint, adminID int64, text string, total int64) Broadcast {
+	return Broadcast{
+		Id:      broadcastID,
+		Created: time.Now().Unix(),
+		AdminID: adminID,
+		Text:    text,
+		Status:  BroadcastRunning,
+		Total:   total,
+	}
+}
+
+func (Broadcast) Table() string {
+	return "broadcast"
+}
+
+func (b Broadcast) EntityID() ID {
+	return NewID(b.Table(), b.Id)
+}
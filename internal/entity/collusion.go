@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// PairStats aggregates the games played between two users, P1ID is the lower id
+type PairStats struct {
+	P1ID         int64   `json:"p1_id" redis:"p1_id"`
+	P2ID         int64   `json:"p2_id" redis:"p2_id"`
+	Games        int     `json:"games" redis:"games"`                 // times the pair was matched
+	Rounds       int     `json:"rounds" redis:"rounds"`               // completed rounds between the pair
+	MutualShares int     `json:"mutual_shares" redis:"mutual_shares"` // rounds both players shared
+	QuickJoins   int     `json:"quick_joins" redis:"quick_joins"`     // matches where both joined the lobby almost at once
+	LastMatch    int64   `json:"last_match" redis:"last_match"`
+	Score        float64 `json:"score" redis:"score"`     // risk score between 0 and 1
+	Reasons      string  `json:"reasons" redis:"reasons"` // why the pair is risky
+}
+
+// PairID is the id of the stats of two users
+type PairID string
+
+func NewPairID(userID int64, otherID int64) PairID {
+	return PairID(fmt.Sprintf("%d:%d", min(userID, otherID), max(userID, otherID)))
+}
+
+func NewPairStats(userID int64, otherID int64) PairStats {
+	p1ID, p2ID := min(userID, otherID), max(userID, otherID)
+	return PairStats{P1ID: p1ID, P2ID: p2ID}
+}
+
+// Key returns the id of the pair
+func (p PairStats) Key() PairID {
+	return NewPairID(p.P1ID, p.P2ID)
+}
+
+func (PairStats) Table() string {
+	return "trust:pair"
+}
+
+func (p PairStats) EntityID() ID {
+	return NewID(p.Table(), p.Key())
+}
+
+// MatchedWithin reports whether the pair was matched in the last window
+func (p PairStats) MatchedWithin(window time.Duration, now time.Time) bool {
+	return p.LastMatch != 0 && now.Sub(time.Unix(p.LastMatch, 0)) < window
+}
@@ -1,6 +1,19 @@
 package entity
 
+// DBModel is a model stored in a hash, EntityID must be NewID(Table(), id)
 type DBModel interface {
 	Table() string
 	EntityID() ID
 }
+
+// Key is the type of the id of a model
+type Key interface {
+	~int64 | ~uint | ~string
+}
+
+// Versioned is implemented by the pointer of a model saved with compare-and-set,
+// a save fails when the stored version is not the version the model was read with
+type Versioned interface {
+	GetVersion() int64
+	SetVersion(version int64)
+}
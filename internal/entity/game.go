@@ -1,8 +1,17 @@
 package entity
 
 import (
-	"fmt"
+	"errors"
 	"time"
+
+	"github.com/onionj/trust/internal/commitment"
+)
+
+var (
+	ErrRoundNotOpen      = errors.New("round is not open")
+	ErrInvalidCommit     = errors.New("invalid commitment")
+	ErrCommitMismatch    = errors.New("choice and nonce don't match the commitment")
+	ErrCommitsIncomplete = errors.New("both players must commit first")
 )
 
 // Define constants for each status
@@ -26,52 +35,102 @@ const (
 
 // Game represents a game instance between two players
 type Game struct {
-	Id        uint   `json:"id" redis:"id"`                 // Id
-	Created   uint   `json:"created" redis:"created"`       // Initial time
-	P1ID      int64  `json:"p1_id" redis:"p1_id"`           // Foreign key to User
-	P2ID      int64  `json:"p2_id" redis:"p2_id"`           // Foreign key to User
-	Rounds    int    `json:"rounds" redis:"rounds"`         // Total number of rounds
-	TimeLimit int    `json:"time_limit" redis:"time_limit"` // Decision time limit in seconds
-	Coins     int    `json:"coins" redis:"coins"`           // Total coins
-	Status    string `json:"status" redis:"status"`         // 'active' or 'completed'
-	MaxSteal  int    `json:"max_steal" redis:"max_steal"`   // max steal per player
+	Id         uint   `json:"id" redis:"id"`                   // Id
+	Created    uint   `json:"created" redis:"created"`         // Initial time
+	P1ID       int64  `json:"p1_id" redis:"p1_id"`             // Foreign key to User
+	P2ID       int64  `json:"p2_id" redis:"p2_id"`             // Foreign key to User
+	Rounds     int    `json:"rounds" redis:"rounds"`           // Total number of rounds
+	TimeLimit  int    `json:"time_limit" redis:"time_limit"`   // Decision time limit in seconds
+	Coins      int    `json:"coins" redis:"coins"`             // Total coins
+	Status     string `json:"status" redis:"status"`           // 'active' or 'completed'
+	MaxSteal   int    `json:"max_steal" redis:"max_steal"`     // max steal per player
+	Mode       string `json:"mode" redis:"mode"`               // name of the game mode, '' for games created before modes
+	CoopReward int    `json:"coop_reward" redis:"coop_reward"` // server reward of a mutual share round
+	Blind      int    `json:"blind" redis:"blind"`             // 1 to reveal the rounds only when the game is completed
+	Sealed     int    `json:"sealed" redis:"sealed"`           // 1 when decisions are made with commit-reveal
+	RevealBy   int64  `json:"reveal_by" redis:"reveal_by"`     // sealed games: deadline to reveal the last round both players committed to
+	Tournament uint   `json:"tournament" redis:"tournament"`   // id of the tournament the game is a pairing of, 0 for lobby games
+	Version    int64  `json:"version" redis:"version"`         // incremented on every save, see Versioned
+
+	PayoffBothShare  float64 `json:"payoff_both_share" redis:"payoff_both_share"` // payoff matrix of the mode
+	PayoffBothSteal  float64 `json:"payoff_both_steal" redis:"payoff_both_steal"`
+	PayoffTemptation float64 `json:"payoff_temptation" redis:"payoff_temptation"`
+	PayoffSucker     float64 `json:"payoff_sucker" redis:"payoff_sucker"`
 
 	R1P1Decision string `json:"r1_p1_decision" redis:"r1_p1_decision"` // '' or 'share' or 'steal'
 	R1P2Decision string `json:"r1_p2_decision" redis:"r1_p2_decision"` // '' or 'share' or 'steal'
 	R1Winner     string `json:"r1_winner" redis:"r1_winner"`           // '' or 'p1' or 'p2' or 'p1p2' or 'server'
 	R1Status     string `json:"r1_status" redis:"r1_status"`           // 'in_progress' or 'completed'
 	R1Rewards    int    `json:"r1_rewards" redis:"r1_rewards"`         // server rewards wen winner is p1p2
+	R1P1Commit   string `json:"r1_p1_commit" redis:"r1_p1_commit"`     // commit-reveal games: sha256 commitment of p1
+	R1P2Commit   string `json:"r1_p2_commit" redis:"r1_p2_commit"`     // commit-reveal games: sha256 commitment of p2
+	R1P1Nonce    string `json:"r1_p1_nonce" redis:"r1_p1_nonce"`       // commit-reveal games: revealed nonce of p1
+	R1P2Nonce    string `json:"r1_p2_nonce" redis:"r1_p2_nonce"`       // commit-reveal games: revealed nonce of p2
 
 	R2P1Decision string `json:"r2_p1_decision" redis:"r2_p1_decision"` // '' or 'share' or 'steal'
 	R2P2Decision string `json:"r2_p2_decision" redis:"r2_p2_decision"` // '' or 'share' or 'steal'
 	R2Winner     string `json:"r2_winner" redis:"r2_winner"`           // '' or 'p1' or 'p2' or 'p1p2' or 'server'
 	R2Status     string `json:"r2_status" redis:"r2_status"`           // 'in_progress' or 'completed'
 	R2Rewards    int    `json:"r2_rewards" redis:"r2_rewards"`         // server rewards wen winner is p1p2
+	R2P1Commit   string `json:"r2_p1_commit" redis:"r2_p1_commit"`     // commit-reveal games: sha256 commitment of p1
+	R2P2Commit   string `json:"r2_p2_commit" redis:"r2_p2_commit"`     // commit-reveal games: sha256 commitment of p2
+	R2P1Nonce    string `json:"r2_p1_nonce" redis:"r2_p1_nonce"`       // commit-reveal games: revealed nonce of p1
+	R2P2Nonce    string `json:"r2_p2_nonce" redis:"r2_p2_nonce"`       // commit-reveal games: revealed nonce of p2
 
 	R3P1Decision string `json:"r3_p1_decision" redis:"r3_p1_decision"` // '' or 'share' or 'steal'
 	R3P2Decision string `json:"r3_p2_decision" redis:"r3_p2_decision"` // '' or 'share' or 'steal'
 	R3Winner     string `json:"r3_winner" redis:"r3_winner"`           // '' or 'p1' or 'p2' or 'p1p2' or 'server'
 	R3Status     string `json:"r3_status" redis:"r3_status"`           // 'in_progress' or 'completed'
 	R3Rewards    int    `json:"r3_rewards" redis:"r3_rewards"`         // server rewards wen winner is p1p2
+	R3P1Commit   string `json:"r3_p1_commit" redis:"r3_p1_commit"`     // commit-reveal games: sha256 commitment of p1
+	R3P2Commit   string `json:"r3_p2_commit" redis:"r3_p2_commit"`     // commit-reveal games: sha256 commitment of p2
+	R3P1Nonce    string `json:"r3_p1_nonce" redis:"r3_p1_nonce"`       // commit-reveal games: revealed nonce of p1
+	R3P2Nonce    string `json:"r3_p2_nonce" redis:"r3_p2_nonce"`       // commit-reveal games: revealed nonce of p2
 
 	R4P1Decision string `json:"r4_p1_decision" redis:"r4_p1_decision"` // '' or 'share' or 'steal'
 	R4P2Decision string `json:"r4_p2_decision" redis:"r4_p2_decision"` // '' or 'share' or 'steal'
 	R4Winner     string `json:"r4_winner" redis:"r4_winner"`           // '' or 'p1' or 'p2' or 'p1p2' or 'server'
 	R4Status     string `json:"r4_status" redis:"r4_status"`           // 'in_progress' or 'completed'
 	R4Rewards    int    `json:"r4_rewards" redis:"r4_rewards"`         // server rewards wen winner is p1p2
+	R4P1Commit   string `json:"r4_p1_commit" redis:"r4_p1_commit"`     // commit-reveal games: sha256 commitment of p1
+	R4P2Commit   string `json:"r4_p2_commit" redis:"r4_p2_commit"`     // commit-reveal games: sha256 commitment of p2
+	R4P1Nonce    string `json:"r4_p1_nonce" redis:"r4_p1_nonce"`       // commit-reveal games: revealed nonce of p1
+	R4P2Nonce    string `json:"r4_p2_nonce" redis:"r4_p2_nonce"`       // commit-reveal games: revealed nonce of p2
 }
 
 func NewGame(GameID uint, p1ID int64, p2ID int64) Game {
+	return NewModeGame(GameID, p1ID, p2ID, ClassicMode)
+}
+
+// NewModeGame creates a game with the rules of the mode
+func NewModeGame(GameID uint, p1ID int64, p2ID int64, mode GameMode) Game {
+	blind, sealed := 0, 0
+	if mode.Blind {
+		blind = 1
+	}
+	if mode.CommitReveal {
+		sealed = 1
+	}
+
 	return Game{
-		Id:        GameID,
-		Created:   uint(time.Now().Unix()),
-		P1ID:      p1ID,
-		P2ID:      p2ID,
-		Rounds:    4,
-		TimeLimit: 120,
-		Coins:     400,
-		Status:    Active,
-		MaxSteal:  4,
+		Id:         GameID,
+		Created:    uint(time.Now().Unix()),
+		P1ID:       p1ID,
+		P2ID:       p2ID,
+		Rounds:     mode.Rounds,
+		TimeLimit:  mode.TimeLimit,
+		Coins:      mode.Coins,
+		Status:     Active,
+		MaxSteal:   mode.MaxSteal,
+		Mode:       mode.Name,
+		CoopReward: mode.RoundCoopReward(),
+		Blind:      blind,
+		Sealed:     sealed,
+
+		PayoffBothShare:  mode.Payoff.BothShare,
+		PayoffBothSteal:  mode.Payoff.BothSteal,
+		PayoffTemptation: mode.Payoff.Temptation,
+		PayoffSucker:     mode.Payoff.Sucker,
 	}
 }
 
@@ -80,5 +139,297 @@ func (Game) Table() string {
 }
 
 func (g Game) EntityID() ID {
-	return NewID(fmt.Sprintf("game:p%d:p%d", g.P1ID, g.P2ID), g.Id)
+	return NewID(g.Table(), g.Id)
+}
+
+func (g *Game) GetVersion() int64 {
+	return g.Version
+}
+
+func (g *Game) SetVersion(version int64) {
+	g.Version = version
+}
+
+// PlayerIDs returns the ids of both players
+func (g Game) PlayerIDs() []int64 {
+	return []int64{g.P1ID, g.P2ID}
+}
+
+// HasPlayer reports whether the user plays in the game
+func (g Game) HasPlayer(userID int64) bool {
+	return g.P1ID == userID || g.P2ID == userID
+}
+
+// GameRound is a read only view of the per round fields of a game
+type GameRound struct {
+	P1Decision string
+	P2Decision string
+	Winner     string
+	Status     string
+	Rewards    int
+	P1Commit   string
+	P2Commit   string
+	P1Nonce    string
+	P2Nonce    string
+}
+
+// Decision returns the decision of the player in this round
+func (r GameRound) Decision(game Game, userID int64) string {
+	if game.P1ID == userID {
+		return r.P1Decision
+	}
+	return r.P2Decision
+}
+
+// CompetitorDecision returns the decision of the other player in this round
+func (r GameRound) CompetitorDecision(game Game, userID int64) string {
+	if game.P1ID == userID {
+		return r.P2Decision
+	}
+	return r.P1Decision
+}
+
+// GetRounds returns the rounds of the game in order
+func (g Game) GetRounds() []GameRound {
+	return []GameRound{
+		{g.R1P1Decision, g.R1P2Decision, g.R1Winner, g.R1Status, g.R1Rewards, g.R1P1Commit, g.R1P2Commit, g.R1P1Nonce, g.R1P2Nonce},
+		{g.R2P1Decision, g.R2P2Decision, g.R2Winner, g.R2Status, g.R2Rewards, g.R2P1Commit, g.R2P2Commit, g.R2P1Nonce, g.R2P2Nonce},
+		{g.R3P1Decision, g.R3P2Decision, g.R3Winner, g.R3Status, g.R3Rewards, g.R3P1Commit, g.R3P2Commit, g.R3P1Nonce, g.R3P2Nonce},
+		{g.R4P1Decision, g.R4P2Decision, g.R4Winner, g.R4Status, g.R4Rewards, g.R4P1Commit, g.R4P2Commit, g.R4P1Nonce, g.R4P2Nonce},
+	}[:g.Rounds]
+}
+
+// RoundCoopReward returns the server reward of a mutual share round
+func (g Game) RoundCoopReward() int {
+	if g.Mode == "" {
+		return g.Coins / 40
+	}
+	return g.CoopReward
+}
+
+// Payoff returns the payoff matrix the game is played with
+func (g Game) Payoff() Payoff {
+	if g.Mode == "" {
+		return ClassicPayoff
+	}
+	return Payoff{
+		BothShare:  g.PayoffBothShare,
+		BothSteal:  g.PayoffBothSteal,
+		Temptation: g.PayoffTemptation,
+		Sucker:     g.PayoffSucker,
+	}
+}
+
+// RoundPayout returns the coins of both players for the round, 0 for both until the round has a winner.
+// A round won without both decisions (a forfeit) gives the winner all the round coins,
+// a round given to the server without both decisions (abandoned) pays nobody.
+func (g Game) RoundPayout(round GameRound) (p1 int, p2 int) {
+	roundCoins := g.Coins / g.Rounds
+	payoff := g.Payoff()
+
+	switch round.Winner {
+	case P1P2:
+		each := payoff.Coins(roundCoins, payoff.BothShare)
+		if round.Rewards > 1 {
+			each += round.Rewards / 2
+		}
+		return each, each
+	case Server:
+		if round.P1Decision != Steal || round.P2Decision != Steal {
+			return 0, 0
+		}
+		each := payoff.Coins(roundCoins, payoff.BothSteal)
+		return each, each
+	case P1:
+		if round.P1Decision != Steal || round.P2Decision != Share {
+			return roundCoins, 0
+		}
+		return payoff.Coins(roundCoins, payoff.Temptation), payoff.Coins(roundCoins, payoff.Sucker)
+	case P2:
+		if round.P2Decision != Steal || round.P1Decision != Share {
+			return 0, roundCoins
+		}
+		return payoff.Coins(roundCoins, payoff.Sucker), payoff.Coins(roundCoins, payoff.Temptation)
+	}
+	return 0, 0
+}
+
+// Hidden reports whether the competitor decisions and round results are still hidden
+func (g Game) Hidden() bool {
+	return g.Blind == 1 && g.Status != Completed
+}
+
+// Finished reports whether every round of the game is completed
+func (g Game) Finished() bool {
+	for _, round := range g.GetRounds() {
+		if round.Status != Completed {
+			return false
+		}
+	}
+	return true
+}
+
+// PlayerSide returns 'p1' or 'p2' for the player of the game
+func (g Game) PlayerSide(userID int64) string {
+	if g.P1ID == userID {
+		return P1
+	}
+	return P2
+}
+
+// CompetitorID returns the id of the other player
+func (g Game) CompetitorID(userID int64) int64 {
+	if g.P1ID == userID {
+		return g.P2ID
+	}
+	return g.P1ID
+}
+
+// Forfeit gives every round that is not completed to the competitor of the loser
+func (g *Game) Forfeit(loserID int64) {
+	winner := P1
+	if g.P1ID == loserID {
+		winner = P2
+	}
+	g.completeOpenRounds(winner)
+}
+
+// Abandon gives every round that is not completed to the server, neither player is paid for them
+func (g *Game) Abandon() {
+	g.completeOpenRounds(Server)
+}
+
+func (g *Game) completeOpenRounds(winner string) {
+	rounds := []struct {
+		winner *string
+		status *string
+	}{
+		{&g.R1Winner, &g.R1Status},
+		{&g.R2Winner, &g.R2Status},
+		{&g.R3Winner, &g.R3Status},
+		{&g.R4Winner, &g.R4Status},
+	}
+	for _, round := range rounds[:g.Rounds] {
+		if *round.status != Completed {
+			*round.winner = winner
+			*round.status = Completed
+		}
+	}
+}
+
+// Commitment returns the commitment of the player in this round
+func (r GameRound) Commitment(game Game, userID int64) string {
+	if game.P1ID == userID {
+		return r.P1Commit
+	}
+	return r.P2Commit
+}
+
+// CompetitorCommitment returns the commitment of the other player in this round
+func (r GameRound) CompetitorCommitment(game Game, userID int64) string {
+	if game.P1ID == userID {
+		return r.P2Commit
+	}
+	return r.P1Commit
+}
+
+// Verified reports whether both revealed decisions open the commitments of the round
+func (r GameRound) Verified() bool {
+	return commitment.Verify(r.P1Commit, r.P1Decision, r.P1Nonce) &&
+		commitment.Verify(r.P2Commit, r.P2Decision, r.P2Nonce)
+}
+
+// playerRound returns pointers to the decision, commitment and nonce of the player in the round, 1 based
+func (g *Game) playerRound(round int, userID int64) (decision *string, commit *string, nonce *string) {
+	rounds := []struct {
+		decision, commit, nonce *string
+	}{
+		{&g.R1P1Decision, &g.R1P1Commit, &g.R1P1Nonce},
+		{&g.R1P2Decision, &g.R1P2Commit, &g.R1P2Nonce},
+		{&g.R2P1Decision, &g.R2P1Commit, &g.R2P1Nonce},
+		{&g.R2P2Decision, &g.R2P2Commit, &g.R2P2Nonce},
+		{&g.R3P1Decision, &g.R3P1Commit, &g.R3P1Nonce},
+		{&g.R3P2Decision, &g.R3P2Commit, &g.R3P2Nonce},
+		{&g.R4P1Decision, &g.R4P1Commit, &g.R4P1Nonce},
+		{&g.R4P2Decision, &g.R4P2Commit, &g.R4P2Nonce},
+	}
+	idx := (round - 1) * 2
+	if g.P2ID == userID {
+		idx++
+	}
+	return rounds[idx].decision, rounds[idx].commit, rounds[idx].nonce
+}
+
+// roundOpen reports whether the player can decide the round, the previous round must be decided
+func (g *Game) roundOpen(round int, userID int64) bool {
+	if g.Status != Active || round < 1 || round > g.Rounds {
+		return false
+	}
+	if round == 1 {
+		return true
+	}
+	previous, _, _ := g.playerRound(round-1, userID)
+	return *previous != ""
+}
+
+// Commit stores the hash commitment of the player for the round
+func (g *Game) Commit(round int, userID int64, hash string) error {
+	if !commitment.ValidHash(hash) {
+		return ErrInvalidCommit
+	}
+	if !g.roundOpen(round, userID) {
+		return ErrRoundNotOpen
+	}
+	_, commit, _ := g.playerRound(round, userID)
+	if *commit != "" {
+		return ErrRoundNotOpen
+	}
+	*commit = hash
+	if _, competitorCommit, _ := g.playerRound(round, g.CompetitorID(userID)); *competitorCommit != "" {
+		g.RevealBy = time.Now().Add(time.Duration(g.TimeLimit) * time.Second).Unix()
+	}
+	return nil
+}
+
+// LateRevealer returns the player who didn't reveal a round of a sealed game before RevealBy,
+// 0 when both players are late
+func (g Game) LateRevealer(now time.Time) (int64, bool) {
+	if g.Sealed != 1 || g.Status != Active || g.RevealBy == 0 || now.Unix() < g.RevealBy {
+		return 0, false
+	}
+	for _, round := range g.GetRounds()[:g.Rounds] {
+		if round.P1Commit == "" || round.P2Commit == "" {
+			continue
+		}
+		switch {
+		case round.P1Decision == "" && round.P2Decision == "":
+			return 0, true
+		case round.P2Decision == "":
+			return g.P2ID, true
+		case round.P1Decision == "":
+			return g.P1ID, true
+		}
+	}
+	return 0, false
+}
+
+// Reveal opens the commitment of the player once both players committed to the round
+func (g *Game) Reveal(round int, userID int64, choice string, nonce string) error {
+	if !g.roundOpen(round, userID) {
+		return ErrRoundNotOpen
+	}
+	decision, commit, revealed := g.playerRound(round, userID)
+	if *decision != "" {
+		return ErrRoundNotOpen
+	}
+	_, competitorCommit, _ := g.playerRound(round, g.CompetitorID(userID))
+	if *commit == "" || *competitorCommit == "" {
+		return ErrCommitsIncomplete
+	}
+	if !commitment.Verify(*commit, choice, nonce) {
+		return ErrCommitMismatch
+	}
+	*decision = choice
+	*revealed = nonce
+	return nil
 }
@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onionj/trust/internal/commitment"
+)
+
+func TestHidden(t *testing.T) {
+	mode := ClassicMode
+	mode.Blind = true
+	game := NewModeGame(1, 10, 11, mode)
+	assert.True(t, game.Hidden())
+	game.Status = Completed
+	assert.False(t, game.Hidden())
+	assert.False(t, NewGame(1, 10, 11).Hidden())
+}
+
+func TestCommitReveal(t *testing.T) {
+	mode := ClassicMode
+	mode.CommitReveal = true
+	game := NewModeGame(1, 10, 11, mode)
+	assert.Equal(t, 1, game.Sealed)
+
+	p1Nonce, p2Nonce := "00112233445566778899aabbccddeeff", "ffeeddccbbaa99887766554433221100"
+	assert.ErrorIs(t, game.Commit(1, 10, "not a hash"), ErrInvalidCommit)
+	assert.ErrorIs(t, game.Commit(2, 10, commitment.Hash(Share, p1Nonce)), ErrRoundNotOpen)
+	assert.NoError(t, game.Commit(1, 10, commitment.Hash(Share, p1Nonce)))
+	assert.ErrorIs(t, game.Commit(1, 10, commitment.Hash(Steal, p1Nonce)), ErrRoundNotOpen)
+
+	// nothing is revealed before both players committed
+	assert.ErrorIs(t, game.Reveal(1, 10, Share, p1Nonce), ErrCommitsIncomplete)
+	assert.NoError(t, game.Commit(1, 11, commitment.Hash(Steal, p2Nonce)))
+
+	assert.ErrorIs(t, game.Reveal(1, 11, Share, p2Nonce), ErrCommitMismatch)
+	assert.NoError(t, game.Reveal(1, 11, Steal, p2Nonce))
+	assert.NoError(t, game.Reveal(1, 10, Share, p1Nonce))
+
+	round := game.GetRounds()[0]
+	assert.Equal(t, Share, round.P1Decision)
+	assert.Equal(t, Steal, round.P2Decision)
+	assert.True(t, round.Verified())
+	assert.False(t, game.GetRounds()[1].Verified())
+}
+
+func TestLateRevealer(t *testing.T) {
+	mode := ClassicMode
+	mode.CommitReveal = true
+	game := NewModeGame(1, 10, 11, mode)
+	nonce := "00112233445566778899aabbccddeeff"
+
+	// the deadline starts once both players committed
+	assert.NoError(t, game.Commit(1, 10, commitment.Hash(Share, nonce)))
+	assert.Zero(t, game.RevealBy)
+	assert.NoError(t, game.Commit(1, 11, commitment.Hash(Steal, nonce)))
+	assert.NotZero(t, game.RevealBy)
+
+	_, late := game.LateRevealer(time.Now())
+	assert.False(t, late)
+	// nobody wins the rounds when neither player revealed
+	loserID, late := game.LateRevealer(time.Unix(game.RevealBy, 0))
+	assert.True(t, late)
+	assert.Zero(t, loserID)
+
+	assert.NoError(t, game.Reveal(1, 11, Steal, nonce))
+	loserID, late = game.LateRevealer(time.Unix(game.RevealBy, 0))
+	assert.True(t, late)
+	assert.Equal(t, int64(10), loserID)
+
+	assert.NoError(t, game.Reveal(1, 10, Share, nonce))
+	_, late = game.LateRevealer(time.Unix(game.RevealBy, 0))
+	assert.False(t, late)
+}
+
+func TestForfeit(t *testing.T) {
+	game := NewGame(1, 10, 11)
+	game.R1Winner, game.R1Status = P1P2, Completed
+
+	// the rounds that are not completed go to the competitor of the loser
+	game.Forfeit(10)
+	assert.True(t, game.Finished())
+	rounds := game.GetRounds()
+	assert.Equal(t, P1P2, rounds[0].Winner)
+	for _, round := range rounds[1:] {
+		assert.Equal(t, P2, round.Winner)
+	}
+
+	game = NewGame(2, 10, 11)
+	game.Forfeit(11)
+	for _, round := range game.GetRounds() {
+		assert.Equal(t, P1, round.Winner)
+	}
+}
+
+func TestAbandon(t *testing.T) {
+	game := NewGame(1, 10, 11)
+	game.R1P1Decision, game.R1P2Decision, game.R1Winner, game.R1Status = Share, Share, P1P2, Completed
+	game.R2P1Decision = Steal
+
+	// the decided round is kept and the open rounds pay nobody
+	game.Abandon()
+	assert.True(t, game.Finished())
+	rounds := game.GetRounds()
+	p1, p2 := game.RoundPayout(rounds[0])
+	assert.Positive(t, p1)
+	assert.Positive(t, p2)
+	for _, round := range rounds[1:] {
+		assert.Equal(t, Server, round.Winner)
+		p1, p2 := game.RoundPayout(round)
+		assert.Zero(t, p1)
+		assert.Zero(t, p2)
+	}
+}
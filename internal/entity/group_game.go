@@ -0,0 +1,229 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MinGroupPlayers = 3
+	MaxGroupPlayers = 6
+)
+
+// Forfeited is the decision of the rounds of a player removed from a group game,
+// the player gets no coins of the round and the other players decide it
+const Forfeited string = "forfeit"
+
+var ErrNotInGroup = errors.New("player is not in the group game")
+
+// GroupGame is a public goods game between 3 to 6 players. Each round every player shares or steals,
+// the round coins are split among the sharers when nobody steals, otherwise among the stealers,
+// and the server takes the coins when everybody steals.
+type GroupGame struct {
+	Id         uint   `json:"id" redis:"id"`
+	Created    uint   `json:"created" redis:"created"`
+	Players    string `json:"players" redis:"players"`         // comma separated user ids in seat order
+	Rounds     int    `json:"rounds" redis:"rounds"`           // total number of rounds, 1..MaxRounds
+	Coins      int    `json:"coins" redis:"coins"`             // total coins
+	CoopReward int    `json:"coop_reward" redis:"coop_reward"` // server reward split when everybody shares a round
+	Status     string `json:"status" redis:"status"`           // 'active' or 'completed'
+	Decisions  string `json:"decisions" redis:"decisions"`     // rounds separated by '|', seats by ',', '' or 'share' or 'steal'
+	TimeLimit  int    `json:"time_limit" redis:"time_limit"`   // seconds to decide a round, 0 without a deadline
+	RoundBy    int64  `json:"round_by" redis:"round_by"`       // deadline of the current round, the idle players forfeit after it
+}
+
+func NewGroupGame(gameID uint, playerIDs []int64, rounds int, coins int, coopReward int, timeLimit int) GroupGame {
+	players := make([]string, len(playerIDs))
+	for idx, playerID := range playerIDs {
+		players[idx] = fmt.Sprint(playerID)
+	}
+
+	seats := strings.Repeat(",", len(playerIDs)-1)
+	decisions := make([]string, rounds)
+	for idx := range decisions {
+		decisions[idx] = seats
+	}
+
+	game := GroupGame{
+		Id:         gameID,
+		Created:    uint(time.Now().Unix()),
+		Players:    strings.Join(players, ","),
+		Rounds:     rounds,
+		Coins:      coins,
+		CoopReward: coopReward,
+		Status:     Active,
+		Decisions:  strings.Join(decisions, "|"),
+		TimeLimit:  timeLimit,
+	}
+	game.startRound()
+	return game
+}
+
+func (GroupGame) Table() string {
+	return "group_game"
+}
+
+func (g GroupGame) EntityID() ID {
+	return NewID(g.Table(), g.Id)
+}
+
+// PlayerIDs returns the players in seat order
+func (g GroupGame) PlayerIDs() []int64 {
+	ids := []int64{}
+	for _, raw := range strings.Split(g.Players, ",") {
+		if id, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Seat returns the seat of the player, -1 when the player is not in the game
+func (g GroupGame) Seat(userID int64) int {
+	return slices.Index(g.PlayerIDs(), userID)
+}
+
+// RoundDecisions returns the decisions of every seat in the round, 1 based
+func (g GroupGame) RoundDecisions(round int) []string {
+	return strings.Split(strings.Split(g.Decisions, "|")[round-1], ",")
+}
+
+// RoundCompleted reports whether every player decided the round
+func (g GroupGame) RoundCompleted(round int) bool {
+	return !slices.Contains(g.RoundDecisions(round), "")
+}
+
+// ActiveRound returns the next round the player has to decide, -1 when the player decided every round
+func (g GroupGame) ActiveRound(userID int64) int {
+	seat := g.Seat(userID)
+	if seat < 0 {
+		return -1
+	}
+	for round := 1; round <= g.Rounds; round++ {
+		if g.RoundDecisions(round)[seat] == "" {
+			return round
+		}
+	}
+	return -1
+}
+
+// CurrentRound returns the first round not every player decided, -1 when every round is completed
+func (g GroupGame) CurrentRound() int {
+	for round := 1; round <= g.Rounds; round++ {
+		if !g.RoundCompleted(round) {
+			return round
+		}
+	}
+	return -1
+}
+
+// Decide stores the choice of the player for the round, rounds are decided in order.
+// The deadline restarts when the choice completes the current round.
+func (g *GroupGame) Decide(round int, userID int64, choice string) error {
+	seat := g.Seat(userID)
+	if seat < 0 {
+		return ErrNotInGroup
+	}
+	if g.Status != Active || round != g.ActiveRound(userID) {
+		return ErrRoundNotOpen
+	}
+
+	current := g.CurrentRound()
+	rounds := strings.Split(g.Decisions, "|")
+	decisions := g.RoundDecisions(round)
+	decisions[seat] = choice
+	rounds[round-1] = strings.Join(decisions, ",")
+	g.Decisions = strings.Join(rounds, "|")
+
+	if current != g.CurrentRound() {
+		g.startRound()
+	}
+	return nil
+}
+
+// startRound sets the deadline of the current round
+func (g *GroupGame) startRound() {
+	if g.TimeLimit > 0 {
+		g.RoundBy = time.Now().Add(time.Duration(g.TimeLimit) * time.Second).Unix()
+	}
+}
+
+// IdlePlayers returns the players who didn't decide the current round before RoundBy
+func (g GroupGame) IdlePlayers(now time.Time) []int64 {
+	round := g.CurrentRound()
+	if g.Status != Active || g.RoundBy == 0 || now.Unix() < g.RoundBy || round == -1 {
+		return nil
+	}
+
+	idle := []int64{}
+	playerIDs := g.PlayerIDs()
+	for seat, decision := range g.RoundDecisions(round) {
+		if decision == "" {
+			idle = append(idle, playerIDs[seat])
+		}
+	}
+	return idle
+}
+
+// Finished reports whether every round is completed
+func (g GroupGame) Finished() bool {
+	for round := 1; round <= g.Rounds; round++ {
+		if !g.RoundCompleted(round) {
+			return false
+		}
+	}
+	return true
+}
+
+// RoundPayout returns the coins of every seat for a completed round
+func (g GroupGame) RoundPayout(round int) []int {
+	decisions := g.RoundDecisions(round)
+	payout := make([]int, len(decisions))
+	if !g.RoundCompleted(round) {
+		return payout
+	}
+
+	roundCoins := g.Coins / g.Rounds
+	players, stealers := 0, 0
+	for _, decision := range decisions {
+		if decision != Forfeited {
+			players++
+		}
+		if decision == Steal {
+			stealers++
+		}
+	}
+
+	switch stealers {
+	case players: // the server takes the coins, also when every player forfeited
+	case 0:
+		each := (roundCoins + g.CoopReward) / players
+		for seat, decision := range decisions {
+			if decision == Share {
+				payout[seat] = each
+			}
+		}
+	default:
+		for seat, decision := range decisions {
+			if decision == Steal {
+				payout[seat] = roundCoins / stealers
+			}
+		}
+	}
+	return payout
+}
+
+// Payout returns the coins of every seat for the completed rounds
+func (g GroupGame) Payout() []int {
+	total := make([]int, len(g.PlayerIDs()))
+	for round := 1; round <= g.Rounds; round++ {
+		for seat, coins := range g.RoundPayout(round) {
+			total[seat] += coins
+		}
+	}
+	return total
+}
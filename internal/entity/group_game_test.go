@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupGame(t *testing.T) {
+	game := NewGroupGame(1, []int64{10, 11, 12}, 3, 300, 6, 0)
+	assert.Equal(t, 1, game.ActiveRound(11))
+	assert.Equal(t, -1, game.ActiveRound(99))
+	assert.ErrorIs(t, game.Decide(1, 99, Share), ErrNotInGroup)
+	assert.ErrorIs(t, game.Decide(2, 10, Share), ErrRoundNotOpen)
+
+	// everybody shares: the round coins and the co-op reward are split
+	for _, playerID := range []int64{10, 11, 12} {
+		assert.NoError(t, game.Decide(1, playerID, Share))
+	}
+	assert.True(t, game.RoundCompleted(1))
+	assert.Equal(t, []int{35, 35, 35}, game.RoundPayout(1))
+
+	// two stealers split the round coins
+	assert.NoError(t, game.Decide(2, 10, Steal))
+	assert.NoError(t, game.Decide(2, 11, Share))
+	assert.False(t, game.RoundCompleted(2))
+	assert.Equal(t, []int{0, 0, 0}, game.RoundPayout(2))
+	assert.NoError(t, game.Decide(2, 12, Steal))
+	assert.Equal(t, []int{50, 0, 50}, game.RoundPayout(2))
+
+	// everybody steals: the server takes the coins
+	for _, playerID := range []int64{10, 11, 12} {
+		assert.NoError(t, game.Decide(3, playerID, Steal))
+	}
+	assert.Equal(t, []int{0, 0, 0}, game.RoundPayout(3))
+
+	assert.True(t, game.Finished())
+	assert.Equal(t, []int{85, 35, 85}, game.Payout())
+	assert.Equal(t, -1, game.ActiveRound(10))
+}
+
+func TestGroupGameForfeit(t *testing.T) {
+	game := NewGroupGame(1, []int64{10, 11, 12}, 2, 200, 6, 0)
+
+	// the other players split the share of the forfeited player
+	assert.NoError(t, game.Decide(1, 10, Forfeited))
+	assert.NoError(t, game.Decide(1, 11, Share))
+	assert.NoError(t, game.Decide(1, 12, Share))
+	assert.Equal(t, []int{0, 53, 53}, game.RoundPayout(1))
+
+	// the forfeited player doesn't count as a stealer
+	assert.NoError(t, game.Decide(2, 10, Forfeited))
+	assert.NoError(t, game.Decide(2, 11, Steal))
+	assert.NoError(t, game.Decide(2, 12, Steal))
+	assert.Equal(t, []int{0, 0, 0}, game.RoundPayout(2))
+
+	everybody := NewGroupGame(2, []int64{10, 11, 12}, 1, 100, 6, 0)
+	for _, playerID := range []int64{10, 11, 12} {
+		assert.NoError(t, everybody.Decide(1, playerID, Forfeited))
+	}
+	assert.Equal(t, []int{0, 0, 0}, everybody.RoundPayout(1))
+}
+
+func TestGroupGameIdlePlayers(t *testing.T) {
+	game := NewGroupGame(1, []int64{10, 11, 12}, 2, 200, 6, 30)
+	assert.NotZero(t, game.RoundBy)
+	assert.Empty(t, game.IdlePlayers(time.Now()))
+
+	// the players who didn't decide the current round are idle once the deadline passed
+	assert.NoError(t, game.Decide(1, 10, Share))
+	assert.NoError(t, game.Decide(2, 10, Share))
+	late := time.Unix(game.RoundBy, 0)
+	assert.Equal(t, []int64{11, 12}, game.IdlePlayers(late))
+
+	// the deadline restarts when the round is completed
+	game.RoundBy = 1
+	assert.NoError(t, game.Decide(1, 11, Forfeited))
+	assert.Equal(t, 1, game.CurrentRound())
+	assert.NoError(t, game.Decide(1, 12, Steal))
+	assert.Equal(t, 2, game.CurrentRound())
+	assert.Greater(t, game.RoundBy, time.Now().Unix())
+	assert.Equal(t, []int64{11, 12}, game.IdlePlayers(time.Unix(game.RoundBy, 0)))
+
+	// games without a time limit never have idle players
+	unlimited := NewGroupGame(2, []int64{10, 11, 12}, 1, 100, 6, 0)
+	assert.Empty(t, unlimited.IdlePlayers(time.Now().Add(time.Hour)))
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "time"
+
+const (
+	LedgerGame       string = "game"
+	LedgerPurchase   string = "purchase"
+	LedgerDaily      string = "daily"
+	LedgerReferral   string = "referral"
+	LedgerAdmin      string = "admin"
+	LedgerTournament string = "tournament"
+	LedgerSeason     string = "season"
+	LedgerStars      string = "stars"
+)
+
+// LedgerEntry records a single change of a user balance
+type LedgerEntry struct {
+	Created int64  `json:"created"`
+	UserID  int64  `json:"user_id"`
+	Amount  int    `json:"amount"`  // positive for credit, negative for debit
+	Balance int    `json:"balance"` // balance after the change
+	Kind    string `json:"kind"`    // 'game' or 'purchase' ...
+	Note    string `json:"note"`
+}
+
+func NewLedgerEntry(userID int64, amount int, balance int, kind string, note string) LedgerEntry {
+	return LedgerEntry{
+		Created: time.Now().Unix(),
+		UserID:  userID,
+		Amount:  amount,
+		Balance: balance,
+		Kind:    kind,
+		Note:    note,
+	}
+}
@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxRounds is the number of rounds a game can store
+const MaxRounds = 4
+
+// Payoff is the share of the round coins each player gets for every outcome of a round
+type Payoff struct {
+	BothShare  float64 `json:"both_share"` // each player when both share, the co-op reward is paid on top
+	BothSteal  float64 `json:"both_steal"` // each player when both steal, a refund of the mutual steal penalty
+	Temptation float64 `json:"temptation"` // the stealer when the other player shares
+	Sucker     float64 `json:"sucker"`     // the sharer when the other player steals
+}
+
+// ClassicPayoff splits the coins on mutual share and gives everything to a lone stealer
+var ClassicPayoff = Payoff{BothShare: 0.5, BothSteal: 0, Temptation: 1, Sucker: 0}
+
+// Validate checks the payoff never pays more than the round coins and stealing stays tempting
+func (p Payoff) Validate() error {
+	if p.BothShare < 0 || p.BothSteal < 0 || p.Temptation < 0 || p.Sucker < 0 {
+		return errors.New("payoffs can't be negative")
+	}
+	if 2*p.BothShare > 1 || 2*p.BothSteal > 1 || p.Temptation+p.Sucker > 1 {
+		return errors.New("payoffs can't pay more than the round coins")
+	}
+	if p.Temptation <= 0 || p.Temptation < p.BothShare || p.BothShare < p.BothSteal {
+		return errors.New("payoffs must keep temptation >= both share >= both steal")
+	}
+	return nil
+}
+
+// Coins returns the part of the round coins for the ratio
+func (p Payoff) Coins(roundCoins int, ratio float64) int {
+	return int(float64(roundCoins) * ratio)
+}
+
+// GameMode describes the rules new games are created with
+type GameMode struct {
+	Name         string  `json:"name"`          // registry key, e.g. 'classic'
+	Title        string  `json:"title"`         // shown in the menu
+	Rounds       int     `json:"rounds"`        // number of rounds, 1..MaxRounds
+	Coins        int     `json:"coins"`         // coin pot split over the rounds
+	TimeLimit    int     `json:"time_limit"`    // decision time limit in seconds
+	MaxSteal     int     `json:"max_steal"`     // max steal per player
+	CoopReward   float64 `json:"coop_reward"`   // server reward per mutual share round as a ratio of the pot
+	Payoff       Payoff  `json:"payoff"`        // coins distribution of each round outcome
+	Blind        bool    `json:"blind"`         // reveal decisions and results only when all rounds are decided
+	CommitReveal bool    `json:"commit_reveal"` // decisions are sent as hash commitments and revealed when both committed
+}
+
+// ClassicMode is the mode games were created with before modes existed
+var ClassicMode = GameMode{
+	Name:       "classic",
+	Title:      "Classic",
+	Rounds:     4,
+	Coins:      400,
+	TimeLimit:  120,
+	MaxSteal:   4,
+	CoopReward: 0.025,
+	Payoff:     ClassicPayoff,
+}
+
+// Validate checks the mode can be played
+func (m GameMode) Validate() error {
+	if m.Name == "" {
+		return errors.New("mode name is required")
+	}
+	if m.Rounds < 1 || m.Rounds > MaxRounds {
+		return fmt.Errorf("mode %s: rounds must be between 1 and %d", m.Name, MaxRounds)
+	}
+	if m.Coins < m.Rounds {
+		return fmt.Errorf("mode %s: coins must be at least one per round", m.Name)
+	}
+	if m.TimeLimit <= 0 {
+		return fmt.Errorf("mode %s: time limit must be positive", m.Name)
+	}
+	if m.MaxSteal < 0 {
+		return fmt.Errorf("mode %s: max steal can't be negative", m.Name)
+	}
+	if m.CoopReward < 0 {
+		return fmt.Errorf("mode %s: co-op reward can't be negative", m.Name)
+	}
+	if err := m.Payoff.Validate(); err != nil {
+		return fmt.Errorf("mode %s: %w", m.Name, err)
+	}
+	return nil
+}
+
+// RoundCoopReward is the server reward of a mutual share round
+func (m GameMode) RoundCoopReward() int {
+	return int(float64(m.Coins) * m.CoopReward)
+}
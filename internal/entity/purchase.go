@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+// Purchase is a Telegram Stars payment, identified by the Telegram charge id
+type Purchase struct {
+	ChargeID string `json:"charge_id"`
+	UserID   int64  `json:"user_id"`
+	PackID   string `json:"pack_id"`
+	Stars    int    `json:"stars"`
+	Coins    int    `json:"coins"`  // coins credited for the pack
+	Tokens   int    `json:"tokens"` // bonus game tokens credited for the pack
+	Created  int64  `json:"created"`
+	Refunded int64  `json:"refunded"` // 0 or time the stars were returned
+	Revoked  int64  `json:"revoked"`  // 0 or time the pack was taken back
+}
+
+func NewPurchase(chargeID string, userID int64, packID string, stars int, coins int, tokens int) Purchase {
+	return Purchase{
+		ChargeID: chargeID,
+		UserID:   userID,
+		PackID:   packID,
+		Stars:    stars,
+		Coins:    coins,
+		Tokens:   tokens,
+		Created:  time.Now().Unix(),
+	}
+}
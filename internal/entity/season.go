@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	SeasonActive   string = "active"
+	SeasonArchived string = "archived"
+)
+
+// Season is a ranked period, players collect season points next to their lifetime balance
+type Season struct {
+	Id     uint   `json:"id" redis:"id"`
+	Name   string `json:"name" redis:"name"`
+	Start  int64  `json:"start" redis:"start"`
+	End    int64  `json:"end" redis:"end"`       // the season is archived on the first scheduler tick after it
+	Status string `json:"status" redis:"status"` // 'active' or 'archived'
+}
+
+func NewSeason(seasonID uint, start time.Time, length time.Duration) Season {
+	return Season{
+		Id:     seasonID,
+		Name:   fmt.Sprintf("Season %d", seasonID),
+		Start:  start.Unix(),
+		End:    start.Add(length).Unix(),
+		Status: SeasonActive,
+	}
+}
+
+func (Season) Table() string {
+	return "season"
+}
+
+func (s Season) EntityID() ID {
+	return NewID(s.Table(), s.Id)
+}
+
+func (s Season) Ended(now time.Time) bool {
+	return now.Unix() >= s.End
+}
+
+// SeasonScore is the live season points of a user
+type SeasonScore struct {
+	UserID int64
+	Points int
+}
+
+// SeasonRank is a line of the final ranking archived at the end of a season
+type SeasonRank struct {
+	Place       int    `json:"place"`
+	UserID      int64  `json:"user_id"`
+	DisplayName string `json:"display_name"` // name at the end of the season
+	Points      int    `json:"points"`
+	Reward      int    `json:"reward"` // coins paid for the place
+}
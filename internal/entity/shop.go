@@ -0,0 +1,57 @@
+package entity
+
+import "fmt"
+
+const (
+	ShopCosmetic         string = "cosmetic"          // an avatar added to the inventory
+	ShopBonusTokens      string = "bonus_tokens"      // extra games once the hourly allowance ran out
+	ShopStreakProtection string = "streak_protection" // missed days that keep the daily streak alive
+)
+
+// MaxStreakProtection is the number of protected days a user can hold
+const MaxStreakProtection = 3
+
+// ShopItem is an entry of the shop catalog
+type ShopItem struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Price       int    `json:"price"`
+	Amount      int    `json:"amount"`    // tokens or protected days, 0 for cosmetics
+	AvatarID    int    `json:"avatar_id"` // avatar of a cosmetic item
+}
+
+var shopConsumables = []ShopItem{
+	{ID: "tokens_5", Kind: ShopBonusTokens, Title: "5 bonus tokens", Description: "5 extra games once your hourly games run out", Price: 500, Amount: 5},
+	{ID: "tokens_20", Kind: ShopBonusTokens, Title: "20 bonus tokens", Description: "20 extra games once your hourly games run out", Price: 1_500, Amount: 20},
+	{ID: "streak_protection", Kind: ShopStreakProtection, Title: "Streak protection", Description: "Keeps your daily streak when you miss a day", Price: 1_000, Amount: 1},
+}
+
+// ShopCatalog returns the purchasable avatars followed by the consumables
+func ShopCatalog() []ShopItem {
+	items := []ShopItem{}
+	for _, avatar := range Avatars {
+		if avatar.Price <= 0 {
+			continue
+		}
+		items = append(items, ShopItem{
+			ID:          avatar.ShopItemID(),
+			Kind:        ShopCosmetic,
+			Title:       fmt.Sprintf("Avatar #%d", avatar.ID),
+			Description: "A profile picture for your games",
+			Price:       avatar.Price,
+			AvatarID:    avatar.ID,
+		})
+	}
+	return append(items, shopConsumables...)
+}
+
+func FindShopItem(id string) (ShopItem, bool) {
+	for _, item := range ShopCatalog() {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return ShopItem{}, false
+}
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShopCatalog(t *testing.T) {
+	ids := map[string]bool{}
+	for _, item := range ShopCatalog() {
+		assert.False(t, ids[item.ID], "duplicate item %s", item.ID)
+		ids[item.ID] = true
+		assert.Positive(t, item.Price, item.ID)
+
+		if item.Kind == ShopCosmetic {
+			avatar, ok := FindAvatar(item.AvatarID)
+			assert.True(t, ok, item.ID)
+			assert.Equal(t, avatar.Price, item.Price)
+		} else {
+			assert.Positive(t, item.Amount, item.ID)
+		}
+	}
+
+	item, ok := FindShopItem("avatar_8")
+	assert.True(t, ok)
+	assert.Equal(t, 8, item.AvatarID)
+
+	_, ok = FindShopItem("avatar_1") // free avatars are not sold
+	assert.False(t, ok)
+}
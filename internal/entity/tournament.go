@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	TournamentSingleElimination string = "single_elimination"
+	TournamentSwiss             string = "swiss"
+)
+
+const (
+	TournamentRegistration string = "registration"
+	TournamentRunning      string = "running"
+	TournamentCompleted    string = "completed"
+	TournamentCancelled    string = "cancelled"
+)
+
+// Tournament is a scheduled competition, every pairing is played as a normal game
+type Tournament struct {
+	Id            uint   `json:"id" redis:"id"`
+	Created       int64  `json:"created" redis:"created"`
+	AdminID       int64  `json:"admin_id" redis:"admin_id"` // admin who created the tournament
+	Name          string `json:"name" redis:"name"`
+	Format        string `json:"format" redis:"format"`                 // 'single_elimination' or 'swiss'
+	Mode          string `json:"mode" redis:"mode"`                     // game mode of the pairings
+	EntryFee      int    `json:"entry_fee" redis:"entry_fee"`           // coins paid on registration, added to the prize pool
+	PrizePool     int    `json:"prize_pool" redis:"prize_pool"`         // guaranteed prize plus the entry fees
+	StartsAt      int64  `json:"starts_at" redis:"starts_at"`           // registration closes and the first round is paired
+	Status        string `json:"status" redis:"status"`                 // 'registration', 'running', 'completed' or 'cancelled'
+	Round         int    `json:"round" redis:"round"`                   // current round, 0 before the start
+	Rounds        int    `json:"rounds" redis:"rounds"`                 // total rounds, set on start
+	RoundDeadline int64  `json:"round_deadline" redis:"round_deadline"` // unfinished games of the round are forfeited after it
+	WinnerID      int64  `json:"winner_id" redis:"winner_id"`
+}
+
+func NewTournament(tournamentID uint, adminID int64, name string, format string, mode string, entryFee int, prize int, startsAt time.Time) Tournament {
+	return Tournament{
+		Id:        tournamentID,
+		Created:   time.Now().Unix(),
+		AdminID:   adminID,
+		Name:      name,
+		Format:    format,
+		Mode:      mode,
+		EntryFee:  entryFee,
+		PrizePool: prize,
+		StartsAt:  startsAt.Unix(),
+		Status:    TournamentRegistration,
+	}
+}
+
+func (Tournament) Table() string {
+	return "tournament"
+}
+
+func (t Tournament) EntityID() ID {
+	return NewID(t.Table(), t.Id)
+}
+
+// TournamentMatch is a pairing of a tournament round, P2ID is 0 for a bye
+type TournamentMatch struct {
+	Round    int   `json:"round"`
+	Index    int   `json:"index"`
+	P1ID     int64 `json:"p1_id"`
+	P2ID     int64 `json:"p2_id"`
+	GameID   uint  `json:"game_id"`
+	WinnerID int64 `json:"winner_id"` // 0 until the game is completed
+	P1Coins  int   `json:"p1_coins"`
+	P2Coins  int   `json:"p2_coins"`
+}
+
+// Field is the key of the match in the tournament matches
+func (m TournamentMatch) Field() string {
+	return fmt.Sprintf("%d:%d", m.Round, m.Index)
+}
+
+// Decided reports whether the winner of the match is known
+func (m TournamentMatch) Decided() bool {
+	return m.WinnerID != 0
+}
@@ -13,11 +13,26 @@ type User struct {
 	AvatarID        int    `json:"avatar_id" redis:"avatar_id"`
 	HourLimit       int    `json:"hour_limit" redis:"hour_limit"`
 	LastGamesResult string `json:"last_games_result" redis:"last_games_result"`
+
+	DailyStreak      int    `json:"daily_streak" redis:"daily_streak"`           // consecutive days the daily reward was claimed
+	DailyLastClaim   string `json:"daily_last_claim" redis:"daily_last_claim"`   // '' or day of the last claim 'YYYY-MM-DD'
+	StreakProtection int    `json:"streak_protection" redis:"streak_protection"` // missed days that keep the streak alive
+
+	ReferrerID       int64 `json:"referrer_id" redis:"referrer_id"`             // 0 or the user who invited this user
+	ReferralRewarded int64 `json:"referral_rewarded" redis:"referral_rewarded"` // 0 or time the referral bonus was paid
+
+	BannedUntil int64  `json:"banned_until" redis:"banned_until"` // 0, end of the ban or -1 for a permanent ban
+	BanReason   string `json:"ban_reason" redis:"ban_reason"`
+
+	Inactive int64 `json:"inactive" redis:"inactive"` // 0 or time the user was found to have blocked the bot
+
+	Version int64 `json:"version" redis:"version"` // incremented on every save, see Versioned
 }
 
-var avatar_ids = [11]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+const BanPermanent int64 = -1
 
 func NewUser(id int64, displayName string, Balance int) User {
+	avatar_ids := freeAvatarIDs()
 	return User{
 		Id:              id,
 		Created:         time.Now().Unix(),
@@ -35,5 +50,17 @@ func (u User) Table() string {
 }
 
 func (u User) EntityID() ID {
-	return NewID("user", u.Id)
+	return NewID(u.Table(), u.Id)
+}
+
+func (u *User) GetVersion() int64 {
+	return u.Version
+}
+
+func (u *User) SetVersion(version int64) {
+	u.Version = version
+}
+
+func (u User) IsBanned(now time.Time) bool {
+	return u.BannedUntil == BanPermanent || u.BannedUntil > now.Unix()
 }
@@ -0,0 +1,21 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsBanned(t *testing.T) {
+	now := time.Now()
+	user := NewUser(10, "Onion", 0)
+	assert.False(t, user.IsBanned(now))
+
+	user.BannedUntil = now.Add(time.Hour).Unix()
+	assert.True(t, user.IsBanned(now))
+	assert.False(t, user.IsBanned(now.Add(2*time.Hour)))
+
+	user.BannedUntil = BanPermanent
+	assert.True(t, user.IsBanned(now.Add(24*365*time.Hour)))
+}
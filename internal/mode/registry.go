@@ -0,0 +1,110 @@
+package mode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+var ErrUnknownMode = errors.New("unknown game mode")
+
+// Defaults are the modes used when no modes file is configured
+var Defaults = []entity.GameMode{
+	entity.ClassicMode,
+	{
+		Name: "blitz", Title: "Blitz", Rounds: 2, Coins: 200, TimeLimit: 30, MaxSteal: 2, CoopReward: 0.025,
+		Payoff: entity.Payoff{BothShare: 0.5, BothSteal: 0.25, Temptation: 1, Sucker: 0},
+	},
+	{
+		Name: "high-stakes", Title: "High Stakes", Rounds: 4, Coins: 2000, TimeLimit: 120, MaxSteal: 2, CoopReward: 0.01,
+		Payoff: entity.ClassicPayoff,
+	},
+	{
+		Name: "no-steal-limit", Title: "No Steal Limit", Rounds: 4, Coins: 400, TimeLimit: 120, MaxSteal: entity.MaxRounds, CoopReward: 0.05,
+		Payoff: entity.Payoff{BothShare: 0.5, BothSteal: 0, Temptation: 0.9, Sucker: 0.1},
+	},
+	{
+		Name: "blind", Title: "Blind", Rounds: 4, Coins: 400, TimeLimit: 120, MaxSteal: 4, CoopReward: 0.025,
+		Payoff: entity.ClassicPayoff, Blind: true,
+	},
+	{
+		Name: "sealed", Title: "Sealed", Rounds: 4, Coins: 400, TimeLimit: 120, MaxSteal: 4, CoopReward: 0.025,
+		Payoff: entity.ClassicPayoff, CommitReveal: true,
+	},
+}
+
+// Registry holds the playable game modes in menu order
+type Registry struct {
+	modes       []entity.GameMode
+	defaultName string
+}
+
+// NewRegistry validates the modes, the first one is the default mode
+func NewRegistry(modes []entity.GameMode) (*Registry, error) {
+	if len(modes) == 0 {
+		return nil, errors.New("at least one game mode is required")
+	}
+
+	seen := map[string]bool{}
+	for _, m := range modes {
+		if err := m.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[m.Name] {
+			return nil, fmt.Errorf("duplicate game mode %s", m.Name)
+		}
+		seen[m.Name] = true
+	}
+
+	return &Registry{modes: modes, defaultName: modes[0].Name}, nil
+}
+
+// Load reads the modes from a json file, an empty path loads the defaults.
+// Modes without a payoff use the classic payoff.
+func Load(path string) (*Registry, error) {
+	if path == "" {
+		return NewRegistry(Defaults)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	modes := []entity.GameMode{}
+	if err := json.Unmarshal(raw, &modes); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for idx := range modes {
+		if modes[idx].Payoff == (entity.Payoff{}) {
+			modes[idx].Payoff = entity.ClassicPayoff
+		}
+	}
+	return NewRegistry(modes)
+}
+
+// Get returns the mode by name, an empty name is the default mode
+func (r *Registry) Get(name string) (entity.GameMode, error) {
+	if name == "" {
+		name = r.defaultName
+	}
+	for _, m := range r.modes {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	return entity.GameMode{}, ErrUnknownMode
+}
+
+// Default returns the default mode
+func (r *Registry) Default() entity.GameMode {
+	return r.modes[0]
+}
+
+// All returns the modes in menu order
+func (r *Registry) All() []entity.GameMode {
+	return r.modes
+}
@@ -0,0 +1,96 @@
+package mode
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaults(t *testing.T) {
+	registry, err := Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ClassicMode, registry.Default())
+
+	classic, err := registry.Get("")
+	assert.NoError(t, err)
+	assert.Equal(t, "classic", classic.Name)
+
+	blitz, err := registry.Get("blitz")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, blitz.Rounds)
+
+	blind, err := registry.Get("blind")
+	assert.NoError(t, err)
+	assert.True(t, blind.Blind)
+
+	_, err = registry.Get("missing")
+	assert.ErrorIs(t, err, ErrUnknownMode)
+}
+
+func TestValidation(t *testing.T) {
+	_, err := NewRegistry(nil)
+	assert.Error(t, err)
+
+	tooLong := entity.ClassicMode
+	tooLong.Rounds = entity.MaxRounds + 1
+	_, err = NewRegistry([]entity.GameMode{tooLong})
+	assert.Error(t, err)
+
+	_, err = NewRegistry([]entity.GameMode{entity.ClassicMode, entity.ClassicMode})
+	assert.Error(t, err)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "modes.json")
+	err := os.WriteFile(path, []byte(`[
+		{"name": "duel", "title": "Duel", "rounds": 1, "coins": 100, "time_limit": 20, "max_steal": 1, "coop_reward": 0.1}
+	]`), 0o600)
+	assert.NoError(t, err)
+
+	registry, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "duel", registry.Default().Name)
+	assert.Equal(t, 10, registry.Default().RoundCoopReward())
+}
+
+func TestPayoff(t *testing.T) {
+	assert.NoError(t, entity.ClassicPayoff.Validate())
+	assert.Error(t, entity.Payoff{BothShare: 0.6, Temptation: 1}.Validate())
+	assert.Error(t, entity.Payoff{BothShare: 0.5, Temptation: 0.8, Sucker: 0.3}.Validate())
+	assert.Error(t, entity.Payoff{BothShare: 0.2, BothSteal: 0.3, Temptation: 1}.Validate())
+
+	refund := entity.ClassicMode
+	refund.Name = "refund"
+	refund.Payoff = entity.Payoff{BothShare: 0.5, BothSteal: 0.25, Temptation: 0.9, Sucker: 0.1}
+	game := entity.NewModeGame(1, 10, 11, refund)
+
+	p1, p2 := game.RoundPayout(entity.GameRound{P1Decision: entity.Share, P2Decision: entity.Share, Winner: entity.P1P2, Rewards: 10})
+	assert.Equal(t, []int{55, 55}, []int{p1, p2})
+	p1, p2 = game.RoundPayout(entity.GameRound{P1Decision: entity.Steal, P2Decision: entity.Steal, Winner: entity.Server})
+	assert.Equal(t, []int{25, 25}, []int{p1, p2})
+	p1, p2 = game.RoundPayout(entity.GameRound{P1Decision: entity.Share, P2Decision: entity.Steal, Winner: entity.P2})
+	assert.Equal(t, []int{10, 90}, []int{p1, p2})
+
+	// forfeited rounds go to the winner
+	p1, p2 = game.RoundPayout(entity.GameRound{Winner: entity.P1})
+	assert.Equal(t, []int{100, 0}, []int{p1, p2})
+
+	// games created before modes keep the classic rules
+	legacy := game
+	legacy.Mode = ""
+	p1, p2 = legacy.RoundPayout(entity.GameRound{P1Decision: entity.Steal, P2Decision: entity.Share, Winner: entity.P1})
+	assert.Equal(t, []int{100, 0}, []int{p1, p2})
+}
+
+func TestSealedMode(t *testing.T) {
+	registry, err := Load("")
+	assert.NoError(t, err)
+	sealed, err := registry.Get("sealed")
+	assert.NoError(t, err)
+
+	game := entity.NewModeGame(1, 10, 11, sealed)
+	assert.Equal(t, 1, game.Sealed)
+}
@@ -0,0 +1,230 @@
+// Package payment sells coin and token packs for Telegram Stars.
+//
+// The flow is: SendInvoice -> Telegram asks PreCheckout -> the user pays -> Complete credits the
+// pack once per Telegram charge id. Refund returns the stars of a purchase and takes the pack back.
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+var (
+	ErrUnknownPack     = errors.New("unknown pack")
+	ErrInvalidPayload  = errors.New("invalid invoice payload")
+	ErrInvalidPayment  = errors.New("payment doesn't match the pack")
+	ErrDuplicateCharge = errors.New("charge already credited")
+	ErrRefunded        = errors.New("purchase already refunded")
+	ErrPartialCredit   = errors.New("pack partially credited")
+	ErrPackSpent       = errors.New("the tokens of the pack are already spent")
+)
+
+// Pack is a product sold for stars
+type Pack struct {
+	ID          string
+	Title       string
+	Description string
+	Stars       int
+	Coins       int
+	Tokens      int // bonus game tokens
+}
+
+var Packs = []Pack{
+	{ID: "coins_5000", Title: "5,000 Coins", Description: "5,000 coins for the shop and tournaments", Stars: 50, Coins: 5_000},
+	{ID: "coins_25000", Title: "25,000 Coins", Description: "25,000 coins for the shop and tournaments", Stars: 200, Coins: 25_000},
+	{ID: "tokens_30", Title: "30 Bonus Tokens", Description: "30 extra games once your hourly games run out", Stars: 25, Tokens: 30},
+	{ID: "tokens_100", Title: "100 Bonus Tokens", Description: "100 extra games once your hourly games run out", Stars: 75, Tokens: 100},
+}
+
+func FindPack(id string) (Pack, bool) {
+	for _, pack := range Packs {
+		if pack.ID == id {
+			return pack, true
+		}
+	}
+	return Pack{}, false
+}
+
+// Bot is the part of the Telegram bot API used by payments, *tele.Bot implements it
+type Bot interface {
+	Send(to tele.Recipient, what interface{}, opts ...interface{}) (*tele.Message, error)
+	Accept(query *tele.PreCheckoutQuery, errorMessage ...string) error
+	RefundStars(to tele.Recipient, chargeID string) error
+}
+
+// Store keeps the purchases by charge id, Get fails for an unknown charge id
+type Store interface {
+	// Claim saves the purchase, false when the charge id is already saved
+	Claim(ctx context.Context, purchase entity.Purchase) (bool, error)
+	Release(ctx context.Context, chargeID string) error
+	Get(ctx context.Context, chargeID string) (entity.Purchase, error)
+	Save(ctx context.Context, purchase entity.Purchase) error
+}
+
+// Wallet credits and takes back the content of a purchase
+type Wallet interface {
+	// Credit credits the whole pack or nothing, ErrPartialCredit when a failure left a part credited
+	Credit(ctx context.Context, purchase entity.Purchase) error
+	// Revoke takes the whole pack back or nothing, it fails when the user already spent
+	// the coins or ErrPackSpent when the user already spent the tokens
+	Revoke(ctx context.Context, purchase entity.Purchase) error
+	// Reclaim takes the pack back even when the coins were spent, the balance may go negative
+	Reclaim(ctx context.Context, purchase entity.Purchase) error
+}
+
+type Service struct {
+	bot    Bot
+	store  Store
+	wallet Wallet
+}
+
+func NewService(bot Bot, store Store, wallet Wallet) *Service {
+	return &Service{bot: bot, store: store, wallet: wallet}
+}
+
+// Payload identifies the pack and the buyer of an invoice
+func Payload(pack Pack, userID int64) string {
+	return fmt.Sprintf("%s:%d", pack.ID, userID)
+}
+
+func ParsePayload(payload string) (Pack, int64, error) {
+	packID, rawUserID, ok := strings.Cut(payload, ":")
+	if !ok {
+		return Pack{}, 0, ErrInvalidPayload
+	}
+	userID, err := strconv.ParseInt(rawUserID, 10, 64)
+	if err != nil {
+		return Pack{}, 0, ErrInvalidPayload
+	}
+	pack, ok := FindPack(packID)
+	if !ok {
+		return Pack{}, 0, ErrUnknownPack
+	}
+	return pack, userID, nil
+}
+
+// SendInvoice sends the invoice of the pack to the user chat
+func (s *Service) SendInvoice(userID int64, packID string) error {
+	pack, ok := FindPack(packID)
+	if !ok {
+		return ErrUnknownPack
+	}
+	_, err := s.bot.Send(tele.ChatID(userID), &tele.Invoice{
+		Title:       pack.Title,
+		Description: pack.Description,
+		Payload:     Payload(pack, userID),
+		Currency:    tele.Stars,
+		Prices:      []tele.Price{{Label: pack.Title, Amount: pack.Stars}},
+	})
+	return err
+}
+
+// PreCheckout accepts the query only when it matches an invoice of the sender
+func (s *Service) PreCheckout(query *tele.PreCheckoutQuery) error {
+	if err := validate(query.Payload, query.Currency, query.Total, query.Sender.ID); err != nil {
+		return s.bot.Accept(query, "This invoice is no longer valid, please request a new one.")
+	}
+	return s.bot.Accept(query)
+}
+
+// Complete credits a successful payment, a charge id is credited only once
+func (s *Service) Complete(ctx context.Context, userID int64, payment *tele.Payment) (entity.Purchase, error) {
+	if err := validate(payment.Payload, payment.Currency, payment.Total, userID); err != nil {
+		return entity.Purchase{}, err
+	}
+	pack, _, _ := ParsePayload(payment.Payload)
+
+	purchase := entity.NewPurchase(payment.TelegramChargeID, userID, pack.ID, pack.Stars, pack.Coins, pack.Tokens)
+	claimed, err := s.store.Claim(ctx, purchase)
+	if err != nil {
+		return purchase, err
+	}
+	if !claimed {
+		return purchase, ErrDuplicateCharge
+	}
+
+	if err := s.wallet.Credit(ctx, purchase); err != nil {
+		// keep the claim so that a later delivery doesn't credit the same part twice
+		if errors.Is(err, ErrPartialCredit) {
+			return purchase, err
+		}
+		// let a later delivery of the same payment credit it
+		if releaseErr := s.store.Release(ctx, purchase.ChargeID); releaseErr != nil {
+			return purchase, errors.Join(err, releaseErr)
+		}
+		return purchase, err
+	}
+	return purchase, nil
+}
+
+// Refund returns the stars of a purchase and takes the pack back,
+// the refund fails when the user already spent the credited coins
+func (s *Service) Refund(ctx context.Context, chargeID string) (entity.Purchase, error) {
+	purchase, err := s.store.Get(ctx, chargeID)
+	if err != nil {
+		return purchase, err
+	}
+	if purchase.Refunded != 0 {
+		return purchase, ErrRefunded
+	}
+
+	if err := s.wallet.Revoke(ctx, purchase); err != nil {
+		return purchase, err
+	}
+	if err := s.bot.RefundStars(tele.ChatID(purchase.UserID), chargeID); err != nil {
+		if creditErr := s.wallet.Credit(ctx, purchase); creditErr != nil {
+			return purchase, errors.Join(err, creditErr)
+		}
+		return purchase, err
+	}
+	purchase.Revoked = time.Now().Unix()
+	return s.markRefunded(ctx, purchase)
+}
+
+// Refunded records a refund made outside of Refund. The stars are already returned so the refund
+// is recorded first, then the pack is taken back even if the coins were spent. A later
+// notification of the same refund takes the pack back if that failed.
+func (s *Service) Refunded(ctx context.Context, refund *tele.RefundedPayment) (entity.Purchase, error) {
+	purchase, err := s.store.Get(ctx, refund.TelegramChargeID)
+	if err != nil {
+		return purchase, err
+	}
+	if purchase.Refunded == 0 {
+		if purchase, err = s.markRefunded(ctx, purchase); err != nil {
+			return purchase, err
+		}
+	}
+	if purchase.Revoked != 0 {
+		return purchase, nil
+	}
+
+	if err := s.wallet.Reclaim(ctx, purchase); err != nil {
+		return purchase, err
+	}
+	purchase.Revoked = time.Now().Unix()
+	return purchase, s.store.Save(ctx, purchase)
+}
+
+func (s *Service) markRefunded(ctx context.Context, purchase entity.Purchase) (entity.Purchase, error) {
+	purchase.Refunded = time.Now().Unix()
+	return purchase, s.store.Save(ctx, purchase)
+}
+
+func validate(payload string, currency string, total int, userID int64) error {
+	pack, buyerID, err := ParsePayload(payload)
+	if err != nil {
+		return err
+	}
+	if buyerID != userID || currency != tele.Stars || total != pack.Stars {
+		return ErrInvalidPayment
+	}
+	return nil
+}
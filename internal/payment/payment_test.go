@@ -0,0 +1,286 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+type fakeBot struct {
+	sent       []*tele.Invoice
+	accepted   map[string]string // query id -> error message, '' when accepted
+	refunded   []string
+	failRefund bool
+}
+
+func (b *fakeBot) Send(to tele.Recipient, what interface{}, opts ...interface{}) (*tele.Message, error) {
+	b.sent = append(b.sent, what.(*tele.Invoice))
+	return &tele.Message{}, nil
+}
+
+func (b *fakeBot) Accept(query *tele.PreCheckoutQuery, errorMessage ...string) error {
+	b.accepted[query.ID] = ""
+	if len(errorMessage) > 0 {
+		b.accepted[query.ID] = errorMessage[0]
+	}
+	return nil
+}
+
+func (b *fakeBot) RefundStars(to tele.Recipient, chargeID string) error {
+	if b.failRefund {
+		return errors.New("refund failed")
+	}
+	b.refunded = append(b.refunded, chargeID)
+	return nil
+}
+
+type fakeStore map[string]entity.Purchase
+
+func (s fakeStore) Claim(ctx context.Context, purchase entity.Purchase) (bool, error) {
+	if _, ok := s[purchase.ChargeID]; ok {
+		return false, nil
+	}
+	s[purchase.ChargeID] = purchase
+	return true, nil
+}
+
+func (s fakeStore) Release(ctx context.Context, chargeID string) error {
+	delete(s, chargeID)
+	return nil
+}
+
+func (s fakeStore) Get(ctx context.Context, chargeID string) (entity.Purchase, error) {
+	purchase, ok := s[chargeID]
+	if !ok {
+		return purchase, errors.New("purchase not found")
+	}
+	return purchase, nil
+}
+
+func (s fakeStore) Save(ctx context.Context, purchase entity.Purchase) error {
+	s[purchase.ChargeID] = purchase
+	return nil
+}
+
+type fakeWallet struct {
+	coins  map[int64]int
+	tokens map[int64]int
+	fail   error
+}
+
+func (w *fakeWallet) Credit(ctx context.Context, purchase entity.Purchase) error {
+	if w.fail != nil {
+		return w.fail
+	}
+	w.coins[purchase.UserID] += purchase.Coins
+	w.tokens[purchase.UserID] += purchase.Tokens
+	return nil
+}
+
+func (w *fakeWallet) Revoke(ctx context.Context, purchase entity.Purchase) error {
+	if w.coins[purchase.UserID] < purchase.Coins {
+		return errors.New("insufficient balance")
+	}
+	w.coins[purchase.UserID] -= purchase.Coins
+	w.tokens[purchase.UserID] = max(w.tokens[purchase.UserID]-purchase.Tokens, 0)
+	return nil
+}
+
+func (w *fakeWallet) Reclaim(ctx context.Context, purchase entity.Purchase) error {
+	w.coins[purchase.UserID] -= purchase.Coins
+	w.tokens[purchase.UserID] = max(w.tokens[purchase.UserID]-purchase.Tokens, 0)
+	return nil
+}
+
+func newTestService() (*Service, *fakeBot, fakeStore, *fakeWallet) {
+	bot := &fakeBot{accepted: map[string]string{}}
+	store := fakeStore{}
+	wallet := &fakeWallet{coins: map[int64]int{}, tokens: map[int64]int{}}
+	return NewService(bot, store, wallet), bot, store, wallet
+}
+
+func payment(packID string, userID int64, chargeID string) *tele.Payment {
+	pack, _ := FindPack(packID)
+	return &tele.Payment{
+		Currency:         tele.Stars,
+		Total:            pack.Stars,
+		Payload:          Payload(pack, userID),
+		TelegramChargeID: chargeID,
+	}
+}
+
+func TestSendInvoice(t *testing.T) {
+	service, bot, _, _ := newTestService()
+
+	assert.NoError(t, service.SendInvoice(10, "coins_5000"))
+	assert.Len(t, bot.sent, 1)
+	assert.Equal(t, tele.Stars, bot.sent[0].Currency)
+	assert.Equal(t, "coins_5000:10", bot.sent[0].Payload)
+	assert.Equal(t, 50, bot.sent[0].Prices[0].Amount)
+
+	assert.ErrorIs(t, service.SendInvoice(10, "gold"), ErrUnknownPack)
+}
+
+func TestPreCheckout(t *testing.T) {
+	service, bot, _, _ := newTestService()
+
+	query := &tele.PreCheckoutQuery{ID: "q1", Sender: &tele.User{ID: 10}, Currency: tele.Stars, Total: 50, Payload: "coins_5000:10"}
+	assert.NoError(t, service.PreCheckout(query))
+	assert.Equal(t, "", bot.accepted["q1"])
+
+	for id, query := range map[string]*tele.PreCheckoutQuery{
+		"other_sender": {Sender: &tele.User{ID: 11}, Currency: tele.Stars, Total: 50, Payload: "coins_5000:10"},
+		"wrong_total":  {Sender: &tele.User{ID: 10}, Currency: tele.Stars, Total: 1, Payload: "coins_5000:10"},
+		"currency":     {Sender: &tele.User{ID: 10}, Currency: "USD", Total: 50, Payload: "coins_5000:10"},
+		"unknown_pack": {Sender: &tele.User{ID: 10}, Currency: tele.Stars, Total: 50, Payload: "gold:10"},
+	} {
+		query.ID = id
+		assert.NoError(t, service.PreCheckout(query))
+		assert.NotEqual(t, "", bot.accepted[id], id)
+	}
+}
+
+func TestCompleteIsIdempotent(t *testing.T) {
+	service, _, store, wallet := newTestService()
+	ctx := context.Background()
+
+	purchase, err := service.Complete(ctx, 10, payment("coins_5000", 10, "charge-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5_000, purchase.Coins)
+	assert.Equal(t, 5_000, wallet.coins[10])
+
+	_, err = service.Complete(ctx, 10, payment("coins_5000", 10, "charge-1"))
+	assert.ErrorIs(t, err, ErrDuplicateCharge)
+	assert.Equal(t, 5_000, wallet.coins[10])
+
+	_, err = service.Complete(ctx, 10, payment("tokens_30", 10, "charge-2"))
+	assert.NoError(t, err)
+	assert.Equal(t, 30, wallet.tokens[10])
+	assert.Len(t, store, 2)
+
+	_, err = service.Complete(ctx, 11, payment("coins_5000", 10, "charge-3"))
+	assert.ErrorIs(t, err, ErrInvalidPayment)
+	assert.Len(t, store, 2)
+}
+
+func TestCompleteCreditFailure(t *testing.T) {
+	service, _, store, wallet := newTestService()
+	ctx := context.Background()
+
+	// nothing was credited, a later delivery credits the pack
+	wallet.fail = errors.New("redis down")
+	_, err := service.Complete(ctx, 10, payment("coins_5000", 10, "charge-1"))
+	assert.Error(t, err)
+	assert.Empty(t, store)
+
+	wallet.fail = nil
+	_, err = service.Complete(ctx, 10, payment("coins_5000", 10, "charge-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5_000, wallet.coins[10])
+
+	// a part was credited, the claim stays so it isn't credited twice
+	wallet.fail = ErrPartialCredit
+	_, err = service.Complete(ctx, 10, payment("coins_5000", 10, "charge-2"))
+	assert.ErrorIs(t, err, ErrPartialCredit)
+	assert.Contains(t, store, "charge-2")
+
+	wallet.fail = nil
+	_, err = service.Complete(ctx, 10, payment("coins_5000", 10, "charge-2"))
+	assert.ErrorIs(t, err, ErrDuplicateCharge)
+}
+
+func TestRefund(t *testing.T) {
+	service, bot, store, wallet := newTestService()
+	ctx := context.Background()
+
+	_, err := service.Complete(ctx, 10, payment("coins_5000", 10, "charge-1"))
+	assert.NoError(t, err)
+
+	purchase, err := service.Refund(ctx, "charge-1")
+	assert.NoError(t, err)
+	assert.NotZero(t, purchase.Refunded)
+	assert.Equal(t, []string{"charge-1"}, bot.refunded)
+	assert.Equal(t, 0, wallet.coins[10])
+
+	_, err = service.Refund(ctx, "charge-1")
+	assert.ErrorIs(t, err, ErrRefunded)
+
+	_, err = service.Refund(ctx, "charge-404")
+	assert.Error(t, err)
+
+	// the coins are credited back when telegram rejects the refund
+	_, err = service.Complete(ctx, 10, payment("coins_5000", 10, "charge-2"))
+	assert.NoError(t, err)
+	bot.failRefund = true
+	_, err = service.Refund(ctx, "charge-2")
+	assert.Error(t, err)
+	assert.Equal(t, 5_000, wallet.coins[10])
+	assert.Zero(t, store["charge-2"].Refunded)
+
+	// spent coins can't be refunded
+	bot.failRefund = false
+	wallet.coins[10] = 100
+	_, err = service.Refund(ctx, "charge-2")
+	assert.Error(t, err)
+	assert.Zero(t, store["charge-2"].Refunded)
+}
+
+func TestRefunded(t *testing.T) {
+	service, _, _, wallet := newTestService()
+	ctx := context.Background()
+
+	_, err := service.Complete(ctx, 10, payment("tokens_30", 10, "charge-1"))
+	assert.NoError(t, err)
+
+	purchase, err := service.Refunded(ctx, &tele.RefundedPayment{TelegramChargeID: "charge-1"})
+	assert.NoError(t, err)
+	assert.NotZero(t, purchase.Refunded)
+	assert.Equal(t, 0, wallet.tokens[10])
+
+	// a second notification changes nothing
+	wallet.tokens[10] = 5
+	_, err = service.Refunded(ctx, &tele.RefundedPayment{TelegramChargeID: "charge-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, wallet.tokens[10])
+}
+
+func TestRefundedSpentCoins(t *testing.T) {
+	service, _, store, wallet := newTestService()
+	ctx := context.Background()
+
+	_, err := service.Complete(ctx, 10, payment("coins_5000", 10, "charge-1"))
+	assert.NoError(t, err)
+	wallet.coins[10] = 1_000
+
+	// the stars are already returned, the spent coins become a debt
+	purchase, err := service.Refunded(ctx, &tele.RefundedPayment{TelegramChargeID: "charge-1"})
+	assert.NoError(t, err)
+	assert.NotZero(t, purchase.Refunded)
+	assert.NotZero(t, purchase.Revoked)
+	assert.Equal(t, -4_000, wallet.coins[10])
+	assert.Equal(t, purchase, store["charge-1"])
+
+	_, err = service.Refunded(ctx, &tele.RefundedPayment{TelegramChargeID: "charge-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, -4_000, wallet.coins[10])
+}
+
+func TestRefundedAfterRefund(t *testing.T) {
+	service, _, _, wallet := newTestService()
+	ctx := context.Background()
+
+	_, err := service.Complete(ctx, 10, payment("coins_5000", 10, "charge-1"))
+	assert.NoError(t, err)
+	_, err = service.Refund(ctx, "charge-1")
+	assert.NoError(t, err)
+
+	// the notification of a refund made by Refund doesn't take the pack twice
+	_, err = service.Refunded(ctx, &tele.RefundedPayment{TelegramChargeID: "charge-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, wallet.coins[10])
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/pkg/maptostruct"
+)
+
+var _ AchievementRepository = (*achievementRepository)(nil) // implement check
+
+const (
+	achievementProgressKey = "trust:user%d:achievements:progress"
+	achievementUnlockedKey = "trust:user%d:achievements"
+)
+
+type achievementRepository struct {
+	redis *redis.Client
+}
+
+func NewAchievementRepository(redis *redis.Client) AchievementRepository {
+	return &achievementRepository{redis: redis}
+}
+
+// GetProgress returns the achievement counters of the user, zero values if none are stored
+func (a achievementRepository) GetProgress(ctx context.Context, userID int64) (entity.AchievementProgress, error) {
+	var progress entity.AchievementProgress
+	hash, err := a.redis.HGetAll(ctx, fmt.Sprintf(achievementProgressKey, userID)).Result()
+	if err != nil {
+		return progress, fmt.Errorf("failed to retrieve achievement progress of user %d: %v", userID, err)
+	}
+	if err := maptostruct.MapToStruct(hash, &progress); err != nil {
+		return progress, fmt.Errorf("failed to map redis hash to struct: %v", err)
+	}
+	return progress, nil
+}
+
+func (a achievementRepository) SaveProgress(ctx context.Context, userID int64, progress entity.AchievementProgress) error {
+	return a.redis.HSet(ctx, fmt.Sprintf(achievementProgressKey, userID), progress).Err()
+}
+
+// Unlocked returns the ids of the achievements the user owns
+func (a achievementRepository) Unlocked(ctx context.Context, userID int64) ([]string, error) {
+	return a.redis.SMembers(ctx, fmt.Sprintf(achievementUnlockedKey, userID)).Result()
+}
+
+// Unlock marks the achievement as owned and reports whether it was newly added
+func (a achievementRepository) Unlock(ctx context.Context, userID int64, achievementID string) (bool, error) {
+	added, err := a.redis.SAdd(ctx, fmt.Sprintf(achievementUnlockedKey, userID), achievementID).Result()
+	return added == 1, err
+}
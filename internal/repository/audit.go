@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/pkg/jsonhelper"
+)
+
+var _ AuditRepository = (*auditRepository)(nil) // implement check
+
+const auditKey = "trust:audit"
+
+type auditRepository struct {
+	redis *redis.Client
+}
+
+func NewAuditRepository(redis *redis.Client) AuditRepository {
+	return &auditRepository{redis: redis}
+}
+
+// Append stores the entry on top of the audit log
+func (a auditRepository) Append(ctx context.Context, entry entity.AuditEntry) error {
+	return a.redis.LPush(ctx, auditKey, jsonhelper.Encode(entry)).Err()
+}
+
+// List returns the latest audit entries, newest first
+func (a auditRepository) List(ctx context.Context, limit int) ([]entity.AuditEntry, error) {
+	raw, err := a.redis.LRange(ctx, auditKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %v", err)
+	}
+
+	entries := make([]entity.AuditEntry, len(raw))
+	for i, item := range raw {
+		entries[i] = jsonhelper.Decode[entity.AuditEntry]([]byte(item))
+	}
+	return entries, nil
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"github.com/onionj/trust/internal/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ BroadcastRepository = (*broadcastRepository)(nil) // implement check
+
+type broadcastRepository struct {
+	redis *redis.Client
+	CommonBehaviorRepository[entity.Broadcast, uint]
+}
+
+func NewBroadcastRepository(redis *redis.Client) BroadcastRepository {
+	return &broadcastRepository{
+		redis:                    redis,
+		CommonBehaviorRepository: NewCommonBehavior[entity.Broadcast, uint](redis),
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+var _ CollusionRepository = (*collusionRepository)(nil) // implement check
+
+const (
+	pairRiskKey = "trust:risk:pairs" // pair key -> risk score
+	userRiskKey = "trust:risk:users" // user id -> highest risk score of the user pairs
+)
+
+type collusionRepository struct {
+	redis *redis.Client
+	CommonBehaviorRepository[entity.PairStats, entity.PairID]
+}
+
+func NewCollusionRepository(redis *redis.Client) CollusionRepository {
+	return &collusionRepository{
+		redis:                    redis,
+		CommonBehaviorRepository: NewCommonBehavior[entity.PairStats, entity.PairID](redis),
+	}
+}
+
+// GetPair returns the stats of two users, empty stats if they never played together
+func (c collusionRepository) GetPair(ctx context.Context, userID int64, otherID int64) (entity.PairStats, error) {
+	stats := entity.NewPairStats(userID, otherID)
+	dbStats, err := c.GetByID(ctx, stats.Key())
+	if errors.Is(err, ErrNotFound) {
+		return stats, nil
+	}
+	return dbStats, err
+}
+
+// SaveRisk stores the pair score in the risk report and raises the risk of both users
+func (c collusionRepository) SaveRisk(ctx context.Context, stats entity.PairStats) error {
+	pipe := c.redis.Pipeline()
+	pipe.ZAdd(ctx, pairRiskKey, redis.Z{Score: stats.Score, Member: stats.EntityID().String()})
+	pipe.ZAddGT(ctx, userRiskKey, redis.Z{Score: stats.Score, Member: stats.P1ID})
+	pipe.ZAddGT(ctx, userRiskKey, redis.Z{Score: stats.Score, Member: stats.P2ID})
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// TopPairs returns the riskiest pairs with a score above zero
+func (c collusionRepository) TopPairs(ctx context.Context, limit int) ([]entity.PairStats, error) {
+	keys, err := c.redis.ZRevRangeByScore(ctx, pairRiskKey, &redis.ZRangeBy{Min: "(0", Max: "+inf", Count: int64(limit)}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pair risks: %v", err)
+	}
+
+	pairs := make([]entity.PairStats, 0, len(keys))
+	for _, key := range keys {
+		stats, err := c.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, stats)
+	}
+	return pairs, nil
+}
+
+// UserRisk returns the highest risk score of the user pairs, 0 if none
+func (c collusionRepository) UserRisk(ctx context.Context, userID int64) (float64, error) {
+	score, err := c.redis.ZScore(ctx, userRiskKey, fmt.Sprint(userID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return score, err
+}
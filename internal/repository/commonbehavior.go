@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/redis/go-redis/v9"
@@ -10,23 +11,80 @@ import (
 	"github.com/onionj/trust/pkg/maptostruct"
 )
 
-type commonBehavior[T entity.DBModel] struct {
-	redis *redis.Client
+// indexFunc queues the writes of the indexes of the model in the transaction that saves it
+type indexFunc[T any] func(ctx context.Context, pipe redis.Pipeliner, model *T)
+
+type commonBehavior[T entity.DBModel, K entity.Key] struct {
+	redis   *redis.Client
+	indexes indexFunc[T]
 }
 
-func NewCommonBehavior[T entity.DBModel](redis *redis.Client) CommonBehaviorRepository[T] {
-	return &commonBehavior[T]{
+func NewCommonBehavior[T entity.DBModel, K entity.Key](redis *redis.Client) CommonBehaviorRepository[T, K] {
+	return &commonBehavior[T, K]{
 		redis: redis,
 	}
 }
 
-func (c commonBehavior[T]) Save(ctx context.Context, model T) error {
-	_, err := c.redis.HSet(ctx, model.EntityID().String(), model).Result()
+// newIndexedBehavior is NewCommonBehavior that keeps the indexes of the model in every save
+func newIndexedBehavior[T entity.DBModel, K entity.Key](redis *redis.Client, indexes indexFunc[T]) CommonBehaviorRepository[T, K] {
+	return &commonBehavior[T, K]{
+		redis:   redis,
+		indexes: indexes,
+	}
+}
+
+// Save stores the model and its indexes in one transaction. A Versioned model is saved only if the stored
+// version is the version of the model, the version is incremented on success and ErrConflict is returned otherwise.
+func (c commonBehavior[T, K]) Save(ctx context.Context, model *T) error {
+	key := (*model).EntityID().String()
+
+	versioned, ok := any(model).(entity.Versioned)
+	if !ok {
+		_, err := c.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			c.write(ctx, pipe, key, model)
+			return nil
+		})
+		return err
+	}
+
+	version := versioned.GetVersion()
+	err := c.redis.Watch(ctx, func(tx *redis.Tx) error {
+		stored, err := tx.HGet(ctx, key, "version").Int64()
+		if errors.Is(err, redis.Nil) {
+			stored = 0 // new model or saved before versions
+		} else if err != nil {
+			return fmt.Errorf("failed to retrieve version of %s: %v", key, err)
+		}
+		if stored != version {
+			return ErrConflict
+		}
+
+		versioned.SetVersion(version + 1)
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			c.write(ctx, pipe, key, model)
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil {
+		versioned.SetVersion(version)
+	}
+	if errors.Is(err, redis.TxFailedErr) {
+		return ErrConflict
+	}
 	return err
 }
 
+// write queues the hash of the model and its indexes
+func (c commonBehavior[T, K]) write(ctx context.Context, pipe redis.Pipeliner, key string, model *T) {
+	pipe.HSet(ctx, key, model)
+	if c.indexes != nil {
+		c.indexes(ctx, pipe, model)
+	}
+}
+
 // Get retrieves a specific key from Redis and converts it into the struct T
-func (c commonBehavior[T]) Get(ctx context.Context, key string) (T, error) {
+func (c commonBehavior[T, K]) Get(ctx context.Context, key string) (T, error) {
 	// Fetch all fields for the key using HGetAll
 	hash, err := c.redis.HGetAll(ctx, key).Result()
 	if err != nil {
@@ -47,19 +105,91 @@ func (c commonBehavior[T]) Get(ctx context.Context, key string) (T, error) {
 	return model, nil
 }
 
+// GetByID retrieves the model with the id
+func (c commonBehavior[T, K]) GetByID(ctx context.Context, id K) (T, error) {
+	return c.Get(ctx, c.key(id))
+}
+
+// GetMany retrieves the models with the ids in one pipeline, in the order of the ids.
+// The zero value of T is returned for the ids that don't exist.
+func (c commonBehavior[T, K]) GetMany(ctx context.Context, ids []K) ([]T, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = c.key(id)
+	}
+	return c.getKeys(ctx, keys)
+}
+
+// Delete removes the model with the id
+func (c commonBehavior[T, K]) Delete(ctx context.Context, id K) error {
+	return c.redis.Del(ctx, c.key(id)).Err()
+}
+
+// Exists reports whether the model with the id exists
+func (c commonBehavior[T, K]) Exists(ctx context.Context, id K) (bool, error) {
+	n, err := c.redis.Exists(ctx, c.key(id)).Result()
+	return n == 1, err
+}
+
+// Update loads the model with the id, applies fn and saves the result in a WATCH/MULTI transaction.
+// The version of a Versioned model is incremented.
+// Nothing is saved if fn returns an error, ErrConflict is returned if the model changed meanwhile.
+// The indexes of the model are written in the same transaction like in Save.
+func (c commonBehavior[T, K]) Update(ctx context.Context, id K, fn func(model *T) error) (T, error) {
+	key := c.key(id)
+	var model T
+
+	err := c.redis.Watch(ctx, func(tx *redis.Tx) error {
+		hash, err := tx.HGetAll(ctx, key).Result()
+		if err != nil {
+			return fmt.Errorf("failed to retrieve key %s: %v", key, err)
+		}
+		if len(hash) == 0 {
+			return ErrNotFound
+		}
+		if err := maptostruct.MapToStruct(hash, &model); err != nil {
+			return fmt.Errorf("failed to map redis hash to struct: %v", err)
+		}
+
+		if err := fn(&model); err != nil {
+			return err
+		}
+		if versioned, ok := any(&model).(entity.Versioned); ok {
+			versioned.SetVersion(versioned.GetVersion() + 1)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			c.write(ctx, pipe, key, &model)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.TxFailedErr) {
+		return model, ErrConflict
+	}
+	return model, err
+}
+
+// key returns the key of the model with the id, the EntityID of the model
+func (c commonBehavior[T, K]) key(id K) string {
+	var model T
+	return entity.NewID(model.Table(), id).String()
+}
+
 // Keys retrieves all keys matching the pattern
-func (c commonBehavior[T]) Keys(ctx context.Context, pattern string) []string {
+func (c commonBehavior[T, K]) Keys(ctx context.Context, pattern string) []string {
 	return c.redis.Keys(context.Background(), pattern).Val()
 }
 
-// Scan retrieves all keys matching the pattern and fetches their associated values using a pipeline
-func (c commonBehavior[T]) Scan(ctx context.Context, pattern string, limit int) ([]T, error) {
+// Scan retrieves all keys matching the pattern and fetches their associated values using a pipeline.
+// Only hashes are read, other keys matching the pattern like user:<id>:ledger are skipped.
+func (c commonBehavior[T, K]) Scan(ctx context.Context, pattern string, limit int) ([]T, error) {
 	var allKeys []string
 	var cursor uint64
 
 	// Use SCAN to retrieve keys in chunks
 	for {
-		keys, nextCursor, err := c.redis.Scan(ctx, cursor, pattern, 1000).Result()
+		keys, nextCursor, err := c.redis.ScanType(ctx, cursor, pattern, 1000, "hash").Result()
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan keys: %v", err)
 		}
@@ -74,19 +204,24 @@ func (c commonBehavior[T]) Scan(ctx context.Context, pattern string, limit int)
 		}
 	}
 
+	return c.getKeys(ctx, allKeys)
+}
+
+// getKeys fetches the values of the keys using a pipeline
+func (c commonBehavior[T, K]) getKeys(ctx context.Context, keys []string) ([]T, error) {
 	// Early return if no keys were found
-	if len(allKeys) == 0 {
+	if len(keys) == 0 {
 		return []T{}, nil
 	}
 
 	// Initialize slice to store results
-	results := make([]T, len(allKeys))
+	results := make([]T, len(keys))
 
 	// Use pipelining to retrieve all the keys' values in parallel
 	pipe := c.redis.Pipeline()
-	cmds := make([]*redis.MapStringStringCmd, len(allKeys))
+	cmds := make([]*redis.MapStringStringCmd, len(keys))
 
-	for i, key := range allKeys {
+	for i, key := range keys {
 		// Fetch all fields of the hash using HGetAll for each key
 		cmds[i] = pipe.HGetAll(ctx, key)
 	}
@@ -101,7 +236,10 @@ func (c commonBehavior[T]) Scan(ctx context.Context, pattern string, limit int)
 	for i, cmd := range cmds {
 		hash, err := cmd.Result()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get hash for key %s: %v", allKeys[i], err)
+			return nil, fmt.Errorf("failed to get hash for key %s: %v", keys[i], err)
+		}
+		if len(hash) == 0 {
+			continue
 		}
 
 		// Convert the hash map back to the struct
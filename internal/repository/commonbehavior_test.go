@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/onionj/trust/config"
+	"github.com/onionj/trust/db"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommonBehaviorQueries(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	userRepo := NewUserRepository(redis)
+	ctx := context.Background()
+
+	onion, sarah := entity.NewUser(10, "Onion", 10000), entity.NewUser(11, "Sarah", 10000)
+	assert.NoError(t, userRepo.Save(ctx, &onion))
+	assert.NoError(t, userRepo.Save(ctx, &sarah))
+
+	user, err := userRepo.GetByID(ctx, int64(10))
+	assert.NoError(t, err)
+	assert.Equal(t, "Onion", user.DisplayName)
+
+	_, err = userRepo.GetByID(ctx, int64(12))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	users, err := userRepo.GetMany(ctx, []int64{11, 12, 10})
+	assert.NoError(t, err)
+	assert.Len(t, users, 3)
+	assert.Equal(t, "Sarah", users[0].DisplayName)
+	assert.Zero(t, users[1].Id)
+	assert.Equal(t, "Onion", users[2].DisplayName)
+
+	exists, err := userRepo.Exists(ctx, 11)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, userRepo.Delete(ctx, 11))
+	exists, err = userRepo.Exists(ctx, 11)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// the id key is the key the model is saved under
+	collusionRepo := NewCollusionRepository(redis)
+	stats := entity.NewPairStats(11, 10)
+	stats.Games = 3
+	assert.NoError(t, collusionRepo.Save(ctx, &stats))
+
+	dbStats, err := collusionRepo.GetByID(ctx, entity.NewPairID(10, 11))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, dbStats.Games)
+}
+
+func TestCommonBehaviorUpdate(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	userRepo := NewUserRepository(redis)
+	ctx := context.Background()
+
+	onion := entity.NewUser(10, "Onion", 10000)
+	assert.NoError(t, userRepo.Save(ctx, &onion))
+
+	user, err := userRepo.Update(ctx, 10, func(user *entity.User) error {
+		user.Balance += 500
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 10500, user.Balance)
+
+	// an error of fn saves nothing
+	errStop := errors.New("stop")
+	_, err = userRepo.Update(ctx, 10, func(user *entity.User) error {
+		user.Balance = 0
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+
+	// a write between the read and the save is a conflict and is kept
+	_, err = userRepo.Update(ctx, 10, func(user *entity.User) error {
+		user.Balance = 0
+		return redis.HSet(ctx, "user:10", "balance", 7).Err()
+	})
+	assert.ErrorIs(t, err, ErrConflict)
+
+	user, err = userRepo.GetByID(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, user.Balance)
+
+	_, err = userRepo.Update(ctx, 12, func(user *entity.User) error { return nil })
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCommonBehaviorVersion(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	userRepo := NewUserRepository(redis)
+	ctx := context.Background()
+
+	user := entity.NewUser(10, "Onion", 10000)
+	assert.NoError(t, userRepo.Save(ctx, &user))
+	assert.Equal(t, int64(1), user.Version)
+
+	stale, err := userRepo.GetByID(ctx, 10)
+	assert.NoError(t, err)
+
+	user.Balance = 500
+	assert.NoError(t, userRepo.Save(ctx, &user))
+	assert.Equal(t, int64(2), user.Version)
+
+	// a copy read before the last save can't overwrite it
+	stale.Balance = 0
+	assert.ErrorIs(t, userRepo.Save(ctx, &stale), ErrConflict)
+	assert.Equal(t, int64(1), stale.Version)
+
+	updated, err := userRepo.Update(ctx, 10, func(user *entity.User) error {
+		user.Balance += 1
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 501, updated.Balance)
+	assert.Equal(t, int64(3), updated.Version)
+	assert.ErrorIs(t, userRepo.Save(ctx, &user), ErrConflict)
+
+	// a new model can't replace a saved one
+	again := entity.NewUser(10, "Onion", 10000)
+	assert.ErrorIs(t, userRepo.Save(ctx, &again), ErrConflict)
+
+	// UpdateRetry applies fn again on a fresh copy after a conflict
+	calls := 0
+	updated, err = UpdateRetry(ctx, userRepo, 10, func(user *entity.User) error {
+		calls++
+		if calls == 1 {
+			conflicting, err := userRepo.GetByID(ctx, 10)
+			if err != nil {
+				return err
+			}
+			conflicting.Balance = 1000
+			if err := userRepo.Save(ctx, &conflicting); err != nil {
+				return err
+			}
+		}
+		user.Balance += 1
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 1001, updated.Balance)
+}
@@ -1,19 +1,137 @@
 package repository
 
 import (
-	"github.com/onionj/trust/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
 )
 
 var _ GameRepository = (*gameRepository)(nil) // implement check
 
+const (
+	activeGameKey  = "trust:user%d:active_game" // id of the active game of the user
+	playerGamesKey = "trust:user%d:games"       // sorted set of the game ids of the user scored by creation time
+	legacyGameKeys = "game:p*"                  // games saved before the indexes were keyed game:p<p1>:p<p2>:<id>
+)
+
+// clearActiveScript removes the active game of the players (KEYS) only when it is still the game (ARGV[1])
+var clearActiveScript = redis.NewScript(`
+for _, key in ipairs(KEYS) do
+	if redis.call('GET', key) == ARGV[1] then
+		redis.call('DEL', key)
+	end
+end
+return 0
+`)
+
 type gameRepository struct {
 	redis *redis.Client
-	CommonBehaviorRepository[entity.Game]
+	CommonBehaviorRepository[entity.Game, uint]
 }
 
 func NewGameRepository(redis *redis.Client) GameRepository {
 	return &gameRepository{
-		CommonBehaviorRepository: NewCommonBehavior[entity.Game](redis),
+		redis:                    redis,
+		CommonBehaviorRepository: newIndexedBehavior[entity.Game, uint](redis, gameIndexes),
+	}
+}
+
+// gameIndexes keeps the game indexes of both players in the transaction that saves the game
+func gameIndexes(ctx context.Context, pipe redis.Pipeliner, game *entity.Game) {
+	activeKeys := []string{}
+	for _, playerID := range game.PlayerIDs() {
+		pipe.ZAdd(ctx, fmt.Sprintf(playerGamesKey, playerID), redis.Z{Score: float64(game.Created), Member: game.Id})
+		activeKeys = append(activeKeys, fmt.Sprintf(activeGameKey, playerID))
+	}
+
+	if game.Status == entity.Active {
+		for _, key := range activeKeys {
+			pipe.Set(ctx, key, game.Id, 0)
+		}
+	} else {
+		clearActiveScript.Eval(ctx, pipe, activeKeys, game.Id)
+	}
+}
+
+// ActiveGame returns the active game of the user, ErrNotFound when the user has none
+func (g gameRepository) ActiveGame(ctx context.Context, userID int64) (entity.Game, error) {
+	gameID, err := g.redis.Get(ctx, fmt.Sprintf(activeGameKey, userID)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return entity.Game{}, ErrNotFound
+	} else if err != nil {
+		return entity.Game{}, err
+	}
+	return g.GetByID(ctx, uint(gameID))
+}
+
+// PlayerGames returns the last limit games of the user, newest first, every game when limit is 0
+func (g gameRepository) PlayerGames(ctx context.Context, userID int64, limit int64) ([]entity.Game, error) {
+	members, err := g.redis.ZRevRange(ctx, fmt.Sprintf(playerGamesKey, userID), 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read games of user %d: %v", userID, err)
+	}
+
+	gameIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		gameID, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid game id %s in index: %v", member, err)
+		}
+		gameIDs = append(gameIDs, uint(gameID))
+	}
+
+	games, err := g.GetMany(ctx, gameIDs)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(games, func(game entity.Game) bool { return game.Id == 0 }), nil
+}
+
+// Migrate moves the games saved under the legacy keys to their id key and indexes them,
+// it returns the number of moved games. A legacy key of a game that was already moved is removed.
+func (g gameRepository) Migrate(ctx context.Context) (int, error) {
+	keys := []string{}
+	iter := g.redis.Scan(ctx, 0, legacyGameKeys, 1000).Iterator()
+	for iter.Next(ctx) {
+		// a legacy key has the players and the id, game:p<p1>:p<p2>:<id>
+		if strings.Count(iter.Val(), ":") == 3 {
+			keys = append(keys, iter.Val())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return 0, fmt.Errorf("failed to scan legacy games: %v", err)
+	}
+
+	moved := 0
+	for _, key := range keys {
+		game, err := g.Get(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		} else if err != nil {
+			return moved, err
+		}
+
+		// a run stopped between the save and the delete already moved the game
+		exists, err := g.Exists(ctx, game.Id)
+		if err != nil {
+			return moved, err
+		}
+		if !exists {
+			if err := g.Save(ctx, &game); err != nil {
+				return moved, err
+			}
+			moved++
+		}
+		if err := g.redis.Del(ctx, key).Err(); err != nil {
+			return moved, err
+		}
 	}
+	return moved, nil
 }
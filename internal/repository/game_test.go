@@ -22,18 +22,18 @@ func TestGameRepository(t *testing.T) {
 	gameRepo := NewGameRepository(redis)
 
 	user := entity.NewUser(10, "Onion", 10000)
-	err = userRepo.Save(context.Background(), user)
+	err = userRepo.Save(context.Background(), &user)
 	assert.NoError(t, err)
 
 	user2 := entity.NewUser(11, "Sarah", 10000)
-	err = userRepo.Save(context.Background(), user2)
+	err = userRepo.Save(context.Background(), &user2)
 	assert.NoError(t, err)
 
 	game := entity.NewGame(1, 10, 11)
-	err = gameRepo.Save(context.Background(), game)
+	err = gameRepo.Save(context.Background(), &game)
 	assert.NoError(t, err)
 
-	dbGame, err := gameRepo.Get(context.Background(), "game:p10:p11:1")
+	dbGame, err := gameRepo.Get(context.Background(), "game:1")
 	assert.NoError(t, err)
 
 	assert.Equal(t, game.Created, dbGame.Created)
@@ -45,7 +45,11 @@ func TestGameRepository(t *testing.T) {
 	assert.Equal(t, game.Coins, dbGame.Coins)
 	assert.Equal(t, game.Status, dbGame.Status)
 
-	dbGames, err := gameRepo.Scan(context.Background(), "game*:p10*", 1)
+	activeGame, err := gameRepo.ActiveGame(context.Background(), 11)
+	assert.NoError(t, err)
+	assert.Equal(t, game.Id, activeGame.Id)
+
+	dbGames, err := gameRepo.PlayerGames(context.Background(), 10, 0)
 	assert.NoError(t, err)
 	assert.Len(t, dbGames, 1)
 	assert.Equal(t, game.Created, dbGames[0].Created)
@@ -64,10 +68,10 @@ func TestGameRepository(t *testing.T) {
 	game.R1Status = entity.Completed
 	game.R1Rewards = 0
 
-	err = gameRepo.Save(context.Background(), game)
+	err = gameRepo.Save(context.Background(), &game)
 	assert.NoError(t, err)
 
-	dbGameNew, err := gameRepo.Get(context.Background(), "game:p10:p11:1")
+	dbGameNew, err := gameRepo.Get(context.Background(), "game:1")
 	assert.NoError(t, err)
 
 	assert.Equal(t, game.Created, dbGameNew.Created)
@@ -83,4 +87,124 @@ func TestGameRepository(t *testing.T) {
 	assert.Equal(t, entity.P2, dbGameNew.R1Winner)
 	assert.Equal(t, entity.Completed, dbGameNew.R1Status)
 	assert.Equal(t, 0, dbGameNew.R1Rewards)
+
+	_, err = gameRepo.ActiveGame(context.Background(), 10)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGameRepositoryIndexes(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	gameRepo := NewGameRepository(redis)
+	ctx := context.Background()
+
+	// user 1 must not see the games of user 10
+	other := entity.NewGame(1, 10, 11)
+	assert.NoError(t, gameRepo.Save(ctx, &other))
+	games, err := gameRepo.PlayerGames(ctx, 1, 0)
+	assert.NoError(t, err)
+	assert.Len(t, games, 0)
+	_, err = gameRepo.ActiveGame(ctx, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// completing an older game keeps the newer active game of the player
+	older := entity.NewGame(2, 1, 12)
+	older.Created -= 10
+	assert.NoError(t, gameRepo.Save(ctx, &older))
+	newer := entity.NewGame(3, 1, 13)
+	assert.NoError(t, gameRepo.Save(ctx, &newer))
+	older.Status = entity.Completed
+	assert.NoError(t, gameRepo.Save(ctx, &older))
+
+	activeGame, err := gameRepo.ActiveGame(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, newer.Id, activeGame.Id)
+	_, err = gameRepo.ActiveGame(ctx, 12)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	games, err = gameRepo.PlayerGames(ctx, 1, 0)
+	assert.NoError(t, err)
+	assert.Len(t, games, 2)
+	assert.Equal(t, newer.Id, games[0].Id)
+	assert.Equal(t, older.Id, games[1].Id)
+
+	games, err = gameRepo.PlayerGames(ctx, 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, games, 1)
+
+	dbGame, err := gameRepo.GetByID(ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), dbGame.P2ID)
+	_, err = gameRepo.GetByID(ctx, 4)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// a rejected save leaves the indexes alone
+	stale := dbGame
+	assert.NoError(t, gameRepo.Save(ctx, &dbGame))
+	stale.Status = entity.Completed
+	assert.ErrorIs(t, gameRepo.Save(ctx, &stale), ErrConflict)
+	activeGame, err = gameRepo.ActiveGame(ctx, 13)
+	assert.NoError(t, err)
+	assert.Equal(t, newer.Id, activeGame.Id)
+
+	// Update keeps the indexes like Save
+	_, err = gameRepo.Update(ctx, newer.Id, func(game *entity.Game) error {
+		game.Status = entity.Completed
+		return nil
+	})
+	assert.NoError(t, err)
+	_, err = gameRepo.ActiveGame(ctx, 13)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGameRepositoryMigrate(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	gameRepo := NewGameRepository(redis)
+	ctx := context.Background()
+
+	legacy := entity.NewGame(7, 20, 21)
+	assert.NoError(t, redis.HSet(ctx, "game:p20:p21:7", legacy).Err())
+
+	moved, err := gameRepo.Migrate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, moved)
+
+	exists, err := redis.Exists(ctx, "game:p20:p21:7").Result()
+	assert.NoError(t, err)
+	assert.Zero(t, exists)
+
+	activeGame, err := gameRepo.ActiveGame(ctx, 21)
+	assert.NoError(t, err)
+	assert.Equal(t, legacy.Id, activeGame.Id)
+	assert.Equal(t, legacy.Created, activeGame.Created)
+
+	moved, err = gameRepo.Migrate(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, moved)
+
+	// a legacy key left by an interrupted migration is removed, the moved game is kept
+	activeGame.Status = entity.Completed
+	assert.NoError(t, gameRepo.Save(ctx, &activeGame))
+	assert.NoError(t, redis.HSet(ctx, "game:p20:p21:7", legacy).Err())
+
+	moved, err = gameRepo.Migrate(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, moved)
+	exists, err = redis.Exists(ctx, "game:p20:p21:7").Result()
+	assert.NoError(t, err)
+	assert.Zero(t, exists)
+	dbGame, err := gameRepo.GetByID(ctx, legacy.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Completed, dbGame.Status)
 }
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+var _ GroupGameRepository = (*groupGameRepository)(nil) // implement check
+
+const activeGroupGameKey = "trust:user%d:group_game" // id of the active group game of the user
+
+type groupGameRepository struct {
+	redis *redis.Client
+	CommonBehaviorRepository[entity.GroupGame, uint]
+}
+
+func NewGroupGameRepository(redis *redis.Client) GroupGameRepository {
+	return &groupGameRepository{
+		redis:                    redis,
+		CommonBehaviorRepository: NewCommonBehavior[entity.GroupGame, uint](redis),
+	}
+}
+
+// SetActive marks the game as the active group game of every player
+func (g groupGameRepository) SetActive(ctx context.Context, game entity.GroupGame) error {
+	pipe := g.redis.Pipeline()
+	for _, playerID := range game.PlayerIDs() {
+		pipe.Set(ctx, fmt.Sprintf(activeGroupGameKey, playerID), game.Id, 0)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ActiveID returns the active group game of the user, 0 when the user has none
+func (g groupGameRepository) ActiveID(ctx context.Context, userID int64) (uint, error) {
+	id, err := g.redis.Get(ctx, fmt.Sprintf(activeGroupGameKey, userID)).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return uint(id), err
+}
+
+// ClearActive removes the active group game of every player of the game
+func (g groupGameRepository) ClearActive(ctx context.Context, game entity.GroupGame) error {
+	pipe := g.redis.Pipeline()
+	for _, playerID := range game.PlayerIDs() {
+		pipe.Del(ctx, fmt.Sprintf(activeGroupGameKey, playerID))
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onionj/trust/config"
+	"github.com/onionj/trust/db"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupGameRepository(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	groupRepo := NewGroupGameRepository(redis)
+
+	game := entity.NewGroupGame(1, []int64{10, 11, 12}, 2, 300, 6, 0)
+	assert.NoError(t, game.Decide(1, 11, entity.Share))
+	err = groupRepo.Save(context.Background(), &game)
+	assert.NoError(t, err)
+
+	dbGame, err := groupRepo.Get(context.Background(), "group_game:1")
+	assert.NoError(t, err)
+	assert.Equal(t, game.Players, dbGame.Players)
+	assert.Equal(t, game.Decisions, dbGame.Decisions)
+	assert.Equal(t, []int64{10, 11, 12}, dbGame.PlayerIDs())
+	assert.Equal(t, []string{"", entity.Share, ""}, dbGame.RoundDecisions(1))
+
+	activeID, err := groupRepo.ActiveID(context.Background(), 12)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), activeID)
+
+	assert.NoError(t, groupRepo.SetActive(context.Background(), game))
+	activeID, err = groupRepo.ActiveID(context.Background(), 12)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), activeID)
+
+	assert.NoError(t, groupRepo.ClearActive(context.Background(), game))
+	activeID, err = groupRepo.ActiveID(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), activeID)
+}
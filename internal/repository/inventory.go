@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ InventoryRepository = (*inventoryRepository)(nil) // implement check
+
+const inventoryKey = "trust:user%d:inventory:%s"
+
+type inventoryRepository struct {
+	redis *redis.Client
+}
+
+func NewInventoryRepository(redis *redis.Client) InventoryRepository {
+	return &inventoryRepository{redis: redis}
+}
+
+// Add puts the item in the user inventory of the given kind
+func (i inventoryRepository) Add(ctx context.Context, userID int64, kind string, itemID string) error {
+	return i.redis.SAdd(ctx, fmt.Sprintf(inventoryKey, userID, kind), itemID).Err()
+}
+
+// Has reports whether the user owns the item
+func (i inventoryRepository) Has(ctx context.Context, userID int64, kind string, itemID string) (bool, error) {
+	return i.redis.SIsMember(ctx, fmt.Sprintf(inventoryKey, userID, kind), itemID).Result()
+}
+
+// List returns all items of the given kind owned by the user
+func (i inventoryRepository) List(ctx context.Context, userID int64, kind string) ([]string, error) {
+	return i.redis.SMembers(ctx, fmt.Sprintf(inventoryKey, userID, kind)).Result()
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/pkg/jsonhelper"
+)
+
+var _ LedgerRepository = (*ledgerRepository)(nil) // implement check
+
+const ledgerKey = "trust:user%d:ledger"
+
+type ledgerRepository struct {
+	redis *redis.Client
+}
+
+func NewLedgerRepository(redis *redis.Client) LedgerRepository {
+	return &ledgerRepository{redis: redis}
+}
+
+// Append stores the entry on top of the user ledger
+func (l ledgerRepository) Append(ctx context.Context, entry entity.LedgerEntry) error {
+	return l.redis.LPush(ctx, fmt.Sprintf(ledgerKey, entry.UserID), jsonhelper.Encode(entry)).Err()
+}
+
+// List returns the latest ledger entries of the user, newest first
+func (l ledgerRepository) List(ctx context.Context, userID int64, limit int) ([]entity.LedgerEntry, error) {
+	raw, err := l.redis.LRange(ctx, fmt.Sprintf(ledgerKey, userID), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ledger of user %d: %v", userID, err)
+	}
+
+	entries := make([]entity.LedgerEntry, len(raw))
+	for i, item := range raw {
+		entries[i] = jsonhelper.Decode[entity.LedgerEntry]([]byte(item))
+	}
+	return entries, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/pkg/jsonhelper"
+)
+
+var _ PaymentRepository = (*paymentRepository)(nil) // implement check
+
+const paymentKey = "trust:payment:%s" // telegram charge id -> purchase json
+
+type paymentRepository struct {
+	redis *redis.Client
+}
+
+func NewPaymentRepository(redis *redis.Client) PaymentRepository {
+	return &paymentRepository{redis: redis}
+}
+
+// Claim saves the purchase, false when a purchase with the same charge id exists
+func (p paymentRepository) Claim(ctx context.Context, purchase entity.Purchase) (bool, error) {
+	return p.redis.SetNX(ctx, fmt.Sprintf(paymentKey, purchase.ChargeID), jsonhelper.Encode(purchase), 0).Result()
+}
+
+func (p paymentRepository) Release(ctx context.Context, chargeID string) error {
+	return p.redis.Del(ctx, fmt.Sprintf(paymentKey, chargeID)).Err()
+}
+
+func (p paymentRepository) Get(ctx context.Context, chargeID string) (entity.Purchase, error) {
+	raw, err := p.redis.Get(ctx, fmt.Sprintf(paymentKey, chargeID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return entity.Purchase{}, ErrNotFound
+	} else if err != nil {
+		return entity.Purchase{}, err
+	}
+	return jsonhelper.Decode[entity.Purchase](raw), nil
+}
+
+func (p paymentRepository) Save(ctx context.Context, purchase entity.Purchase) error {
+	return p.redis.Set(ctx, fmt.Sprintf(paymentKey, purchase.ChargeID), jsonhelper.Encode(purchase), 0).Err()
+}
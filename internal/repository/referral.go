@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ ReferralRepository = (*referralRepository)(nil) // implement check
+
+const referralKey = "trust:user%d:referrals"
+
+// addReferralScript adds the referee (ARGV[1]) to the referrals (KEYS[1]) while they hold less than the cap (ARGV[2]),
+// it returns 1 when the referee is attributed and 0 when the referrer reached the cap
+var addReferralScript = redis.NewScript(`
+if redis.call('SISMEMBER', KEYS[1], ARGV[1]) == 1 then
+	return 1
+end
+if redis.call('SCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('SADD', KEYS[1], ARGV[1])
+return 1
+`)
+
+type referralRepository struct {
+	redis *redis.Client
+}
+
+func NewReferralRepository(redis *redis.Client) ReferralRepository {
+	return &referralRepository{redis: redis}
+}
+
+// Add attributes the referee to the referrer unless the referrer already has max referees,
+// it returns false when the referrer reached the cap
+func (r referralRepository) Add(ctx context.Context, referrerID int64, refereeID int64, max int) (bool, error) {
+	added, err := addReferralScript.Run(ctx, r.redis, []string{fmt.Sprintf(referralKey, referrerID)}, refereeID, max).Int()
+	if err != nil {
+		return false, err
+	}
+	return added == 1, nil
+}
+
+// Count returns the number of users attributed to the referrer
+func (r referralRepository) Count(ctx context.Context, referrerID int64) (int64, error) {
+	return r.redis.SCard(ctx, fmt.Sprintf(referralKey, referrerID)).Result()
+}
+
+// List returns the ids of the users attributed to the referrer
+func (r referralRepository) List(ctx context.Context, referrerID int64) ([]int64, error) {
+	members, err := r.redis.SMembers(ctx, fmt.Sprintf(referralKey, referrerID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid referee id %s: %v", member, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/onionj/trust/config"
+	"github.com/onionj/trust/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReferralRepository(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	referralRepo := NewReferralRepository(redis)
+
+	// the referrer takes referees up to the cap
+	for _, refereeID := range []int64{2, 3} {
+		added, err := referralRepo.Add(context.Background(), 1, refereeID, 2)
+		assert.NoError(t, err)
+		assert.True(t, added)
+	}
+	added, err := referralRepo.Add(context.Background(), 1, 4, 2)
+	assert.NoError(t, err)
+	assert.False(t, added)
+
+	// a referee already attributed stays attributed at the cap
+	added, err = referralRepo.Add(context.Background(), 1, 2, 2)
+	assert.NoError(t, err)
+	assert.True(t, added)
+
+	count, err := referralRepo.Count(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	refereeIDs, err := referralRepo.List(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int64{2, 3}, refereeIDs)
+}
+
+func TestReferralRepositoryConcurrent(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	referralRepo := NewReferralRepository(redis)
+
+	// concurrent referees never push the referrer over the cap
+	var wg sync.WaitGroup
+	var attributed atomic.Int64
+	for refereeID := int64(2); refereeID < 22; refereeID++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			added, err := referralRepo.Add(context.Background(), 1, refereeID, 5)
+			assert.NoError(t, err)
+			if added {
+				attributed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(5), attributed.Load())
+	count, err := referralRepo.Count(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), count)
+}
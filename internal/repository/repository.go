@@ -5,24 +5,120 @@ import (
 	"errors"
 
 	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/tournament"
 )
 
 var (
 	ErrNotFound = errors.New("entity not found")
+	ErrConflict = errors.New("entity changed by another update")
 )
 
-type CommonBehaviorRepository[T entity.DBModel] interface {
-	Save(ctx context.Context, model T) error
+type CommonBehaviorRepository[T entity.DBModel, K entity.Key] interface {
+	Save(ctx context.Context, model *T) error
 	Keys(ctx context.Context, pattern string) []string
 	Scan(ctx context.Context, pattern string, limit int) ([]T, error)
 	Get(ctx context.Context, key string) (T, error)
+	GetByID(ctx context.Context, id K) (T, error)
+	GetMany(ctx context.Context, ids []K) ([]T, error)
+	Delete(ctx context.Context, id K) error
+	Exists(ctx context.Context, id K) (bool, error)
+	Update(ctx context.Context, id K, fn func(model *T) error) (T, error)
 	// add more common behavior
 }
 
 type UserRepository interface {
-	CommonBehaviorRepository[entity.User]
+	CommonBehaviorRepository[entity.User, int64]
+	IndexRange(ctx context.Context, offset int64, count int64) ([]int64, error)
+	IndexSize(ctx context.Context) (int64, error)
+	BuildIndex(ctx context.Context) (int, error)
+	MigrateKeys(ctx context.Context) (int, error)
 }
 
 type GameRepository interface {
-	CommonBehaviorRepository[entity.Game]
+	CommonBehaviorRepository[entity.Game, uint]
+	ActiveGame(ctx context.Context, userID int64) (entity.Game, error)
+	PlayerGames(ctx context.Context, userID int64, limit int64) ([]entity.Game, error)
+	Migrate(ctx context.Context) (int, error)
+}
+
+type GroupGameRepository interface {
+	CommonBehaviorRepository[entity.GroupGame, uint]
+	SetActive(ctx context.Context, game entity.GroupGame) error
+	ActiveID(ctx context.Context, userID int64) (uint, error)
+	ClearActive(ctx context.Context, game entity.GroupGame) error
+}
+
+type TournamentRepository interface {
+	CommonBehaviorRepository[entity.Tournament, uint]
+	AddPlayer(ctx context.Context, tournamentID uint, userID int64) (bool, error)
+	RemovePlayer(ctx context.Context, tournamentID uint, userID int64) error
+	Players(ctx context.Context, tournamentID uint) ([]int64, error)
+	SaveMatch(ctx context.Context, tournamentID uint, match entity.TournamentMatch) error
+	Matches(ctx context.Context, tournamentID uint) ([]entity.TournamentMatch, error)
+	DecideMatch(ctx context.Context, tournamentID uint, match entity.TournamentMatch) error
+	AddResult(ctx context.Context, tournamentID uint, userID int64, win bool, coins int) error
+	Standings(ctx context.Context, tournamentID uint) ([]tournament.Standing, error)
+	ClaimPayout(ctx context.Context, tournamentID uint, payout string) (bool, error)
+	ReleasePayout(ctx context.Context, tournamentID uint, payout string) error
+}
+
+type SeasonRepository interface {
+	CommonBehaviorRepository[entity.Season, uint]
+	CurrentID(ctx context.Context) (uint, error)
+	SetCurrent(ctx context.Context, seasonID uint) error
+	AddPoints(ctx context.Context, seasonID uint, userID int64, points int) error
+	Top(ctx context.Context, seasonID uint, limit int64) ([]entity.SeasonScore, error)
+	Score(ctx context.Context, seasonID uint, userID int64) (int, int, error)
+	Archive(ctx context.Context, seasonID uint, ranking []entity.SeasonRank) error
+	Ranking(ctx context.Context, seasonID uint) ([]entity.SeasonRank, error)
+	ClaimPayout(ctx context.Context, seasonID uint, place int) (bool, error)
+	ReleasePayout(ctx context.Context, seasonID uint, place int) error
+}
+
+type PaymentRepository interface {
+	Claim(ctx context.Context, purchase entity.Purchase) (bool, error)
+	Release(ctx context.Context, chargeID string) error
+	Get(ctx context.Context, chargeID string) (entity.Purchase, error)
+	Save(ctx context.Context, purchase entity.Purchase) error
+}
+
+type InventoryRepository interface {
+	Add(ctx context.Context, userID int64, kind string, itemID string) error
+	Has(ctx context.Context, userID int64, kind string, itemID string) (bool, error)
+	List(ctx context.Context, userID int64, kind string) ([]string, error)
+}
+
+type LedgerRepository interface {
+	Append(ctx context.Context, entry entity.LedgerEntry) error
+	List(ctx context.Context, userID int64, limit int) ([]entity.LedgerEntry, error)
+}
+
+type AchievementRepository interface {
+	GetProgress(ctx context.Context, userID int64) (entity.AchievementProgress, error)
+	SaveProgress(ctx context.Context, userID int64, progress entity.AchievementProgress) error
+	Unlocked(ctx context.Context, userID int64) ([]string, error)
+	Unlock(ctx context.Context, userID int64, achievementID string) (bool, error)
+}
+
+type ReferralRepository interface {
+	Add(ctx context.Context, referrerID int64, refereeID int64, max int) (bool, error)
+	Count(ctx context.Context, referrerID int64) (int64, error)
+	List(ctx context.Context, referrerID int64) ([]int64, error)
+}
+
+type AuditRepository interface {
+	Append(ctx context.Context, entry entity.AuditEntry) error
+	List(ctx context.Context, limit int) ([]entity.AuditEntry, error)
+}
+
+type BroadcastRepository interface {
+	CommonBehaviorRepository[entity.Broadcast, uint]
+}
+
+type CollusionRepository interface {
+	CommonBehaviorRepository[entity.PairStats, entity.PairID]
+	GetPair(ctx context.Context, userID int64, otherID int64) (entity.PairStats, error)
+	SaveRisk(ctx context.Context, stats entity.PairStats) error
+	TopPairs(ctx context.Context, limit int) ([]entity.PairStats, error)
+	UserRisk(ctx context.Context, userID int64) (float64, error)
 }
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/onionj/trust/internal/entity"
+)
+
+// ConflictRetries is the number of attempts of RetryConflicts
+const ConflictRetries = 5
+
+// RetryConflicts runs fn again while it returns ErrConflict, at most attempts times with a growing pause.
+// fn must read the models it saves again on every attempt.
+func RetryConflicts(ctx context.Context, attempts int, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		err = fn()
+		if !errors.Is(err, ErrConflict) || attempt == attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+	return err
+}
+
+// UpdateRetry is Update that is attempted again on ErrConflict, fn may run more than once
+func UpdateRetry[T entity.DBModel, K entity.Key](ctx context.Context, repo CommonBehaviorRepository[T, K], id K, fn func(model *T) error) (T, error) {
+	var model T
+	err := RetryConflicts(ctx, ConflictRetries, func() error {
+		var err error
+		model, err = repo.Update(ctx, id, fn)
+		return err
+	})
+	return model, err
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryConflicts(t *testing.T) {
+	ctx := context.Background()
+
+	calls := 0
+	err := RetryConflicts(ctx, 3, func() error {
+		calls++
+		if calls < 3 {
+			return ErrConflict
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	// the conflict is returned when every attempt conflicts
+	calls = 0
+	err = RetryConflicts(ctx, 2, func() error {
+		calls++
+		return ErrConflict
+	})
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Equal(t, 2, calls)
+
+	// other errors are not retried
+	errStop := errors.New("stop")
+	calls = 0
+	err = RetryConflicts(ctx, 3, func() error {
+		calls++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, calls)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = RetryConflicts(canceled, 3, func() error { return ErrConflict })
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/pkg/jsonhelper"
+)
+
+var _ SeasonRepository = (*seasonRepository)(nil) // implement check
+
+const (
+	currentSeasonKey = "trust:season:current"   // id of the active season
+	seasonPointsKey  = "trust:season%d:points"  // user id -> season points, dropped when archived
+	seasonRankingKey = "trust:season%d:ranking" // final ranking json, best first
+	seasonPayoutKey  = "trust:season%d:payouts" // set of the places already paid
+)
+
+type seasonRepository struct {
+	redis *redis.Client
+	CommonBehaviorRepository[entity.Season, uint]
+}
+
+func NewSeasonRepository(redis *redis.Client) SeasonRepository {
+	return &seasonRepository{
+		redis:                    redis,
+		CommonBehaviorRepository: NewCommonBehavior[entity.Season, uint](redis),
+	}
+}
+
+// CurrentID returns the active season, 0 when no season was started yet
+func (s seasonRepository) CurrentID(ctx context.Context) (uint, error) {
+	id, err := s.redis.Get(ctx, currentSeasonKey).Uint64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return uint(id), err
+}
+
+func (s seasonRepository) SetCurrent(ctx context.Context, seasonID uint) error {
+	return s.redis.Set(ctx, currentSeasonKey, seasonID, 0).Err()
+}
+
+func (s seasonRepository) AddPoints(ctx context.Context, seasonID uint, userID int64, points int) error {
+	return s.redis.ZIncrBy(ctx, fmt.Sprintf(seasonPointsKey, seasonID), float64(points), fmt.Sprint(userID)).Err()
+}
+
+// Top returns the best scores of the live season
+func (s seasonRepository) Top(ctx context.Context, seasonID uint, limit int64) ([]entity.SeasonScore, error) {
+	raw, err := s.redis.ZRevRangeWithScores(ctx, fmt.Sprintf(seasonPointsKey, seasonID), 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read season points: %v", err)
+	}
+
+	scores := make([]entity.SeasonScore, 0, len(raw))
+	for _, item := range raw {
+		userID, err := strconv.ParseInt(fmt.Sprint(item.Member), 10, 64)
+		if err != nil {
+			continue
+		}
+		scores = append(scores, entity.SeasonScore{UserID: userID, Points: int(item.Score)})
+	}
+	return scores, nil
+}
+
+// Score returns the place (1 is the best) and points of the user, place 0 when the user has no points
+func (s seasonRepository) Score(ctx context.Context, seasonID uint, userID int64) (int, int, error) {
+	key := fmt.Sprintf(seasonPointsKey, seasonID)
+
+	pipe := s.redis.Pipeline()
+	rankCmd := pipe.ZRevRank(ctx, key, fmt.Sprint(userID))
+	scoreCmd := pipe.ZScore(ctx, key, fmt.Sprint(userID))
+	if _, err := pipe.Exec(ctx); errors.Is(err, redis.Nil) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	return int(rankCmd.Val()) + 1, int(scoreCmd.Val()), nil
+}
+
+// Archive stores the final ranking and drops the live points of the season
+func (s seasonRepository) Archive(ctx context.Context, seasonID uint, ranking []entity.SeasonRank) error {
+	pipe := s.redis.TxPipeline()
+	pipe.Del(ctx, fmt.Sprintf(seasonRankingKey, seasonID))
+	for _, rank := range ranking {
+		pipe.RPush(ctx, fmt.Sprintf(seasonRankingKey, seasonID), jsonhelper.Encode(rank))
+	}
+	pipe.Del(ctx, fmt.Sprintf(seasonPointsKey, seasonID))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Ranking returns the archived ranking of a season, best first
+func (s seasonRepository) Ranking(ctx context.Context, seasonID uint) ([]entity.SeasonRank, error) {
+	raw, err := s.redis.LRange(ctx, fmt.Sprintf(seasonRankingKey, seasonID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read season ranking: %v", err)
+	}
+
+	ranking := make([]entity.SeasonRank, len(raw))
+	for i, item := range raw {
+		ranking[i] = jsonhelper.Decode[entity.SeasonRank]([]byte(item))
+	}
+	return ranking, nil
+}
+
+// ClaimPayout marks the place of the season as paid, false when it already is
+func (s seasonRepository) ClaimPayout(ctx context.Context, seasonID uint, place int) (bool, error) {
+	added, err := s.redis.SAdd(ctx, fmt.Sprintf(seasonPayoutKey, seasonID), place).Result()
+	return added == 1, err
+}
+
+// ReleasePayout lets a later rotation pay the place again
+func (s seasonRepository) ReleasePayout(ctx context.Context, seasonID uint, place int) error {
+	return s.redis.SRem(ctx, fmt.Sprintf(seasonPayoutKey, seasonID), place).Err()
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/onionj/trust/config"
+	"github.com/onionj/trust/db"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeasonRepository(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	seasonRepo := NewSeasonRepository(redis)
+
+	currentID, err := seasonRepo.CurrentID(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), currentID)
+
+	season := entity.NewSeason(1, time.Now(), time.Hour)
+	assert.NoError(t, seasonRepo.Save(context.Background(), &season))
+	assert.NoError(t, seasonRepo.SetCurrent(context.Background(), season.Id))
+	currentID, err = seasonRepo.CurrentID(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), currentID)
+
+	assert.NoError(t, seasonRepo.AddPoints(context.Background(), 1, 10, 50))
+	assert.NoError(t, seasonRepo.AddPoints(context.Background(), 1, 11, 80))
+	assert.NoError(t, seasonRepo.AddPoints(context.Background(), 1, 10, 40))
+
+	top, err := seasonRepo.Top(context.Background(), 1, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.SeasonScore{{UserID: 10, Points: 90}, {UserID: 11, Points: 80}}, top)
+
+	place, points, err := seasonRepo.Score(context.Background(), 1, 11)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, place)
+	assert.Equal(t, 80, points)
+
+	place, _, err = seasonRepo.Score(context.Background(), 1, 12)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, place)
+
+	ranking := []entity.SeasonRank{{Place: 1, UserID: 10, Points: 90, Reward: 500}, {Place: 2, UserID: 11, Points: 80}}
+	assert.NoError(t, seasonRepo.Archive(context.Background(), 1, ranking))
+
+	archived, err := seasonRepo.Ranking(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, ranking, archived)
+
+	top, err = seasonRepo.Top(context.Background(), 1, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, top)
+
+	claimed, err := seasonRepo.ClaimPayout(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = seasonRepo.ClaimPayout(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	assert.NoError(t, seasonRepo.ReleasePayout(context.Background(), 1, 1))
+	claimed, err = seasonRepo.ClaimPayout(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/tournament"
+	"github.com/onionj/trust/pkg/jsonhelper"
+)
+
+var _ TournamentRepository = (*tournamentRepository)(nil) // implement check
+
+const (
+	tournamentPlayersKey = "trust:tournament%d:players" // user id -> registration time, the seed order
+	tournamentMatchesKey = "trust:tournament%d:matches" // 'round:index' -> match json
+	tournamentWinsKey    = "trust:tournament%d:wins"    // user id -> won matches
+	tournamentCoinsKey   = "trust:tournament%d:coins"   // user id -> coins earned in the tournament games
+	tournamentPayoutKey  = "trust:tournament%d:payouts" // set of the prizes and refunds already paid
+)
+
+type tournamentRepository struct {
+	redis *redis.Client
+	CommonBehaviorRepository[entity.Tournament, uint]
+}
+
+func NewTournamentRepository(redis *redis.Client) TournamentRepository {
+	return &tournamentRepository{
+		redis:                    redis,
+		CommonBehaviorRepository: NewCommonBehavior[entity.Tournament, uint](redis),
+	}
+}
+
+// AddPlayer registers the user, false when the user was already registered
+func (t tournamentRepository) AddPlayer(ctx context.Context, tournamentID uint, userID int64) (bool, error) {
+	added, err := t.redis.ZAddNX(ctx, fmt.Sprintf(tournamentPlayersKey, tournamentID), redis.Z{
+		Score:  float64(time.Now().UnixMilli()),
+		Member: userID,
+	}).Result()
+	return added == 1, err
+}
+
+// RemovePlayer unregisters the user
+func (t tournamentRepository) RemovePlayer(ctx context.Context, tournamentID uint, userID int64) error {
+	return t.redis.ZRem(ctx, fmt.Sprintf(tournamentPlayersKey, tournamentID), userID).Err()
+}
+
+// Players returns the registered users in registration order
+func (t tournamentRepository) Players(ctx context.Context, tournamentID uint) ([]int64, error) {
+	raw, err := t.redis.ZRange(ctx, fmt.Sprintf(tournamentPlayersKey, tournamentID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tournament players: %v", err)
+	}
+	return parseIDs(raw), nil
+}
+
+func (t tournamentRepository) SaveMatch(ctx context.Context, tournamentID uint, match entity.TournamentMatch) error {
+	return t.redis.HSet(ctx, fmt.Sprintf(tournamentMatchesKey, tournamentID), match.Field(), jsonhelper.Encode(match)).Err()
+}
+
+// Matches returns every match of the tournament ordered by round and index
+func (t tournamentRepository) Matches(ctx context.Context, tournamentID uint) ([]entity.TournamentMatch, error) {
+	raw, err := t.redis.HVals(ctx, fmt.Sprintf(tournamentMatchesKey, tournamentID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tournament matches: %v", err)
+	}
+
+	matches := make([]entity.TournamentMatch, len(raw))
+	for i, item := range raw {
+		matches[i] = jsonhelper.Decode[entity.TournamentMatch]([]byte(item))
+	}
+	slices.SortFunc(matches, func(a, b entity.TournamentMatch) int {
+		if a.Round != b.Round {
+			return a.Round - b.Round
+		}
+		return a.Index - b.Index
+	})
+	return matches, nil
+}
+
+// DecideMatch saves the decided match and adds its result to the standings of both players in one transaction
+func (t tournamentRepository) DecideMatch(ctx context.Context, tournamentID uint, match entity.TournamentMatch) error {
+	pipe := t.redis.TxPipeline()
+	for _, result := range []struct {
+		userID int64
+		coins  int
+	}{{match.P1ID, match.P1Coins}, {match.P2ID, match.P2Coins}} {
+		wins := 0
+		if result.userID == match.WinnerID {
+			wins = 1
+		}
+		pipe.ZIncrBy(ctx, fmt.Sprintf(tournamentWinsKey, tournamentID), float64(wins), fmt.Sprint(result.userID))
+		pipe.ZIncrBy(ctx, fmt.Sprintf(tournamentCoinsKey, tournamentID), float64(result.coins), fmt.Sprint(result.userID))
+	}
+	pipe.HSet(ctx, fmt.Sprintf(tournamentMatchesKey, tournamentID), match.Field(), jsonhelper.Encode(match))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ClaimPayout marks the payout of the tournament as paid, false when it already is
+func (t tournamentRepository) ClaimPayout(ctx context.Context, tournamentID uint, payout string) (bool, error) {
+	added, err := t.redis.SAdd(ctx, fmt.Sprintf(tournamentPayoutKey, tournamentID), payout).Result()
+	return added == 1, err
+}
+
+// ReleasePayout lets a later run pay the payout again
+func (t tournamentRepository) ReleasePayout(ctx context.Context, tournamentID uint, payout string) error {
+	return t.redis.SRem(ctx, fmt.Sprintf(tournamentPayoutKey, tournamentID), payout).Err()
+}
+
+// AddResult adds a played match to the standing of the user
+func (t tournamentRepository) AddResult(ctx context.Context, tournamentID uint, userID int64, win bool, coins int) error {
+	wins := 0
+	if win {
+		wins = 1
+	}
+	pipe := t.redis.TxPipeline()
+	pipe.ZIncrBy(ctx, fmt.Sprintf(tournamentWinsKey, tournamentID), float64(wins), fmt.Sprint(userID))
+	pipe.ZIncrBy(ctx, fmt.Sprintf(tournamentCoinsKey, tournamentID), float64(coins), fmt.Sprint(userID))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Standings returns the standing of every registered user, in registration order
+func (t tournamentRepository) Standings(ctx context.Context, tournamentID uint) ([]tournament.Standing, error) {
+	players, err := t.Players(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := t.redis.Pipeline()
+	winsCmds := make([]*redis.FloatCmd, len(players))
+	coinsCmds := make([]*redis.FloatCmd, len(players))
+	for i, playerID := range players {
+		winsCmds[i] = pipe.ZScore(ctx, fmt.Sprintf(tournamentWinsKey, tournamentID), fmt.Sprint(playerID))
+		coinsCmds[i] = pipe.ZScore(ctx, fmt.Sprintf(tournamentCoinsKey, tournamentID), fmt.Sprint(playerID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read tournament standings: %v", err)
+	}
+
+	standings := make([]tournament.Standing, len(players))
+	for i, playerID := range players {
+		standings[i] = tournament.Standing{
+			UserID: playerID,
+			Wins:   int(winsCmds[i].Val()),
+			Coins:  int(coinsCmds[i].Val()),
+		}
+	}
+	return standings, nil
+}
+
+func parseIDs(raw []string) []int64 {
+	ids := make([]int64, 0, len(raw))
+	for _, item := range raw {
+		if id, err := strconv.ParseInt(item, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
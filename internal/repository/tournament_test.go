@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/onionj/trust/config"
+	"github.com/onionj/trust/db"
+	"github.com/onionj/trust/internal/entity"
+	"github.com/onionj/trust/internal/tournament"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTournamentRepository(t *testing.T) {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	redis := db.Init(cfg)
+	err := redis.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+
+	tournamentRepo := NewTournamentRepository(redis)
+	ctx := context.Background()
+
+	for _, userID := range []int64{10, 11} {
+		added, err := tournamentRepo.AddPlayer(ctx, 1, userID)
+		assert.NoError(t, err)
+		assert.True(t, added)
+	}
+
+	// the decided match and the standings are written together
+	match := entity.TournamentMatch{Round: 1, P1ID: 10, P2ID: 11, GameID: 5, WinnerID: 11, P1Coins: 20, P2Coins: 70}
+	assert.NoError(t, tournamentRepo.DecideMatch(ctx, 1, match))
+
+	matches, err := tournamentRepo.Matches(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.TournamentMatch{match}, matches)
+
+	standings, err := tournamentRepo.Standings(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []tournament.Standing{{UserID: 10, Wins: 0, Coins: 20}, {UserID: 11, Wins: 1, Coins: 70}}, standings)
+
+	claimed, err := tournamentRepo.ClaimPayout(ctx, 1, "place 1")
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = tournamentRepo.ClaimPayout(ctx, 1, "place 1")
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	assert.NoError(t, tournamentRepo.ReleasePayout(ctx, 1, "place 1"))
+	claimed, err = tournamentRepo.ClaimPayout(ctx, 1, "place 1")
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+}
@@ -21,7 +21,7 @@ func TestUserRepository(t *testing.T) {
 	userRepo := NewUserRepository(redis)
 
 	user := entity.NewUser(10, "Onion", 10000)
-	err = userRepo.Save(context.Background(), user)
+	err = userRepo.Save(context.Background(), &user)
 	assert.NoError(t, err)
 
 	new_user, err := userRepo.Get(context.Background(), "user:10")
@@ -38,11 +38,64 @@ func TestUserRepository(t *testing.T) {
 	assert.Equal(t, user.Balance, users[0].Balance)
 
 	user2 := entity.NewUser(11, "Sarah", 10000)
-	err = userRepo.Save(context.Background(), user2)
+	err = userRepo.Save(context.Background(), &user2)
 	assert.NoError(t, err)
 
+	// data of the users under the legacy user:<id>:* keys doesn't break the scans
+	assert.NoError(t, redis.LPush(context.Background(), "user:10:ledger", "{}").Err())
+	assert.NoError(t, redis.SAdd(context.Background(), "user:10:referrals", 11).Err())
+
 	users2, err := userRepo.Scan(context.Background(), "user:1*", 0)
 	assert.NoError(t, err)
 	assert.Len(t, users2, 2)
 
+	assert.NoError(t, redis.Del(context.Background(), usersIndexKey).Err())
+	added, err := userRepo.BuildIndex(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	// the legacy keys move to the new keys, data saved under the new keys is kept
+	assert.NoError(t, redis.LPush(context.Background(), "trust:user10:ledger", "{\"new\":1}").Err())
+	assert.NoError(t, redis.SAdd(context.Background(), "user:10:inventory:avatar", "a1").Err())
+	assert.NoError(t, redis.HSet(context.Background(), "user:10:achievements:progress", "games", 3).Err())
+	moved, err := userRepo.MigrateKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 4, moved)
+
+	ledger, err := redis.LRange(context.Background(), "trust:user10:ledger", 0, -1).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"{\"new\":1}", "{}"}, ledger)
+	isMember, err := redis.SIsMember(context.Background(), "trust:user10:referrals", 11).Result()
+	assert.NoError(t, err)
+	assert.True(t, isMember)
+	isMember, err = redis.SIsMember(context.Background(), "trust:user10:inventory:avatar", "a1").Result()
+	assert.NoError(t, err)
+	assert.True(t, isMember)
+	games, err := redis.HGet(context.Background(), "trust:user10:achievements:progress", "games").Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, games)
+
+	// the users are left alone
+	exists, err := userRepo.Exists(context.Background(), 10)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	moved, err = userRepo.MigrateKeys(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, moved)
+}
+
+func TestMigratedUserKey(t *testing.T) {
+	for key, want := range map[string]string{
+		"user:10:ledger":                "trust:user10:ledger",
+		"user:10:achievements:progress": "trust:user10:achievements:progress",
+		"user:10:inventory:avatar":      "trust:user10:inventory:avatar",
+		"user:10":                       "",
+		"user:10:other":                 "",
+		"user:ab:ledger":                "",
+		"game:p1:p2:3":                  "",
+	} {
+		newKey, ok := migratedUserKey(key)
+		assert.Equal(t, want != "", ok, key)
+		assert.Equal(t, want, newKey, key)
+	}
 }
@@ -1,19 +1,152 @@
 package repository
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/onionj/trust/internal/entity"
 	"github.com/redis/go-redis/v9"
 )
 
 var _ UserRepository = (*userRepository)(nil) // implement check
 
+const (
+	usersIndexKey  = "trust:users"   // sorted set of all user ids scored by their creation time
+	legacyUserKeys = "user:*:*"      // user data saved before the trust:user<id>:* keys, user:<id>:<name>
+	userDataKey    = "trust:user%d:" // prefix of the user data keys, followed by the name
+)
+
+// legacyUserData are the names of the user data keys that moved from user:<id>:<name>
+var legacyUserData = []string{"ledger", "referrals", "achievements", "achievements:progress", "inventory:"}
+
+// mergeKeyScript moves KEYS[1] into KEYS[2], the content of an existing KEYS[2] is kept.
+// Sets are merged, the entries of a list are appended and the fields of a hash are added when missing.
+// Returns 1 when the key was moved.
+var mergeKeyScript = redis.NewScript(`
+local kind = redis.call("TYPE", KEYS[1]).ok
+if kind == "set" then
+	redis.call("SUNIONSTORE", KEYS[2], KEYS[2], KEYS[1])
+elseif kind == "list" then
+	for _, item in ipairs(redis.call("LRANGE", KEYS[1], 0, -1)) do
+		redis.call("RPUSH", KEYS[2], item)
+	end
+elseif kind == "hash" then
+	local hash = redis.call("HGETALL", KEYS[1])
+	for idx = 1, #hash, 2 do
+		redis.call("HSETNX", KEYS[2], hash[idx], hash[idx + 1])
+	end
+else
+	return 0
+end
+redis.call("DEL", KEYS[1])
+return 1
+`)
+
 type userRepository struct {
 	redis *redis.Client
-	CommonBehaviorRepository[entity.User]
+	CommonBehaviorRepository[entity.User, int64]
 }
 
 func NewUserRepository(redis *redis.Client) UserRepository {
 	return &userRepository{
-		CommonBehaviorRepository: NewCommonBehavior[entity.User](redis),
+		redis:                    redis,
+		CommonBehaviorRepository: NewCommonBehavior[entity.User, int64](redis),
+	}
+}
+
+// Save stores the user and keeps it in the users index
+func (u userRepository) Save(ctx context.Context, user *entity.User) error {
+	if err := u.CommonBehaviorRepository.Save(ctx, user); err != nil {
+		return err
+	}
+	return u.redis.ZAddNX(ctx, usersIndexKey, redis.Z{Score: float64(user.Created), Member: user.Id}).Err()
+}
+
+// IndexRange returns count user ids of the index starting at offset, oldest users first
+func (u userRepository) IndexRange(ctx context.Context, offset int64, count int64) ([]int64, error) {
+	members, err := u.redis.ZRange(ctx, usersIndexKey, offset, offset+count-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users index: %v", err)
+	}
+
+	ids := make([]int64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user id %s in index: %v", member, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// IndexSize returns the number of indexed users
+func (u userRepository) IndexSize(ctx context.Context) (int64, error) {
+	return u.redis.ZCard(ctx, usersIndexKey).Result()
+}
+
+// BuildIndex adds the users saved before the index existed, it returns the number of added users
+func (u userRepository) BuildIndex(ctx context.Context) (int, error) {
+	users, err := u.Scan(ctx, "user:*", 0)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, user := range users {
+		if user.Id == 0 {
+			continue
+		}
+		n, err := u.redis.ZAddNX(ctx, usersIndexKey, redis.Z{Score: float64(user.Created), Member: user.Id}).Result()
+		if err != nil {
+			return added, err
+		}
+		added += int(n)
+	}
+	return added, nil
+}
+
+// MigrateKeys moves the user data saved under the legacy user:<id>:<name> keys to trust:user<id>:<name>,
+// it returns the number of moved keys. Data saved under the new key meanwhile is kept.
+func (u userRepository) MigrateKeys(ctx context.Context) (int, error) {
+	moves := map[string]string{}
+	iter := u.redis.Scan(ctx, 0, legacyUserKeys, 1000).Iterator()
+	for iter.Next(ctx) {
+		if newKey, ok := migratedUserKey(iter.Val()); ok {
+			moves[iter.Val()] = newKey
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return 0, fmt.Errorf("failed to scan legacy user keys: %v", err)
+	}
+
+	moved := 0
+	for oldKey, newKey := range moves {
+		n, err := mergeKeyScript.Run(ctx, u.redis, []string{oldKey, newKey}).Int()
+		if err != nil {
+			return moved, fmt.Errorf("failed to move %s: %v", oldKey, err)
+		}
+		moved += n
+	}
+	return moved, nil
+}
+
+// migratedUserKey returns the new key of a legacy user data key, false for any other key
+func migratedUserKey(key string) (string, bool) {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) != 3 || parts[0] != "user" {
+		return "", false
+	}
+	userID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", false
+	}
+	for _, name := range legacyUserData {
+		if parts[2] == name || (strings.HasSuffix(name, ":") && strings.HasPrefix(parts[2], name)) {
+			return fmt.Sprintf(userDataKey, userID) + parts[2], true
+		}
 	}
+	return "", false
 }
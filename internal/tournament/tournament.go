@@ -0,0 +1,116 @@
+// Package tournament pairs tournament players and splits the prize pool.
+package tournament
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// Bye is the opponent of a player that advances without playing
+const Bye int64 = 0
+
+// Pairing is a match between two players, P2ID is Bye when P1ID advances without playing
+type Pairing struct {
+	P1ID int64
+	P2ID int64
+}
+
+// Standing is the score of a player, players are ranked by wins then by coins earned
+type Standing struct {
+	UserID int64
+	Wins   int
+	Coins  int
+}
+
+// Rounds returns the rounds needed to find a winner between the players, for both formats
+func Rounds(players int) int {
+	if players < 2 {
+		return 0
+	}
+	return bits.Len(uint(players - 1))
+}
+
+// PairSingleElimination pairs the players in seed order.
+// The best seed without a bye so far gets the bye of an odd round.
+func PairSingleElimination(players []int64, hadBye func(userID int64) bool) []Pairing {
+	pairings := []Pairing{}
+	if len(players)%2 == 1 {
+		byeIdx := 0
+		for idx, playerID := range players {
+			if !hadBye(playerID) {
+				byeIdx = idx
+				break
+			}
+		}
+		pairings = append(pairings, Pairing{P1ID: players[byeIdx], P2ID: Bye})
+		players = slices.Delete(slices.Clone(players), byeIdx, byeIdx+1)
+	}
+	// best remaining seed against the worst one
+	for left, right := 0, len(players)-1; left < right; left, right = left+1, right-1 {
+		pairings = append(pairings, Pairing{P1ID: players[left], P2ID: players[right]})
+	}
+	return pairings
+}
+
+// Rank sorts the standings by wins then coins, best first
+func Rank(standings []Standing) []Standing {
+	ranked := slices.Clone(standings)
+	slices.SortStableFunc(ranked, func(a, b Standing) int {
+		if a.Wins != b.Wins {
+			return b.Wins - a.Wins
+		}
+		return b.Coins - a.Coins
+	})
+	return ranked
+}
+
+// PairSwiss pairs players with close scores who didn't play each other yet.
+// The lowest ranked player without a bye so far gets the bye of an odd round.
+func PairSwiss(standings []Standing, played func(a, b int64) bool, hadBye func(userID int64) bool) []Pairing {
+	ranked := Rank(standings)
+	pairings := []Pairing{}
+
+	if len(ranked)%2 == 1 {
+		byeIdx := len(ranked) - 1
+		for idx := len(ranked) - 1; idx >= 0; idx-- {
+			if !hadBye(ranked[idx].UserID) {
+				byeIdx = idx
+				break
+			}
+		}
+		pairings = append(pairings, Pairing{P1ID: ranked[byeIdx].UserID, P2ID: Bye})
+		ranked = slices.Delete(ranked, byeIdx, byeIdx+1)
+	}
+
+	for len(ranked) > 0 {
+		opponent := 1
+		for idx := 1; idx < len(ranked); idx++ {
+			if !played(ranked[0].UserID, ranked[idx].UserID) {
+				opponent = idx
+				break
+			}
+		}
+		pairings = append(pairings, Pairing{P1ID: ranked[0].UserID, P2ID: ranked[opponent].UserID})
+		ranked = slices.Delete(ranked, opponent, opponent+1)
+		ranked = ranked[1:]
+	}
+	return pairings
+}
+
+// PrizeShares are the parts of the prize pool of the first places
+var PrizeShares = []int{50, 30, 20}
+
+// Prizes splits the pool between the first places, the shares of missing places go to the winner
+func Prizes(pool int, players int) []int {
+	places := min(players, len(PrizeShares))
+	prizes := make([]int, places)
+	paid := 0
+	for idx := 1; idx < places; idx++ {
+		prizes[idx] = pool * PrizeShares[idx] / 100
+		paid += prizes[idx]
+	}
+	if places > 0 {
+		prizes[0] = pool - paid
+	}
+	return prizes
+}
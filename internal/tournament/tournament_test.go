@@ -0,0 +1,41 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRounds(t *testing.T) {
+	assert.Equal(t, 0, Rounds(1))
+	assert.Equal(t, 1, Rounds(2))
+	assert.Equal(t, 2, Rounds(3))
+	assert.Equal(t, 2, Rounds(4))
+	assert.Equal(t, 3, Rounds(5))
+	assert.Equal(t, 3, Rounds(8))
+}
+
+func TestPairSingleElimination(t *testing.T) {
+	noBye := func(userID int64) bool { return false }
+	assert.Equal(t, []Pairing{{1, 4}, {2, 3}}, PairSingleElimination([]int64{1, 2, 3, 4}, noBye))
+	assert.Equal(t, []Pairing{{1, Bye}, {2, 5}, {3, 4}}, PairSingleElimination([]int64{1, 2, 3, 4, 5}, noBye))
+
+	// 1 already had a bye, so the next seed gets it
+	hadBye := func(userID int64) bool { return userID == 1 }
+	assert.Equal(t, []Pairing{{2, Bye}, {1, 3}}, PairSingleElimination([]int64{1, 2, 3}, hadBye))
+}
+
+func TestPairSwiss(t *testing.T) {
+	standings := []Standing{{1, 1, 300}, {2, 0, 100}, {3, 1, 200}, {4, 0, 0}, {5, 2, 0}}
+	played := func(a, b int64) bool { return (a == 5 && b == 1) || (a == 1 && b == 5) }
+	hadBye := func(userID int64) bool { return userID == 4 }
+
+	// 5 already played 1, so 5 meets 3. 4 had a bye, so 2 gets it
+	assert.Equal(t, []Pairing{{2, Bye}, {5, 3}, {1, 4}}, PairSwiss(standings, played, hadBye))
+}
+
+func TestPrizes(t *testing.T) {
+	assert.Equal(t, []int{500, 300, 200}, Prizes(1000, 8))
+	assert.Equal(t, []int{700, 300}, Prizes(1000, 2))
+	assert.Equal(t, []int{51, 30, 20}, Prizes(101, 3))
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Limiter = (*TokenBucket)(nil) // implement check
+
+// bucketScript refills the bucket for the elapsed time and takes a token.
+// KEYS[1] bucket hash, ARGV capacity, refill interval ms, now ms.
+// Returns {allowed, remaining, retry after ms, reset ms}.
+var bucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(now - ts, 0) / interval)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) * interval)
+end
+local reset = math.ceil((capacity - tokens) * interval)
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", ARGV[3])
+redis.call("PEXPIRE", KEYS[1], reset + interval)
+return {allowed, math.floor(tokens), retry, reset}
+`)
+
+// TokenBucket allows bursts of Capacity calls and refills a token every Interval
+type TokenBucket struct {
+	rdb      *redis.Client
+	Capacity int
+	Interval time.Duration
+}
+
+func NewTokenBucket(rdb *redis.Client, capacity int, interval time.Duration) *TokenBucket {
+	return &TokenBucket{rdb: rdb, Capacity: capacity, Interval: interval}
+}
+
+func (b *TokenBucket) Take(ctx context.Context, key string) (Result, error) {
+	reply, err := bucketScript.Run(ctx, b.rdb, []string{key},
+		b.Capacity, max(millis(b.Interval), 1), time.Now().UnixMilli()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    reply[0] == 1,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Millisecond,
+		ResetIn:    time.Duration(reply[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// KeyFunc returns the limiter key of a request, an empty key skips the limit
+type KeyFunc func(c echo.Context) string
+
+// KeyByIP limits every client IP separately
+func KeyByIP(format string) KeyFunc {
+	return func(c echo.Context) string {
+		return fmt.Sprintf(format, c.RealIP())
+	}
+}
+
+type MiddlewareConfig struct {
+	Limiter Limiter
+	Key     KeyFunc
+	// Denied writes the response of a limited request, 429 Too Many Requests by default
+	Denied func(c echo.Context, result Result) error
+}
+
+// Middleware limits the requests of every key, requests pass when Redis fails.
+// The remaining tokens and the retry delay are sent in the X-RateLimit-Remaining and Retry-After headers.
+func Middleware(config MiddlewareConfig) echo.MiddlewareFunc {
+	if config.Denied == nil {
+		config.Denied = func(c echo.Context, result Result) error {
+			return echo.NewHTTPError(http.StatusTooManyRequests, "too many requests")
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := config.Key(c)
+			if key == "" {
+				return next(c)
+			}
+
+			result, err := config.Limiter.Take(c.Request().Context(), key)
+			if err != nil {
+				logrus.Error("rate limit error ", err)
+				return next(c)
+			}
+
+			c.Response().Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			if !result.Allowed {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+				return config.Denied(c, result)
+			}
+			return next(c)
+		}
+	}
+}
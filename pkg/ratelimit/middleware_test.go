@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLimiter struct {
+	results map[string]Result
+	err     error
+}
+
+func (f fakeLimiter) Take(ctx context.Context, key string) (Result, error) {
+	return f.results[key], f.err
+}
+
+func serve(config MiddlewareConfig) *httptest.ResponseRecorder {
+	e := echo.New()
+	e.Use(Middleware(config))
+	e.GET("/", func(c echo.Context) error { return c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRealIP, "1.2.3.4")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	limiter := fakeLimiter{results: map[string]Result{
+		"ip:1.2.3.4": {Allowed: false, RetryAfter: 1500 * time.Millisecond},
+		"allowed":    {Allowed: true, Remaining: 7},
+	}}
+
+	rec := serve(MiddlewareConfig{Limiter: limiter, Key: KeyByIP("ip:%s")})
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	rec = serve(MiddlewareConfig{Limiter: limiter, Key: func(c echo.Context) string { return "allowed" }})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "7", rec.Header().Get("X-RateLimit-Remaining"))
+
+	// custom response
+	rec = serve(MiddlewareConfig{Limiter: limiter, Key: KeyByIP("ip:%s"), Denied: func(c echo.Context, result Result) error {
+		return c.String(245, "slow down")
+	}})
+	assert.Equal(t, 245, rec.Code)
+
+	// requests pass when the limiter fails or there is no key
+	rec = serve(MiddlewareConfig{Limiter: fakeLimiter{err: errors.New("down")}, Key: KeyByIP("ip:%s")})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = serve(MiddlewareConfig{Limiter: limiter, Key: func(c echo.Context) string { return "" }})
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
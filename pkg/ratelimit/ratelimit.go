@@ -1,34 +1,35 @@
+// Package ratelimit limits actions with atomic Redis scripts.
+//
+// TokenBucket refills tokens continuously and suits request limits, SlidingWindow counts the
+// events of the last window and suits quotas like games per hour. Both return the remaining
+// tokens and when the next token is available.
 package ratelimit
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
 	"time"
-
-	"github.com/redis/go-redis/v9"
-	"github.com/sirupsen/logrus"
 )
 
-func IsLimited(rdb *redis.Client, key string, Limit int, exp time.Duration) (bool, error) {
-	_, err := rdb.SetNX(context.Background(), key, Limit, exp).Result()
-	if err != nil {
-		logrus.Error("IsLimited error", err)
-		return false, err
-	}
+// Result of a limiter call
+type Result struct {
+	Allowed    bool
+	Remaining  int           // tokens left after the call
+	RetryAfter time.Duration // time until the next token when none is left, 0 otherwise
+	ResetIn    time.Duration // time until every token is back
+	Bonus      bool          // a bonus token was used because the window was full
+	ID         string        // event of the call in a sliding window, used to cancel it
+}
+
+type Limiter interface {
+	Take(ctx context.Context, key string) (Result, error)
+}
 
-	tokens, err := rdb.Get(context.Background(), key).Int()
-	if err != nil {
-		logrus.Error("IsLimited error", err)
-		return false, err
-	}
-	if tokens <= 0 {
-		return true, nil
-	}
-	return false, nil
+func millis(d time.Duration) int64 {
+	return d.Milliseconds()
 }
 
-func BurnToken(rdb *redis.Client, key string) {
-	_, err := rdb.Decr(context.Background(), key).Result()
-	if err != nil {
-		logrus.Error("BurnToken error", err)
-	}
+func eventID(now time.Time) string {
+	return fmt.Sprintf("%d-%d", now.UnixNano(), rand.Int63())
 }
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/onionj/trust/config"
+	"github.com/onionj/trust/db"
+)
+
+func testRedis(t *testing.T) *redis.Client {
+	cfg := config.NewConfig("../../.env")
+	cfg.Redis.DB = 1 // Test DB
+
+	rdb := db.Init(cfg)
+	err := rdb.FlushDB(context.Background()).Err()
+	assert.NoError(t, err)
+	return rdb
+}
+
+func TestTokenBucket(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	bucket := NewTokenBucket(rdb, 2, 200*time.Millisecond)
+
+	result, err := bucket.Take(ctx, "bucket")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+
+	result, err = bucket.Take(ctx, "bucket")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, err = bucket.Take(ctx, "bucket")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+	assert.LessOrEqual(t, result.RetryAfter, 200*time.Millisecond)
+
+	time.Sleep(result.RetryAfter + 20*time.Millisecond)
+	result, err = bucket.Take(ctx, "bucket")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	window := NewSlidingWindow(rdb, 2, 300*time.Millisecond)
+
+	first, err := window.Take(ctx, "window")
+	assert.NoError(t, err)
+	assert.True(t, first.Allowed)
+	assert.NotEmpty(t, first.ID)
+	assert.Equal(t, 1, first.Remaining)
+
+	result, err := window.Take(ctx, "window")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+
+	result, err = window.Take(ctx, "window")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+	assert.LessOrEqual(t, result.RetryAfter, 300*time.Millisecond)
+
+	// a canceled event gives its token back
+	assert.NoError(t, window.Cancel(ctx, "window", first.ID))
+	result, err = window.Peek(ctx, "window")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+
+	time.Sleep(350 * time.Millisecond)
+	result, err = window.Peek(ctx, "window")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Remaining)
+	assert.Zero(t, result.ResetIn)
+}
+
+func TestSlidingWindowBonus(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	window := NewSlidingWindow(rdb, 1, time.Minute)
+
+	result, err := window.TakeOrBonus(ctx, "window", "bonus")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.False(t, result.Bonus)
+
+	result, err = window.TakeOrBonus(ctx, "window", "bonus")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	assert.NoError(t, AddBonusTokens(rdb, "bonus", 2))
+	wallet, err := GetWallet(ctx, window, "window", "bonus")
+	assert.NoError(t, err)
+	assert.Equal(t, Wallet{Tokens: 0, Bonus: 2, RetryAfter: wallet.RetryAfter, ResetIn: wallet.ResetIn}, wallet)
+	assert.False(t, wallet.Empty())
+
+	result, err = window.TakeOrBonus(ctx, "window", "bonus")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.True(t, result.Bonus)
+	assert.Empty(t, result.ID)
+
+	taken, err := TakeBonusTokens(rdb, "bonus", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, taken)
+
+	wallet, err = GetWallet(ctx, window, "window", "bonus")
+	assert.NoError(t, err)
+	assert.True(t, wallet.Empty())
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrReservationPending = errors.New("a reservation is already pending")
+
+// releaseScript gives the token of a pending reservation back to the window or the bonus counter.
+// KEYS[1] reservation hash, KEYS[2] window events zset, KEYS[3] bonus counter.
+// Returns 1 when a reservation was released, 0 when there was none.
+var releaseScript = redis.NewScript(`
+local state = redis.call("HMGET", KEYS[1], "id", "bonus")
+if not state[1] and not state[2] then
+	return 0
+end
+redis.call("DEL", KEYS[1])
+if state[2] == "1" then
+	redis.call("INCR", KEYS[3])
+else
+	redis.call("ZREM", KEYS[2], state[1])
+end
+return 1
+`)
+
+// Reservations hold tokens taken for an action that may not happen.
+// Reserve takes a token of the window or a bonus token, Commit keeps it spent and Release gives it back.
+// A reservation neither committed nor released is dropped after TTL and its token stays spent.
+type Reservations struct {
+	rdb *redis.Client
+	TTL time.Duration
+}
+
+func NewReservations(rdb *redis.Client, ttl time.Duration) *Reservations {
+	return &Reservations{rdb: rdb, TTL: ttl}
+}
+
+// Reserve takes a token of the window, or a bonus token once the window is full, and keeps it pending
+// under key. Nothing is reserved when the result is not allowed or a reservation is already pending.
+func (r *Reservations) Reserve(ctx context.Context, key string, window *SlidingWindow, windowKey string, bonusKey string) (Result, error) {
+	// the pending check, the take and the reservation run in one script
+	return window.run(ctx, []string{windowKey, bonusKey, key}, true, r.TTL)
+}
+
+// Commit keeps the reserved token spent, false when no reservation was pending
+func (r *Reservations) Commit(ctx context.Context, key string) (bool, error) {
+	deleted, err := r.rdb.Del(ctx, key).Result()
+	return deleted == 1, err
+}
+
+// Release gives the reserved token back, false when no reservation was pending
+func (r *Reservations) Release(ctx context.Context, key string, windowKey string, bonusKey string) (bool, error) {
+	released, err := releaseScript.Run(ctx, r.rdb, []string{key, windowKey, bonusKey}).Int()
+	return released == 1, err
+}
@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservationCommit(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	window := NewSlidingWindow(rdb, 2, time.Minute)
+	reservations := NewReservations(rdb, time.Minute)
+
+	result, err := reservations.Reserve(ctx, "reservation", window, "window", "bonus")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+
+	// one pending reservation per key
+	_, err = reservations.Reserve(ctx, "reservation", window, "window", "bonus")
+	assert.ErrorIs(t, err, ErrReservationPending)
+
+	committed, err := reservations.Commit(ctx, "reservation")
+	assert.NoError(t, err)
+	assert.True(t, committed)
+
+	// a committed token stays spent
+	released, err := reservations.Release(ctx, "reservation", "window", "bonus")
+	assert.NoError(t, err)
+	assert.False(t, released)
+	peek, err := window.Peek(ctx, "window")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, peek.Remaining)
+
+	committed, err = reservations.Commit(ctx, "reservation")
+	assert.NoError(t, err)
+	assert.False(t, committed)
+}
+
+func TestReservationRelease(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	window := NewSlidingWindow(rdb, 1, time.Minute)
+	reservations := NewReservations(rdb, time.Minute)
+
+	_, err := reservations.Reserve(ctx, "reservation", window, "window", "bonus")
+	assert.NoError(t, err)
+
+	released, err := reservations.Release(ctx, "reservation", "window", "bonus")
+	assert.NoError(t, err)
+	assert.True(t, released)
+	peek, err := window.Peek(ctx, "window")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, peek.Remaining)
+
+	// a second release gives nothing back
+	released, err = reservations.Release(ctx, "reservation", "window", "bonus")
+	assert.NoError(t, err)
+	assert.False(t, released)
+	peek, err = window.Peek(ctx, "window")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, peek.Remaining)
+}
+
+func TestReservationBonus(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	window := NewSlidingWindow(rdb, 1, time.Minute)
+	reservations := NewReservations(rdb, time.Minute)
+
+	_, err := window.Take(ctx, "window")
+	assert.NoError(t, err)
+
+	// nothing is reserved without a token
+	result, err := reservations.Reserve(ctx, "reservation", window, "window", "bonus")
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+	exists, err := rdb.Exists(ctx, "reservation").Result()
+	assert.NoError(t, err)
+	assert.Zero(t, exists)
+
+	assert.NoError(t, AddBonusTokens(rdb, "bonus", 1))
+	result, err = reservations.Reserve(ctx, "reservation", window, "window", "bonus")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.True(t, result.Bonus)
+
+	released, err := reservations.Release(ctx, "reservation", "window", "bonus")
+	assert.NoError(t, err)
+	assert.True(t, released)
+	bonus, err := rdb.Get(ctx, "bonus").Int()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, bonus)
+}
+
+func TestReservationExpires(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	window := NewSlidingWindow(rdb, 2, time.Minute)
+	reservations := NewReservations(rdb, 100*time.Millisecond)
+
+	_, err := reservations.Reserve(ctx, "reservation", window, "window", "bonus")
+	assert.NoError(t, err)
+	time.Sleep(150 * time.Millisecond)
+
+	// the token of an expired reservation stays spent, a new reservation can be made
+	released, err := reservations.Release(ctx, "reservation", "window", "bonus")
+	assert.NoError(t, err)
+	assert.False(t, released)
+
+	result, err := reservations.Reserve(ctx, "reservation", window, "window", "bonus")
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+}
+
+func TestReservationConcurrent(t *testing.T) {
+	rdb := testRedis(t)
+	ctx := context.Background()
+	window := NewSlidingWindow(rdb, 5, time.Minute)
+	reservations := NewReservations(rdb, time.Minute)
+
+	// concurrent reservations of the same key take a single token
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := reservations.Reserve(ctx, "reservation", window, "window", "bonus")
+			if err == nil && result.Allowed {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), allowed.Load())
+	peek, err := window.Peek(ctx, "window")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, peek.Remaining)
+}
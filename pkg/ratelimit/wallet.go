@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Wallet is the state of a sliding window with the bonus tokens layered on top
+type Wallet struct {
+	Tokens     int           // tokens left in the window
+	Bonus      int           // tokens usable once the window is full, they don't expire
+	RetryAfter time.Duration // time until the next window token when the window is full
+	ResetIn    time.Duration // time until every window token is back, 0 when the window is empty
+}
+
+// Empty reports whether no token can be taken
+func (w Wallet) Empty() bool {
+	return w.Tokens <= 0 && w.Bonus <= 0
+}
+
+// GetWallet returns the state of the window and the bonus tokens
+func GetWallet(ctx context.Context, window *SlidingWindow, key string, bonusKey string) (Wallet, error) {
+	result, err := window.Peek(ctx, key)
+	if err != nil {
+		return Wallet{}, err
+	}
+
+	bonus, err := window.rdb.Get(ctx, bonusKey).Int()
+	if err != nil && err != redis.Nil {
+		return Wallet{}, err
+	}
+	return Wallet{
+		Tokens:     result.Remaining,
+		Bonus:      max(bonus, 0),
+		RetryAfter: result.RetryAfter,
+		ResetIn:    result.ResetIn,
+	}, nil
+}
+
+// AddBonusTokens credits tokens that are used after the window tokens
+func AddBonusTokens(rdb *redis.Client, bonusKey string, tokens int) error {
+	return rdb.IncrBy(context.Background(), bonusKey, int64(tokens)).Err()
+}
+
+var takeBonusScript = redis.NewScript(`
+local bonus = tonumber(redis.call("GET", KEYS[1]) or "0")
+local taken = math.min(bonus, tonumber(ARGV[1]))
+if taken > 0 then
+	redis.call("DECRBY", KEYS[1], taken)
+end
+return taken
+`)
+
+// TakeBonusTokens removes up to tokens bonus tokens and returns how many were removed
+func TakeBonusTokens(rdb *redis.Client, bonusKey string, tokens int) (int, error) {
+	return takeBonusScript.Run(context.Background(), rdb, []string{bonusKey}, tokens).Int()
+}
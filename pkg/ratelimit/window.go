@@ -0,0 +1,130 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var _ Limiter = (*SlidingWindow)(nil) // implement check
+
+// windowScript drops the events older than the window and records a new one when the window isn't full.
+// A full window falls back to the bonus counter KEYS[2] when given. A taken token is kept pending
+// in the reservation hash KEYS[3] when given, nothing is taken while a reservation is pending.
+// KEYS[1] events zset, KEYS[2] optional bonus counter, KEYS[3] optional reservation hash,
+// ARGV limit, window ms, now ms, event id, '1' to take, reservation ttl ms.
+// Returns {allowed, remaining, retry after ms, reset ms, bonus used}, allowed is -1 for a pending reservation.
+var windowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local take = ARGV[5] == "1"
+
+if KEYS[3] and redis.call("EXISTS", KEYS[3]) == 1 then
+	return {-1, 0, 0, 0, 0}
+end
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", tostring(now - window))
+local count = redis.call("ZCARD", KEYS[1])
+
+local allowed = 0
+local bonus = 0
+if count < limit then
+	allowed = 1
+	if take then
+		redis.call("ZADD", KEYS[1], ARGV[3], ARGV[4])
+		redis.call("PEXPIRE", KEYS[1], window)
+		count = count + 1
+	end
+elseif KEYS[2] and tonumber(redis.call("GET", KEYS[2]) or "0") > 0 then
+	allowed = 1
+	bonus = 1
+	if take then
+		redis.call("DECR", KEYS[2])
+	end
+end
+
+if take and allowed == 1 and KEYS[3] then
+	local id = ARGV[4]
+	if bonus == 1 then
+		id = ""
+	end
+	redis.call("HSET", KEYS[3], "id", id, "bonus", bonus)
+	redis.call("PEXPIRE", KEYS[3], ARGV[6])
+end
+
+local retry = 0
+local reset = 0
+if count > 0 then
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	local newest = redis.call("ZRANGE", KEYS[1], -1, -1, "WITHSCORES")
+	if count >= limit then
+		retry = tonumber(oldest[2]) + window - now
+	end
+	reset = tonumber(newest[2]) + window - now
+end
+return {allowed, math.max(limit - count, 0), retry, reset, bonus}
+`)
+
+// SlidingWindow allows Limit events in any period of Window
+type SlidingWindow struct {
+	rdb    *redis.Client
+	Limit  int
+	Window time.Duration
+}
+
+func NewSlidingWindow(rdb *redis.Client, limit int, window time.Duration) *SlidingWindow {
+	return &SlidingWindow{rdb: rdb, Limit: limit, Window: window}
+}
+
+// Take records an event when the window isn't full
+func (w *SlidingWindow) Take(ctx context.Context, key string) (Result, error) {
+	return w.run(ctx, []string{key}, true, 0)
+}
+
+// TakeOrBonus records an event, or uses a token of the bonus counter once the window is full
+func (w *SlidingWindow) TakeOrBonus(ctx context.Context, key string, bonusKey string) (Result, error) {
+	return w.run(ctx, []string{key, bonusKey}, true, 0)
+}
+
+// Peek returns the state of the window without recording an event
+func (w *SlidingWindow) Peek(ctx context.Context, key string) (Result, error) {
+	return w.run(ctx, []string{key}, false, 0)
+}
+
+// Cancel removes an event recorded by Take, its token is available again
+func (w *SlidingWindow) Cancel(ctx context.Context, key string, id string) error {
+	return w.rdb.ZRem(ctx, key, id).Err()
+}
+
+// run takes or peeks a token, a reservation of ttl is kept when keys has the reservation key
+func (w *SlidingWindow) run(ctx context.Context, keys []string, take bool, ttl time.Duration) (Result, error) {
+	now := time.Now()
+	id := eventID(now)
+	flag := "0"
+	if take {
+		flag = "1"
+	}
+
+	reply, err := windowScript.Run(ctx, w.rdb, keys,
+		w.Limit, max(millis(w.Window), 1), now.UnixMilli(), id, flag, max(millis(ttl), 1)).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if reply[0] == -1 {
+		return Result{}, ErrReservationPending
+	}
+
+	result := Result{
+		Allowed:    reply[0] == 1,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Millisecond,
+		ResetIn:    time.Duration(reply[3]) * time.Millisecond,
+		Bonus:      reply[4] == 1,
+	}
+	if take && result.Allowed && !result.Bonus {
+		result.ID = id
+	}
+	return result, nil
+}